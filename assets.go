@@ -0,0 +1,10 @@
+// Package filamentsamples exposes the files shipped at the root of the
+// repository so the CLI can install them into new projects.
+package filamentsamples
+
+import _ "embed"
+
+// Template is the OpenSCAD sample card template.
+//
+//go:embed FilamentSamples.scad
+var Template []byte
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/guntharp/go-filamentsamples/internal/cache"
+	"github.com/guntharp/go-filamentsamples/internal/config"
+)
+
+func init() {
+	register(&command{
+		name:    "cache",
+		summary: "Show statistics of or prune the shared render cache",
+		usage:   "cache stats|prune [options]",
+		run:     runCache,
+	})
+}
+
+func runCache(env *cmdEnv, args []string) int {
+	action := ""
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		action, args = args[0], args[1:]
+	}
+
+	fs := commands["cache"].flagSet(env)
+	flags := registerSettingsFlags(fs)
+	all := fs.Bool("all", false, "With prune, remove every cached render")
+	if code, ok := parseFlags(env, fs, args); !ok {
+		return code
+	}
+
+	if action != "stats" && action != "prune" {
+		fmt.Fprintf(env.stderr, "Error: cache needs an action, stats or prune\n\n")
+		fs.Usage()
+		return exitUsage
+	}
+
+	cfg, code, err := loadSettings(fs, flags, env.getenv)
+	if err != nil {
+		fmt.Fprintf(env.stderr, "Error: %v\n", err)
+		return code
+	}
+
+	dir := cfg.CacheDir
+	if dir == "" {
+		if dir, err = cache.DefaultDir(); err != nil {
+			fmt.Fprintf(env.stderr, "Error: %v\n", err)
+			return exitError
+		}
+	}
+	c := cache.New(dir)
+
+	maxSize := cfg.CacheMaxSize
+	if maxSize <= 0 {
+		maxSize = cache.DefaultMaxSize
+	}
+
+	if action == "prune" {
+		if *all {
+			maxSize = 0
+		}
+		result, err := c.Prune(int64(maxSize))
+		if err != nil {
+			fmt.Fprintf(env.stderr, "Error: %v\n", err)
+			return exitError
+		}
+		fmt.Fprintf(env.stdout, "Removed %d renders, freed %s\n", result.Removed, config.ByteSize(result.Freed))
+		return exitOK
+	}
+
+	stats, err := c.Stats()
+	if err != nil {
+		fmt.Fprintf(env.stderr, "Error: %v\n", err)
+		return exitError
+	}
+
+	fmt.Fprintf(env.stdout, "Directory: %s\n", dir)
+	fmt.Fprintf(env.stdout, "Renders:   %d\n", stats.Entries)
+	fmt.Fprintf(env.stdout, "Size:      %s of %s\n", config.ByteSize(stats.Size), maxSize)
+	if stats.Entries > 0 {
+		fmt.Fprintf(env.stdout, "Used:      %s to %s\n",
+			stats.Oldest.Format(time.DateTime), stats.Newest.Format(time.DateTime))
+	}
+	return exitOK
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/guntharp/go-filamentsamples/internal/config"
+	"github.com/guntharp/go-filamentsamples/internal/csv"
+	"github.com/guntharp/go-filamentsamples/internal/customizer"
+	"github.com/guntharp/go-filamentsamples/internal/openscad"
+	"github.com/guntharp/go-filamentsamples/pkg/models"
+)
+
+func init() {
+	register(&command{
+		name:    "customizer",
+		summary: "Export the samples as Customizer parameter sets or import sets as CSV rows",
+		usage:   "customizer export|import [options]",
+		run:     runCustomizer,
+	})
+}
+
+func runCustomizer(env *cmdEnv, args []string) int {
+	action := ""
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		action, args = args[0], args[1:]
+	}
+
+	fs := commands["customizer"].flagSet(env)
+	flags := registerSettingsFlags(fs)
+	out := fs.String("out", "", "File to write the parameter file or CSV rows to (default stdout)")
+	if code, ok := parseFlags(env, fs, args); !ok {
+		return code
+	}
+
+	if action != "export" && action != "import" {
+		fmt.Fprintf(env.stderr, "Error: customizer needs an action, export or import\n\n")
+		fs.Usage()
+		return exitUsage
+	}
+
+	cfg, code, err := loadSettings(fs, flags, env.getenv)
+	if err != nil {
+		fmt.Fprintf(env.stderr, "Error: %v\n", err)
+		return code
+	}
+
+	var buf bytes.Buffer
+	var count int
+	var what string
+	if action == "export" {
+		count, code = exportParameterSets(env, &buf, cfg)
+		what = "parameter sets"
+	} else {
+		count, code = importParameterSets(env, &buf, cfg)
+		what = "samples"
+	}
+	if code != exitOK {
+		return code
+	}
+
+	if *out == "" || *out == "-" {
+		if _, err := env.stdout.Write(buf.Bytes()); err != nil {
+			fmt.Fprintf(env.stderr, "Error: %v\n", err)
+			return exitError
+		}
+		return exitOK
+	}
+	if err := os.WriteFile(*out, buf.Bytes(), 0644); err != nil {
+		fmt.Fprintf(env.stderr, "Error: %v\n", err)
+		return exitError
+	}
+	fmt.Fprintf(env.stderr, "Wrote %d %s to %s\n", count, what, *out)
+	return exitOK
+}
+
+// exportParameterSets writes a Customizer parameter file with a set per
+// sample of the sample list to w. It returns the number of sets and the
+// exit code.
+func exportParameterSets(env *cmdEnv, w io.Writer, cfg *config.Config) (int, int) {
+	samples, err := newParser(cfg, env.stderr).ParseFile(cfg.CSVFile)
+	if err != nil {
+		fmt.Fprintf(env.stderr, "Error: %v\n", err)
+		return 0, exitParseError
+	}
+
+	// The sets hold the template defaults, so the template is needed.
+	schema, err := openscad.TemplateSchema(cfg.ScadFile)
+	if err != nil {
+		fmt.Fprintf(env.stderr, "Error: %v\n", err)
+		return 0, exitError
+	}
+	opts, err := argOptions(cfg, schema)
+	if err != nil {
+		fmt.Fprintf(env.stderr, "Error: %v\n", err)
+		return 0, exitUsage
+	}
+	if issues := paramIssues(schema, opts, samples); len(issues) > 0 {
+		for _, issue := range issues {
+			fmt.Fprintf(env.stderr, "Error: %s\n", issue.Message)
+		}
+		return 0, exitParseError
+	}
+
+	f, err := customizer.Export(samples, opts, schema)
+	if err != nil {
+		fmt.Fprintf(env.stderr, "Error: %v\n", err)
+		return 0, exitParseError
+	}
+	// OpenSCAD indents its parameter files by four spaces.
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "    ")
+	if err := enc.Encode(f); err != nil {
+		fmt.Fprintf(env.stderr, "Error: %v\n", err)
+		return 0, exitError
+	}
+	return len(f.ParameterSets), exitOK
+}
+
+// importParameterSets writes the sets of the Customizer parameter file, or
+// only the one chosen with -P, to w as CSV rows. It returns the number of
+// rows and the exit code.
+func importParameterSets(env *cmdEnv, w io.Writer, cfg *config.Config) (int, int) {
+	path := cfg.ParameterFile
+	if path == "" {
+		path = openscad.ParameterFilePath(cfg.ScadFile)
+	}
+	f, err := openscad.LoadParameterFile(path)
+	if err != nil {
+		fmt.Fprintf(env.stderr, "Error: %v\n", err)
+		return 0, exitParseError
+	}
+
+	// Without the template, values equal to its defaults are kept and text
+	// that reads as a number becomes one.
+	schema, err := openscad.TemplateSchema(cfg.ScadFile)
+	if err != nil {
+		fmt.Fprintf(env.stderr, "Warning: %v\n", err)
+		schema = nil
+	}
+
+	var samples []*models.FilamentSample
+	if cfg.ParameterSet != "" {
+		set, ok := f.ParameterSets[cfg.ParameterSet]
+		if !ok {
+			fmt.Fprintf(env.stderr, "Error: %s has no parameter set %q (it has %s)\n",
+				path, cfg.ParameterSet, strings.Join(f.SetNames(), ", "))
+			return 0, exitUsage
+		}
+		sample, err := customizer.ImportSet(cfg.ParameterSet, set, schema)
+		if err != nil {
+			fmt.Fprintf(env.stderr, "Error: %v\n", err)
+			return 0, exitParseError
+		}
+		samples = append(samples, sample)
+	} else if samples, err = customizer.Import(f, schema); err != nil {
+		fmt.Fprintf(env.stderr, "Error: %v\n", err)
+		return 0, exitParseError
+	}
+
+	if err := csv.Write(w, samples); err != nil {
+		fmt.Fprintf(env.stderr, "Error: %v\n", err)
+		return 0, exitError
+	}
+	return len(samples), exitOK
+}
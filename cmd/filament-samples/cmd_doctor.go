@@ -0,0 +1,283 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/guntharp/go-filamentsamples/internal/config"
+	"github.com/guntharp/go-filamentsamples/internal/csv"
+	"github.com/guntharp/go-filamentsamples/internal/input"
+	"github.com/guntharp/go-filamentsamples/internal/openscad"
+)
+
+func init() {
+	register(&command{
+		name:    "doctor",
+		summary: "Check that OpenSCAD, fonts and the project files are usable",
+		usage:   "doctor [options]",
+		run:     runDoctor,
+	})
+}
+
+type checkStatus string
+
+const (
+	checkPass checkStatus = "PASS"
+	checkFail checkStatus = "FAIL"
+	checkSkip checkStatus = "SKIP"
+)
+
+type checkResult struct {
+	status checkStatus
+	detail string
+	hint   string
+}
+
+func pass(format string, args ...any) checkResult {
+	return checkResult{status: checkPass, detail: fmt.Sprintf(format, args...)}
+}
+
+func fail(hint string, format string, args ...any) checkResult {
+	return checkResult{status: checkFail, detail: fmt.Sprintf(format, args...), hint: hint}
+}
+
+func skip(format string, args ...any) checkResult {
+	return checkResult{status: checkSkip, detail: fmt.Sprintf(format, args...)}
+}
+
+// doctor runs the preflight checks. Checks run in order and may rely on
+// state recorded by earlier ones, such as the located OpenSCAD executor.
+type doctor struct {
+	cfg      *config.Config
+	executor *openscad.Executor
+}
+
+type check struct {
+	name string
+	run  func(d *doctor) checkResult
+}
+
+var doctorChecks = []check{
+	{name: "OpenSCAD", run: (*doctor).checkOpenSCAD},
+	{name: "OpenSCAD version", run: (*doctor).checkVersion},
+	{name: "Template", run: (*doctor).checkTemplate},
+	{name: "Template parameters", run: (*doctor).checkParams},
+	{name: "Font", run: (*doctor).checkFont},
+	{name: "Output directory", run: (*doctor).checkOutputDir},
+	{name: "CSV file", run: (*doctor).checkCSV},
+}
+
+func runDoctor(env *cmdEnv, args []string) int {
+	fs := commands["doctor"].flagSet(env)
+	flags := registerSettingsFlags(fs)
+	if code, ok := parseFlags(env, fs, args); !ok {
+		return code
+	}
+
+	cfg, code, err := loadSettings(fs, flags, env.getenv)
+	if err != nil {
+		fmt.Fprintf(env.stderr, "Error: %v\n", err)
+		return code
+	}
+
+	d := &doctor{cfg: cfg}
+	failed := 0
+	for _, c := range doctorChecks {
+		result := c.run(d)
+		fmt.Fprintf(env.stdout, "[%s] %s: %s\n", result.status, c.name, result.detail)
+		if result.status == checkFail {
+			failed++
+			if result.hint != "" {
+				fmt.Fprintf(env.stdout, "       hint: %s\n", result.hint)
+			}
+		}
+	}
+
+	if failed > 0 {
+		fmt.Fprintf(env.stdout, "\n%d of %d checks failed\n", failed, len(doctorChecks))
+		return exitError
+	}
+
+	fmt.Fprintf(env.stdout, "\nAll checks passed\n")
+	return exitOK
+}
+
+func (d *doctor) checkOpenSCAD() checkResult {
+	executor, err := openscad.NewExecutorWithPath(d.cfg.ScadFile, d.cfg.OpenSCADPath)
+	if err != nil {
+		return fail("Install OpenSCAD from https://openscad.org and make sure it is in your PATH", "%v", err)
+	}
+
+	if err := executor.CheckAvailable(); err != nil {
+		return fail("Reinstall OpenSCAD", "%v", err)
+	}
+
+	d.executor = executor
+	return pass("%s", executor.OpenSCADPath)
+}
+
+func (d *doctor) checkVersion() checkResult {
+	if d.executor == nil {
+		return skip("OpenSCAD not available")
+	}
+
+	output, err := d.executor.GetVersion()
+	if err != nil {
+		return fail("Run OpenSCAD manually to check that it starts", "%v", err)
+	}
+
+	version, err := openscad.ParseVersion(output)
+	if err != nil {
+		return fail("Run 'openscad --version' to check the installation", "%v", err)
+	}
+
+	if version.Less(openscad.MinimumVersion) {
+		return fail(fmt.Sprintf("Upgrade OpenSCAD to %s or newer", openscad.MinimumVersion),
+			"%s is older than %s", version, openscad.MinimumVersion)
+	}
+
+	return pass("%s", version)
+}
+
+func (d *doctor) checkTemplate() checkResult {
+	if _, err := os.Stat(d.cfg.ScadFile); err != nil {
+		return fail("Pass the template with -scad or run 'filament-samples init'", "%v", err)
+	}
+
+	if d.executor == nil {
+		return skip("%s exists, OpenSCAD not available to parse it", d.cfg.ScadFile)
+	}
+
+	if err := d.executor.CheckSyntax(); err != nil {
+		return fail("Open the template in OpenSCAD to locate the syntax error", "%v", err)
+	}
+
+	return pass("%s", d.cfg.ScadFile)
+}
+
+func (d *doctor) checkParams() checkResult {
+	schema, err := openscad.TemplateSchema(d.cfg.ScadFile)
+	if err != nil {
+		return skip("cannot read template: %v", err)
+	}
+
+	const hint = "Run 'filament-samples params' to list the parameters the template defines"
+	opts, err := argOptions(d.cfg, schema)
+	if err != nil {
+		return fail("Check parameter_file and parameter_set", "%v", err)
+	}
+	if err := schema.CheckAll(opts.Params); err != nil {
+		return fail(hint, "scad_params: %s", strings.ReplaceAll(err.Error(), "\n", "; "))
+	}
+	if err := schema.CheckAll(opts.ParameterSets[opts.ParameterSet]); err != nil {
+		return fail(hint, "parameter set %q: %s", opts.ParameterSet, strings.ReplaceAll(err.Error(), "\n", "; "))
+	}
+
+	summary := fmt.Sprintf("%d parameters", len(schema.Params))
+	if len(opts.Params) > 0 {
+		summary += fmt.Sprintf(", %d set for every sample", len(opts.Params))
+	}
+	if opts.ParameterSet != "" {
+		summary += fmt.Sprintf(", parameter set %q", opts.ParameterSet)
+	} else if len(opts.ParameterSets) > 0 {
+		summary += fmt.Sprintf(", %d parameter sets", len(opts.ParameterSets))
+	}
+	return pass("%s", summary)
+}
+
+func (d *doctor) checkFont() checkResult {
+	font, err := openscad.TemplateFont(d.cfg.ScadFile)
+	if err != nil {
+		return skip("cannot read template: %v", err)
+	}
+	if font == "" {
+		return skip("template does not set FONT")
+	}
+
+	installed, err := openscad.FontInstalled(font)
+	if err != nil {
+		return fail("Check that fontconfig works by running 'fc-list'", "cannot look up %q: %v", font, err)
+	}
+	if !installed {
+		family, _ := openscad.SplitFontSpec(font)
+		return fail(fmt.Sprintf("Install the %q font (e.g. the fonts-liberation package) or change FONT in the template", family),
+			"%q is not installed", font)
+	}
+
+	return pass("%s", font)
+}
+
+// checkOutputDir probes the output directory, or the nearest existing
+// directory a run would create it in, without creating it.
+func (d *doctor) checkOutputDir() checkResult {
+	hint := "Choose a different directory with -output or fix its permissions"
+
+	dir := d.cfg.OutputDir
+	for {
+		info, err := os.Stat(dir)
+		if err == nil {
+			if !info.IsDir() {
+				return fail(hint, "%s is not a directory", dir)
+			}
+			break
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return fail(hint, "cannot use %s: %v", d.cfg.OutputDir, err)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return fail(hint, "cannot use %s: %v", d.cfg.OutputDir, err)
+		}
+		dir = parent
+	}
+
+	probe, err := os.CreateTemp(dir, ".doctor-*")
+	if err != nil {
+		return fail(hint, "%s is not writable: %v", dir, err)
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+
+	if dir != d.cfg.OutputDir {
+		return pass("%s will be created in %s", d.cfg.OutputDir, dir)
+	}
+	return pass("%s is writable", d.cfg.OutputDir)
+}
+
+func (d *doctor) checkCSV() checkResult {
+	parser := newParser(d.cfg, io.Discard)
+	skipped := 0
+	parser.Skipped = func(*csv.RowError) { skipped++ }
+	var unusual []string
+	parser.Detected = func(source string, f csv.Format) {
+		if !f.Plain() {
+			unusual = append(unusual, fmt.Sprintf("%s read as %s", source, f))
+		}
+	}
+
+	sources, err := input.Resolve(d.cfg.CSVFile, d.cfg.InputFormat, stdin)
+	if err != nil {
+		return fail("Check the -csv setting", "%v", err)
+	}
+	samples, err := parser.ParseSources(sources)
+	if err != nil {
+		return fail("Run 'filament-samples validate' for details", "%v", err)
+	}
+
+	detail := fmt.Sprintf("%d samples", len(samples))
+	if len(sources) > 1 {
+		detail += fmt.Sprintf(" from %d files", len(sources))
+	}
+	if skipped > 0 {
+		detail += fmt.Sprintf(", %d problems in skipped rows", skipped)
+	}
+	for _, u := range unusual {
+		detail += ", " + u
+	}
+	return pass("%s (%s)", d.cfg.CSVFile, detail)
+}
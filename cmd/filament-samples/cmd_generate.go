@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/guntharp/go-filamentsamples/internal/config"
+	"github.com/guntharp/go-filamentsamples/internal/generator"
+)
+
+// runner is the part of generator.Generator used by the CLI.
+type runner interface {
+	Generate(ctx context.Context) error
+}
+
+// newRunner builds the generator for a run. Tests replace it to avoid
+// depending on an installed OpenSCAD.
+var newRunner = func(cfg *config.Config) (runner, error) {
+	return generator.NewGenerator(cfg)
+}
+
+func init() {
+	register(&command{
+		name:    "generate",
+		summary: "Generate STL files for every sample in the CSV file",
+		usage:   "generate [options]",
+		run:     runGenerate,
+	})
+}
+
+func runGenerate(env *cmdEnv, args []string) int {
+	fs := commands["generate"].flagSet(env)
+	flags := registerSettingsFlags(fs)
+	if code, ok := parseFlags(env, fs, args); !ok {
+		return code
+	}
+
+	cfg, code, err := loadSettings(fs, flags, env.getenv)
+	if err != nil {
+		fmt.Fprintf(env.stderr, "Error: %v\n", err)
+		return code
+	}
+
+	gen, err := newRunner(cfg)
+	if err != nil {
+		fmt.Fprintf(env.stderr, "Error: %v\n", err)
+		return exitCodeFor(err)
+	}
+
+	ctx, stop := interruptContext(env)
+	err = gen.Generate(ctx)
+	stop()
+	if err != nil {
+		fmt.Fprintf(env.stderr, "Error: %v\n", err)
+		return exitCodeFor(err)
+	}
+
+	return exitOK
+}
+
+// interruptContext returns a context that the first SIGINT or SIGTERM
+// cancels, so that renders in progress are killed and a summary is printed.
+// Signals stay caught until stop is called: a second Ctrl-C must not kill
+// the CLI before it has killed OpenSCAD, which runs in its own process
+// group, and removed its temporary file.
+func interruptContext(env *cmdEnv) (ctx context.Context, stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-signals:
+				if ctx.Err() != nil {
+					fmt.Fprintln(env.stderr, "Still stopping: waiting for OpenSCAD to exit")
+				}
+				cancel()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return ctx, func() {
+		signal.Stop(signals)
+		close(done)
+		wg.Wait()
+		cancel()
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	filamentsamples "github.com/guntharp/go-filamentsamples"
+	"github.com/guntharp/go-filamentsamples/internal/config"
+)
+
+// starterCSV is written by the init command as an example sample list.
+const starterCSV = `# Brand, Type, Color, TempHotend, TempBed, BrandSize, TypeSize, ColorSize
+# Font sizes are optional and default to the values in the .scad template.
+Brand,Type,Color,TempHotend,TempBed,BrandSize,TypeSize,ColorSize
+Generic,PLA,Black,190-220,50-60,,,
+Generic,PETG,Blue,230-250,70-80,,,
+`
+
+func init() {
+	register(&command{
+		name:    "init",
+		summary: "Create a starter config file, CSV file and OpenSCAD template",
+		usage:   "init [options]",
+		run:     runInit,
+	})
+}
+
+func runInit(env *cmdEnv, args []string) int {
+	fs := commands["init"].flagSet(env)
+	dir := fs.String("dir", ".", "Directory to create the project files in")
+	force := fs.Bool("force", false, "Overwrite existing files")
+	if code, ok := parseFlags(env, fs, args); !ok {
+		return code
+	}
+
+	cfg := config.ExampleConfig()
+	configPath := filepath.Join(*dir, localConfigFile)
+	csvPath := filepath.Join(*dir, cfg.CSVFile)
+	scadPath := filepath.Join(*dir, cfg.ScadFile)
+
+	if !*force {
+		for _, path := range []string{configPath, csvPath, scadPath} {
+			if _, err := os.Stat(path); err == nil {
+				fmt.Fprintf(env.stderr, "Error: %s already exists, use -force to overwrite\n", path)
+				return exitError
+			}
+		}
+	}
+
+	if err := cfg.SaveToFile(configPath); err != nil {
+		fmt.Fprintf(env.stderr, "Error: failed to write config: %v\n", err)
+		return exitError
+	}
+	if err := os.WriteFile(csvPath, []byte(starterCSV), 0644); err != nil {
+		fmt.Fprintf(env.stderr, "Error: failed to write CSV file: %v\n", err)
+		return exitError
+	}
+	if err := os.WriteFile(scadPath, filamentsamples.Template, 0644); err != nil {
+		fmt.Fprintf(env.stderr, "Error: failed to write OpenSCAD template: %v\n", err)
+		return exitError
+	}
+
+	for _, path := range []string{configPath, csvPath, scadPath} {
+		fmt.Fprintf(env.stdout, "Created %s\n", path)
+	}
+	return exitOK
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/guntharp/go-filamentsamples/internal/openscad"
+	"github.com/guntharp/go-filamentsamples/pkg/models"
+)
+
+func init() {
+	register(&command{
+		name:    "list",
+		summary: "Print the parsed samples as a table or JSON",
+		usage:   "list [options]",
+		run:     runList,
+	})
+}
+
+// listEntry is the JSON form of a sample printed by the list command.
+type listEntry struct {
+	Brand      string `json:"brand"`
+	Type       string `json:"type"`
+	Color      string `json:"color"`
+	TempHotend string `json:"temp_hotend"`
+	TempBed    string `json:"temp_bed"`
+	// Hotend and Bed are the parsed temperatures in Celsius.
+	Hotend    *models.TemperatureRange `json:"hotend,omitempty"`
+	Bed       *models.TemperatureRange `json:"bed,omitempty"`
+	BrandSize string                   `json:"brand_size,omitempty"`
+	TypeSize  string                   `json:"type_size,omitempty"`
+	ColorSize string                   `json:"color_size,omitempty"`
+	Filename  string                   `json:"filename"`
+	Notes     string                   `json:"notes,omitempty"`
+	Quantity  int                      `json:"quantity,omitempty"`
+	// Params are the extra template parameters of the sample.
+	Params map[string]string `json:"params,omitempty"`
+	// ParameterSet is the Customizer parameter set the sample chooses.
+	ParameterSet string `json:"parameter_set,omitempty"`
+	// Defines are the template parameters passed for the sample as
+	// OpenSCAD literals: its parameter set, the config's scad_params and
+	// -D flags merged with Params, which take precedence.
+	Defines map[string]string `json:"defines,omitempty"`
+	// Inferred names the fields filled in from a material profile, using
+	// their JSON names such as "temp_hotend".
+	Inferred []string `json:"inferred,omitempty"`
+}
+
+// inferredJSON maps the fields recorded in FilamentSample.Inferred to their
+// names in listEntry.
+var inferredJSON = map[string]string{
+	models.FieldTempHotend: "temp_hotend",
+	models.FieldTempBed:    "temp_bed",
+}
+
+func runList(env *cmdEnv, args []string) int {
+	fs := commands["list"].flagSet(env)
+	flags := registerSettingsFlags(fs)
+	format := fs.String("format", "table", "Output format: table or json")
+	if code, ok := parseFlags(env, fs, args); !ok {
+		return code
+	}
+
+	if *format != "table" && *format != "json" {
+		fmt.Fprintf(env.stderr, "Error: unknown format %q, expected table or json\n", *format)
+		return exitUsage
+	}
+
+	cfg, code, err := loadSettings(fs, flags, env.getenv)
+	if err != nil {
+		fmt.Fprintf(env.stderr, "Error: %v\n", err)
+		return code
+	}
+
+	samples, err := newParser(cfg, env.stderr).ParseFile(cfg.CSVFile)
+	if err != nil {
+		fmt.Fprintf(env.stderr, "Error: %v\n", err)
+		return exitParseError
+	}
+
+	schema, _ := openscad.TemplateSchema(cfg.ScadFile)
+	opts, err := argOptions(cfg, schema)
+	if err != nil {
+		fmt.Fprintf(env.stderr, "Error: %v\n", err)
+		return exitUsage
+	}
+	if *format == "json" {
+		err = writeListJSON(env.stdout, samples, opts)
+	} else {
+		err = writeListTable(env.stdout, samples, opts)
+	}
+	if err != nil {
+		fmt.Fprintf(env.stderr, "Error: %v\n", err)
+		return exitError
+	}
+
+	return exitOK
+}
+
+// writeListTable prints a row per sample. A PARAMS column with the merged
+// template parameters is added when any sample has some.
+func writeListTable(w io.Writer, samples []*models.FilamentSample, opts models.ArgOptions) error {
+	withParams := false
+	for _, s := range samples {
+		if len(s.ParamLiterals(opts)) > 0 {
+			withParams = true
+			break
+		}
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if withParams {
+		fmt.Fprintln(tw, "BRAND\tTYPE\tCOLOR\tHOTEND\tBED\tFILENAME\tPARAMS")
+	} else {
+		fmt.Fprintln(tw, "BRAND\tTYPE\tCOLOR\tHOTEND\tBED\tFILENAME")
+	}
+	inferred := false
+	for _, s := range samples {
+		hotend, bed := s.TempHotend, s.TempBed
+		if s.IsInferred(models.FieldTempHotend) {
+			hotend += "*"
+			inferred = true
+		}
+		if s.IsInferred(models.FieldTempBed) {
+			bed += "*"
+			inferred = true
+		}
+		if withParams {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", s.Brand, s.Type, s.Color, hotend, bed, s.Filename(),
+				formatDefines(s.ParamLiterals(opts)))
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", s.Brand, s.Type, s.Color, hotend, bed, s.Filename())
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if inferred {
+		_, err := fmt.Fprintln(w, "* inferred from a material profile")
+		return err
+	}
+	return nil
+}
+
+// formatDefines returns template parameters as NAME=value pairs separated
+// by spaces, in name order.
+func formatDefines(literals map[string]string) string {
+	names := make([]string, 0, len(literals))
+	for name := range literals {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for i, name := range names {
+		names[i] = name + "=" + literals[name]
+	}
+	return strings.Join(names, " ")
+}
+
+func writeListJSON(w io.Writer, samples []*models.FilamentSample, opts models.ArgOptions) error {
+	entries := make([]listEntry, 0, len(samples))
+	for _, s := range samples {
+		entry := listEntry{
+			Brand:        s.Brand,
+			Type:         s.Type,
+			Color:        s.Color,
+			TempHotend:   s.TempHotend,
+			TempBed:      s.TempBed,
+			BrandSize:    s.BrandSize,
+			TypeSize:     s.TypeSize,
+			ColorSize:    s.ColorSize,
+			Filename:     s.Filename(),
+			Notes:        s.Notes,
+			Quantity:     s.Quantity,
+			Params:       s.Params,
+			ParameterSet: s.ParameterSet,
+		}
+		if defines := s.ParamLiterals(opts); len(defines) > 0 {
+			entry.Defines = defines
+		}
+		for _, field := range s.Inferred {
+			entry.Inferred = append(entry.Inferred, inferredJSON[field])
+		}
+		if r, err := s.HotendRange(); err == nil {
+			r = r.Celsius()
+			entry.Hotend = &r
+		}
+		if r, err := s.BedRange(); err == nil {
+			r = r.Celsius()
+			entry.Bed = &r
+		}
+		entries = append(entries, entry)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(entries)
+}
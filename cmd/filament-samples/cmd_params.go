@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"text/tabwriter"
+
+	"github.com/guntharp/go-filamentsamples/internal/openscad"
+	"github.com/guntharp/go-filamentsamples/pkg/models"
+	"github.com/guntharp/go-filamentsamples/pkg/scad"
+)
+
+func init() {
+	register(&command{
+		name:    "params",
+		summary: "List the parameters the OpenSCAD template defines, with their defaults",
+		usage:   "params [options]",
+		run:     runParams,
+	})
+}
+
+// paramEntry is the JSON form of a template parameter printed by the params
+// command.
+type paramEntry struct {
+	scad.Param
+	// Value is the run-wide override from the parameter set, scad_params
+	// or -D, as an OpenSCAD literal.
+	Value string `json:"value,omitempty"`
+	// PerSample marks parameters every sample sets from its own fields,
+	// such as BRAND.
+	PerSample bool `json:"per_sample,omitempty"`
+}
+
+func runParams(env *cmdEnv, args []string) int {
+	fs := commands["params"].flagSet(env)
+	flags := registerSettingsFlags(fs)
+	format := fs.String("format", "table", "Output format: table or json")
+	if code, ok := parseFlags(env, fs, args); !ok {
+		return code
+	}
+
+	if *format != "table" && *format != "json" {
+		fmt.Fprintf(env.stderr, "Error: unknown format %q, expected table or json\n", *format)
+		return exitUsage
+	}
+
+	cfg, code, err := loadSettings(fs, flags, env.getenv)
+	if err != nil {
+		fmt.Fprintf(env.stderr, "Error: %v\n", err)
+		return code
+	}
+
+	schema, err := openscad.TemplateSchema(cfg.ScadFile)
+	if err != nil {
+		fmt.Fprintf(env.stderr, "Error: %v\n", err)
+		return exitError
+	}
+
+	opts, err := argOptions(cfg, schema)
+	if err != nil {
+		fmt.Fprintf(env.stderr, "Error: %v\n", err)
+		return exitUsage
+	}
+	set := opts.ParameterSets[opts.ParameterSet]
+	entries := make([]paramEntry, len(schema.Params))
+	for i, p := range schema.Params {
+		entries[i] = paramEntry{Param: p, PerSample: models.IsFieldVariable(p.Name)}
+		if entries[i].PerSample {
+			continue
+		}
+		entries[i].Value = set[p.Name]
+		if value, ok := opts.Params[p.Name]; ok {
+			entries[i].Value = value
+		}
+	}
+
+	if *format == "json" {
+		err = writeParamsJSON(env.stdout, entries)
+	} else {
+		err = writeParamsTable(env.stdout, entries)
+	}
+	if err != nil {
+		fmt.Fprintf(env.stderr, "Error: %v\n", err)
+		return exitError
+	}
+
+	if err := schema.CheckAll(opts.Params); err != nil {
+		fmt.Fprintf(env.stderr, "Error: scad_params: %v\n", err)
+		return exitUsage
+	}
+	if err := schema.CheckAll(set); err != nil {
+		fmt.Fprintf(env.stderr, "Error: parameter set %q: %v\n", opts.ParameterSet, err)
+		return exitUsage
+	}
+	return exitOK
+}
+
+// lineBreaks matches the line breaks of a default written over several
+// lines, with the indentation around them.
+var lineBreaks = regexp.MustCompile(`\s*\n\s*`)
+
+// writeParamsTable prints a row per parameter. A VALUE column with the
+// run-wide overrides is added when there are some.
+func writeParamsTable(w io.Writer, entries []paramEntry) error {
+	withValues, perSample := false, false
+	for _, e := range entries {
+		withValues = withValues || e.Value != ""
+		perSample = perSample || e.PerSample
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if withValues {
+		fmt.Fprintln(tw, "NAME\tTYPE\tDEFAULT\tVALUE\tDESCRIPTION")
+	} else {
+		fmt.Fprintln(tw, "NAME\tTYPE\tDEFAULT\tDESCRIPTION")
+	}
+	for _, e := range entries {
+		name := e.Name
+		if e.PerSample {
+			name += "*"
+		}
+		def := lineBreaks.ReplaceAllString(e.Default, " ")
+		if withValues {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", name, e.Type, def, e.Value, e.Description)
+		} else {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", name, e.Type, def, e.Description)
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if perSample {
+		_, err := fmt.Fprintln(w, "* set from each sample")
+		return err
+	}
+	return nil
+}
+
+func writeParamsJSON(w io.Writer, entries []paramEntry) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(entries)
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/guntharp/go-filamentsamples/internal/csv"
+	"github.com/guntharp/go-filamentsamples/internal/input"
+	"github.com/guntharp/go-filamentsamples/internal/materials"
+	"github.com/guntharp/go-filamentsamples/internal/openscad"
+	"github.com/guntharp/go-filamentsamples/internal/textutil"
+	"github.com/guntharp/go-filamentsamples/pkg/models"
+	"github.com/guntharp/go-filamentsamples/pkg/scad"
+)
+
+func init() {
+	register(&command{
+		name:    "validate",
+		summary: "Parse and validate the CSV file without generating anything",
+		usage:   "validate [options]",
+		run:     runValidate,
+	})
+}
+
+// validateReport is the JSON form of the validate command's findings.
+type validateReport struct {
+	// File is the sample list as given, which may name several sources.
+	File    string `json:"file"`
+	Valid   bool   `json:"valid"`
+	Samples int    `json:"samples"`
+	// Sources are the files read, in order.
+	Sources []*sourceReport `json:"sources"`
+	// Errors are the invalid rows that reject the file in strict mode.
+	Errors []*csv.RowError `json:"errors,omitempty"`
+	// Skipped are the invalid rows left out in lenient mode.
+	Skipped []*csv.RowError `json:"skipped,omitempty"`
+	// Temperatures are the material plausibility findings.
+	Temperatures []temperatureIssue `json:"temperatures,omitempty"`
+	// Params are the template parameters, of samples, the config or a
+	// parameter set, that the template does not define or types
+	// differently, and parameter sets that cannot be found.
+	Params []paramIssue `json:"params,omitempty"`
+}
+
+// sourceReport describes one file of the sample list.
+type sourceReport struct {
+	File    string `json:"file"`
+	Samples int    `json:"samples"`
+	// Format is how a CSV file was read, as detected from its content.
+	Format *csv.Format `json:"format,omitempty"`
+}
+
+// paramIssue is a template parameter that does not fit the template. File
+// and Line are unset for parameters of the config.
+type paramIssue struct {
+	File    string `json:"file,omitempty"`
+	Line    int    `json:"line,omitempty"`
+	Message string `json:"message"`
+}
+
+type temperatureIssue struct {
+	File     string             `json:"file,omitempty"`
+	Line     int                `json:"line"`
+	Severity materials.Severity `json:"severity"`
+	Message  string             `json:"message"`
+}
+
+func runValidate(env *cmdEnv, args []string) int {
+	fs := commands["validate"].flagSet(env)
+	flags := registerSettingsFlags(fs)
+	format := fs.String("format", "text", "Output format: text or json")
+	if code, ok := parseFlags(env, fs, args); !ok {
+		return code
+	}
+
+	if *format != "text" && *format != "json" {
+		fmt.Fprintf(env.stderr, "Error: unknown format %q, expected text or json\n", *format)
+		return exitUsage
+	}
+
+	cfg, code, err := loadSettings(fs, flags, env.getenv)
+	if err != nil {
+		fmt.Fprintf(env.stderr, "Error: %v\n", err)
+		return code
+	}
+
+	sources, err := input.Resolve(cfg.CSVFile, cfg.InputFormat, stdin)
+	if err != nil {
+		fmt.Fprintf(env.stderr, "Error: %v\n", err)
+		return exitParseError
+	}
+
+	report := validateReport{File: cfg.CSVFile}
+	bySource := make(map[string]*sourceReport, len(sources))
+	for _, src := range sources {
+		sr := &sourceReport{File: src.Name()}
+		report.Sources = append(report.Sources, sr)
+		bySource[sr.File] = sr
+	}
+
+	parser := newParser(cfg, env.stderr)
+	parser.Skipped = func(err *csv.RowError) { report.Skipped = append(report.Skipped, err) }
+	parser.Detected = func(source string, f csv.Format) { bySource[source].Format = &f }
+
+	samples, err := parser.ParseSources(sources)
+	var parseErr *csv.ParseError
+	switch {
+	case errors.As(err, &parseErr):
+		report.Errors = parseErr.Errors
+	case err != nil:
+		fmt.Fprintf(env.stderr, "Error: %v\n", err)
+		return exitParseError
+	}
+
+	report.Samples = len(samples)
+	for _, sample := range samples {
+		bySource[sample.Source].Samples++
+	}
+	issues := cfg.MaterialRules().Check(samples)
+	for _, issue := range issues {
+		report.Temperatures = append(report.Temperatures, temperatureIssue{
+			File: issue.Sample.Source, Line: issue.Line, Severity: issue.Severity, Message: issue.String(),
+		})
+	}
+	schema, _ := openscad.TemplateSchema(cfg.ScadFile)
+	opts, err := argOptions(cfg, schema)
+	if err != nil {
+		report.Params = append(report.Params, paramIssue{Message: err.Error()})
+	}
+	report.Params = append(report.Params, paramIssues(schema, opts, samples)...)
+	report.Valid = len(report.Errors) == 0 && len(materials.Errors(issues)) == 0 && len(report.Params) == 0
+
+	if *format == "json" {
+		enc := json.NewEncoder(env.stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			fmt.Fprintf(env.stderr, "Error: %v\n", err)
+			return exitError
+		}
+	} else {
+		writeValidateText(env, report, len(materials.Errors(issues)))
+	}
+
+	if !report.Valid {
+		return exitParseError
+	}
+	return exitOK
+}
+
+// paramIssues checks the run-wide parameters, the run's parameter set and
+// the parameters and parameter set of every sample against the template,
+// which may be nil.
+func paramIssues(schema *scad.Schema, opts models.ArgOptions, samples []*models.FilamentSample) []paramIssue {
+	var issues []paramIssue
+	if schema != nil {
+		if err := schema.CheckAll(opts.Params); err != nil {
+			for _, err := range models.Unjoin(err) {
+				issues = append(issues, paramIssue{Message: "scad_params: " + err.Error()})
+			}
+		}
+		if err := schema.CheckAll(opts.ParameterSets[opts.ParameterSet]); err != nil {
+			for _, err := range models.Unjoin(err) {
+				issues = append(issues, paramIssue{Message: fmt.Sprintf("parameter set %q: %v", opts.ParameterSet, err)})
+			}
+		}
+	}
+	for _, sample := range samples {
+		var errs []error
+		if schema != nil {
+			if err := sample.CheckParams(schema); err != nil {
+				errs = models.Unjoin(err)
+			}
+		}
+		if err := sample.CheckParameterSet(opts, schema); err != nil {
+			errs = append(errs, err)
+		}
+		for _, err := range errs {
+			issues = append(issues, paramIssue{
+				File: sample.Source, Line: sample.Line, Message: sample.Location() + err.Error(),
+			})
+		}
+	}
+	return issues
+}
+
+func writeValidateText(env *cmdEnv, report validateReport, implausible int) {
+	name := fmt.Sprintf("%d files", len(report.Sources))
+	if len(report.Sources) == 1 {
+		name = report.Sources[0].File
+	}
+
+	for _, src := range report.Sources {
+		if src.Format != nil && !src.Format.Plain() {
+			fmt.Fprintf(env.stdout, "%s: read as %s\n", src.File, src.Format)
+		}
+	}
+	for _, err := range report.Errors {
+		fmt.Fprintln(env.stderr, err)
+	}
+	for _, err := range report.Skipped {
+		fmt.Fprintf(env.stderr, "skipped %v\n", err)
+	}
+	for _, issue := range report.Temperatures {
+		fmt.Fprintln(env.stderr, issue.Message)
+	}
+	for _, issue := range report.Params {
+		fmt.Fprintln(env.stderr, issue.Message)
+	}
+
+	switch {
+	case len(report.Errors) > 0:
+		fmt.Fprintf(env.stderr, "Error: %s: %s in %s\n", name, textutil.Plural(len(report.Errors), "error"), textutil.Plural(countLines(report.Errors), "row"))
+	case implausible == 1:
+		fmt.Fprintf(env.stderr, "Error: %s: 1 sample has implausible temperatures\n", name)
+	case implausible > 0:
+		fmt.Fprintf(env.stderr, "Error: %s: %d samples have implausible temperatures\n", name, implausible)
+	case len(report.Params) > 0:
+		fmt.Fprintf(env.stderr, "Error: %s: %s\n", name, textutil.Plural(len(report.Params), "invalid template parameter"))
+	case len(report.Skipped) > 0 || len(report.Temperatures) > 0:
+		// Rows are only skipped in lenient mode.
+		skipped := ""
+		if len(report.Skipped) > 0 {
+			skipped = ", " + textutil.Plural(countLines(report.Skipped), "skipped row")
+		}
+		fmt.Fprintf(env.stdout, "%s: %s OK%s, %s\n",
+			name, textutil.Plural(report.Samples, "sample"), skipped, textutil.Plural(len(report.Temperatures), "warning"))
+	default:
+		fmt.Fprintf(env.stdout, "%s: %s OK\n", name, textutil.Plural(report.Samples, "sample"))
+	}
+}
+
+// countLines returns the number of rows errs were found in.
+func countLines(errs []*csv.RowError) int {
+	type row struct {
+		file string
+		line int
+	}
+	lines := make(map[row]bool, len(errs))
+	for _, err := range errs {
+		lines[row{err.File, err.Line}] = true
+	}
+	return len(lines)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+)
+
+// cmdEnv carries the process environment a command runs in.
+type cmdEnv struct {
+	stdout io.Writer
+	stderr io.Writer
+	getenv func(string) string
+}
+
+// command is a CLI subcommand. Commands add themselves to the registry from
+// an init function in their own file.
+type command struct {
+	name    string
+	summary string
+	usage   string
+	run     func(env *cmdEnv, args []string) int
+}
+
+var commands = map[string]*command{}
+
+func register(cmd *command) {
+	if _, exists := commands[cmd.name]; exists {
+		panic("command registered twice: " + cmd.name)
+	}
+	commands[cmd.name] = cmd
+}
+
+func init() {
+	register(&command{
+		name:    "help",
+		summary: "Show help for a command",
+		usage:   "help [command]",
+		run:     runHelp,
+	})
+}
+
+func runHelp(env *cmdEnv, args []string) int {
+	if len(args) == 0 {
+		showHelp(env.stdout)
+		return exitOK
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(env.stderr, "Error: unknown command %q\n", args[0])
+		return exitUsage
+	}
+
+	// Every command prints its usage when asked for -h, so route that to
+	// stdout instead of duplicating each command's flag definitions here.
+	helpEnv := &cmdEnv{stdout: env.stdout, stderr: env.stdout, getenv: env.getenv}
+	return cmd.run(helpEnv, []string{"-h"})
+}
+
+// flagSet returns an empty flag set whose usage message describes cmd.
+func (c *command) flagSet(env *cmdEnv) *flag.FlagSet {
+	fs := flag.NewFlagSet(c.name, flag.ContinueOnError)
+	fs.SetOutput(env.stderr)
+	fs.Usage = func() {
+		w := fs.Output()
+		fmt.Fprintf(w, "Usage:\n  filament-samples %s\n\n%s\n", c.usage, c.summary)
+		hasFlags := false
+		fs.VisitAll(func(*flag.Flag) { hasFlags = true })
+		if hasFlags {
+			fmt.Fprintf(w, "\nOptions:\n")
+			fs.PrintDefaults()
+		}
+	}
+	return fs
+}
+
+// parseFlags parses args and reports the exit code to use if parsing stopped
+// the command, either because of an error or because help was requested.
+func parseFlags(env *cmdEnv, fs *flag.FlagSet, args []string) (int, bool) {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK, false
+		}
+		return exitUsage, false
+	}
+
+	if fs.NArg() > 0 {
+		fmt.Fprintf(env.stderr, "Error: unexpected arguments: %v\n", fs.Args())
+		return exitUsage, false
+	}
+
+	return exitOK, true
+}
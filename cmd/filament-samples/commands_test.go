@@ -0,0 +1,656 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/guntharp/go-filamentsamples/internal/config"
+)
+
+func writeCSV(t *testing.T, content string) string {
+	t.Helper()
+	csvFile := filepath.Join(t.TempDir(), "samples.csv")
+	if err := os.WriteFile(csvFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return csvFile
+}
+
+func TestCommands_Registered(t *testing.T) {
+	for _, name := range []string{"generate", "validate", "list", "params", "customizer", "init", "doctor", "cache", "help"} {
+		cmd, ok := commands[name]
+		if !ok {
+			t.Errorf("command %q not registered", name)
+			continue
+		}
+		if cmd.summary == "" || cmd.run == nil {
+			t.Errorf("command %q is incomplete", name)
+		}
+	}
+}
+
+func TestRunValidate(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	tests := []struct {
+		name     string
+		csv      string
+		wantCode int
+		wantOut  string
+	}{
+		{
+			name:     "valid CSV",
+			csv:      "Brand,Type,Color,TempHotend,TempBed\nTest,PLA,Red,200-220,60\n",
+			wantCode: exitOK,
+		},
+		{
+			name:     "invalid temperature",
+			csv:      "Test,PLA,Red,hot,60\n",
+			wantCode: exitParseError,
+		},
+		{
+			name:     "implausible temperature",
+			csv:      "Test,PLA,Red,400,60\n",
+			wantCode: exitParseError,
+		},
+		{
+			name:     "unusual temperature",
+			csv:      "Test,PLA,Red,250,60\n",
+			wantCode: exitOK,
+			wantOut:  ": 1 sample OK, 1 warning\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			csvFile := writeCSV(t, tt.csv)
+
+			var stdout, stderr bytes.Buffer
+			code := run([]string{"validate", "-csv", csvFile}, &stdout, &stderr, envFrom(nil))
+			if code != tt.wantCode {
+				t.Errorf("validate = %d, want %d (stderr: %s)", code, tt.wantCode, stderr.String())
+			}
+			if !strings.Contains(stdout.String(), tt.wantOut) {
+				t.Errorf("output %q does not contain %q", stdout.String(), tt.wantOut)
+			}
+		})
+	}
+}
+
+func TestRunValidate_Report(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	csvFile := writeCSV(t, "Brand,Type,Color,TempHotend,TempBed\nA,PLA,Red,hot,60\nB,PLA,Blue,200,60\nC,PLA,,200,60\n")
+
+	t.Run("text", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		code := run([]string{"validate", "-csv", csvFile}, &stdout, &stderr, envFrom(nil))
+		if code != exitParseError {
+			t.Fatalf("validate = %d, want %d", code, exitParseError)
+		}
+		for _, want := range []string{"line 2, column TempHotend", "line 4, column Color", "2 errors in 2 rows"} {
+			if !strings.Contains(stderr.String(), want) {
+				t.Errorf("output missing %q:\n%s", want, stderr.String())
+			}
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		code := run([]string{"validate", "-csv", csvFile, "-format", "json"}, &stdout, &stderr, envFrom(nil))
+		if code != exitParseError {
+			t.Fatalf("validate = %d, want %d", code, exitParseError)
+		}
+		var report validateReport
+		if err := json.Unmarshal(stdout.Bytes(), &report); err != nil {
+			t.Fatalf("invalid JSON output: %v\n%s", err, stdout.String())
+		}
+		if report.Valid || len(report.Errors) != 2 || report.Errors[0].Value != "hot" {
+			t.Errorf("unexpected report: %+v", report)
+		}
+	})
+
+	t.Run("lenient", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		code := run([]string{"validate", "-csv", csvFile, "-csv-mode", "lenient", "-format", "json"}, &stdout, &stderr, envFrom(nil))
+		if code != exitOK {
+			t.Fatalf("validate = %d, want %d (stderr: %s)", code, exitOK, stderr.String())
+		}
+		var report validateReport
+		if err := json.Unmarshal(stdout.Bytes(), &report); err != nil {
+			t.Fatalf("invalid JSON output: %v", err)
+		}
+		if !report.Valid || report.Samples != 1 || len(report.Skipped) != 2 {
+			t.Errorf("unexpected report: %+v", report)
+		}
+	})
+}
+
+func TestRunValidate_Params(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	csvFile := writeCSV(t, "Brand,Type,Color,TempHotend,TempBed,Params\n"+
+		"A,PLA,Red,200,60,CARD_THICKNESS=3;FONT=Noto Sans\n"+
+		"B,PLA,Blue,200,60,CARD_THICKNES=3;FONT=12\n")
+	scadFile := filepath.Join(filepath.Dir(csvFile), "FilamentSamples.scad")
+	if err := os.WriteFile(scadFile, []byte("CARD_THICKNESS=2.2;\nFONT=\"Liberation Sans\";\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"validate", "-csv", csvFile, "-format", "json"}, &stdout, &stderr, envFrom(nil))
+	if code != exitParseError {
+		t.Fatalf("validate = %d, want %d (stderr: %s)", code, exitParseError, stderr.String())
+	}
+	var report validateReport
+	if err := json.Unmarshal(stdout.Bytes(), &report); err != nil {
+		t.Fatalf("invalid JSON output: %v", err)
+	}
+	if report.Valid || len(report.Params) != 2 {
+		t.Fatalf("Params = %+v, want two errors", report.Params)
+	}
+	for i, want := range []string{"line 3: the template has no parameter CARD_THICKNES", "line 3: FONT must be a string"} {
+		if !strings.Contains(report.Params[i].Message, want) {
+			t.Errorf("Params[%d] = %q, want %q", i, report.Params[i].Message, want)
+		}
+	}
+
+	stdout.Reset()
+	csvFile = writeCSV(t, "Brand,Type,Color,TempHotend,TempBed\nA,PLA,Red,200,60\n")
+	scadFile = filepath.Join(filepath.Dir(csvFile), "FilamentSamples.scad")
+	if err := os.WriteFile(scadFile, []byte("CARD_THICKNESS=2.2;\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	code = run([]string{"validate", "-csv", csvFile, "-D", "CARD_THICKNESS=thick"}, &stdout, &stderr, envFrom(nil))
+	if code != exitParseError || !strings.Contains(stderr.String(), "scad_params: CARD_THICKNESS must be a number") {
+		t.Errorf("validate = %d, want %d and a scad_params error (stderr: %s)", code, exitParseError, stderr.String())
+	}
+
+	// A bookkeeping column the template does not define is not a parameter.
+	stdout.Reset()
+	stderr.Reset()
+	csvFile = filepath.Join(filepath.Dir(scadFile), "supplier.csv")
+	if err := os.WriteFile(csvFile, []byte("Brand,Type,Color,TempHotend,TempBed,Supplier\nA,PLA,Red,200,60,ACME\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	code = run([]string{"validate", "-csv", csvFile}, &stdout, &stderr, envFrom(nil))
+	if code != exitOK || !strings.Contains(stderr.String(), "ignoring column Supplier") {
+		t.Errorf("validate = %d, want %d and a warning (stderr: %s)", code, exitOK, stderr.String())
+	}
+}
+
+func TestRunList_ParameterSets(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	csvFile := writeCSV(t, "Brand,Type,Color,TempHotend,TempBed,Preset\nA,PLA,Red,200,60,Wide\nB,PLA,Blue,200,60,\n")
+	dir := filepath.Dir(csvFile)
+	if err := os.WriteFile(filepath.Join(dir, "FilamentSamples.scad"), []byte("CARD_LENGTH=80;\nFONT=\"Liberation Sans\";\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sets := `{"parameterSets": {"Wide": {"CARD_LENGTH": "100"}, "Mono": {"FONT": "Liberation Mono", "BRAND": "x"}}, "fileFormatVersion": "1"}`
+	if err := os.WriteFile(filepath.Join(dir, "FilamentSamples.json"), []byte(sets), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"list", "-csv", csvFile, "-P", "Mono", "-format", "json"}
+	if code := run(args, &stdout, &stderr, envFrom(nil)); code != exitOK {
+		t.Fatalf("list = %d, want %d (stderr: %s)", code, exitOK, stderr.String())
+	}
+	var entries []listEntry
+	if err := json.Unmarshal(stdout.Bytes(), &entries); err != nil {
+		t.Fatalf("invalid JSON output: %v", err)
+	}
+	if want := map[string]string{"CARD_LENGTH": "100"}; entries[0].ParameterSet != "Wide" || !reflect.DeepEqual(entries[0].Defines, want) {
+		t.Errorf("first sample = %+v, want set Wide with defines %v", entries[0], want)
+	}
+	if want := map[string]string{"FONT": `"Liberation Mono"`}; !reflect.DeepEqual(entries[1].Defines, want) {
+		t.Errorf("second sample defines = %v, want %v", entries[1].Defines, want)
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	if code := run([]string{"list", "-csv", csvFile, "-P", "Nope"}, &stdout, &stderr, envFrom(nil)); code != exitUsage {
+		t.Errorf("list with an unknown -P = %d, want %d", code, exitUsage)
+	}
+
+	csvFile = filepath.Join(dir, "other.csv")
+	if err := os.WriteFile(csvFile, []byte("Brand,Type,Color,TempHotend,TempBed,Preset\nA,PLA,Red,200,60,Narrow\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	code := run([]string{"validate", "-csv", csvFile}, &stdout, &stderr, envFrom(nil))
+	if code != exitParseError || !strings.Contains(stderr.String(), `line 2: unknown parameter set "Narrow"`) {
+		t.Errorf("validate = %d, want %d and an unknown set error (stderr: %s)", code, exitParseError, stderr.String())
+	}
+}
+
+func TestRunList_Defines(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	csvFile := writeCSV(t, "Brand,Type,Color,TempHotend,TempBed,CARD_THICKNESS\nA,PLA,Red,200,60,3\nB,PLA,Blue,200,60,\n")
+	configFile := filepath.Join(filepath.Dir(csvFile), "config.json")
+	if err := os.WriteFile(configFile, []byte(`{"scad_params": {"CARD_THICKNESS": 2, "FONT": "Noto Sans"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"list", "-config", configFile, "-csv", csvFile, "-D", "FONT=Liberation Mono", "-format", "json"}
+	if code := run(args, &stdout, &stderr, envFrom(nil)); code != exitOK {
+		t.Fatalf("list = %d, want %d (stderr: %s)", code, exitOK, stderr.String())
+	}
+	var entries []listEntry
+	if err := json.Unmarshal(stdout.Bytes(), &entries); err != nil {
+		t.Fatalf("invalid JSON output: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+	if got := entries[0].Defines; got["CARD_THICKNESS"] != "3" || got["FONT"] != `"Liberation Mono"` {
+		t.Errorf("first sample defines = %v, want its own CARD_THICKNESS and the -D FONT", got)
+	}
+	if got := entries[1].Defines; got["CARD_THICKNESS"] != "2" {
+		t.Errorf("second sample defines = %v, want CARD_THICKNESS from the config", got)
+	}
+
+	stdout.Reset()
+	args = []string{"list", "-config", configFile, "-csv", csvFile}
+	if code := run(args, &stdout, &stderr, envFrom(nil)); code != exitOK {
+		t.Fatalf("list = %d, want %d (stderr: %s)", code, exitOK, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), `CARD_THICKNESS=3 FONT="Noto Sans"`) {
+		t.Errorf("table should show the merged parameters:\n%s", stdout.String())
+	}
+}
+
+func TestRunList_InputFormats(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	jsonFile := filepath.Join(dir, "samples.json")
+	content := `{"samples": [{"brand": "Test", "type": "PLA", "color": "Red", "params": {"height": 3}, "qty": 2}]}`
+	if err := os.WriteFile(jsonFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"list", "-csv", jsonFile, "-format", "json"}, &stdout, &stderr, envFrom(nil))
+	if code != exitOK {
+		t.Fatalf("list = %d, want %d (stderr: %s)", code, exitOK, stderr.String())
+	}
+	var entries []listEntry
+	if err := json.Unmarshal(stdout.Bytes(), &entries); err != nil {
+		t.Fatalf("invalid JSON output: %v\n%s", err, stdout.String())
+	}
+	if len(entries) != 1 || entries[0].Params["height"] != "3" || entries[0].Quantity != 2 {
+		t.Errorf("unexpected entries: %+v", entries)
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	code = run([]string{"validate", "-csv", jsonFile, "-input-format", "yaml"}, &stdout, &stderr, envFrom(nil))
+	if code != exitOK {
+		t.Errorf("validate JSON as YAML = %d, want %d (stderr: %s)", code, exitOK, stderr.String())
+	}
+
+	code = run([]string{"validate", "-csv", jsonFile, "-input-format", "xml"}, &stdout, &stderr, envFrom(nil))
+	if code == exitOK {
+		t.Error("validate with an unknown input format should fail")
+	}
+}
+
+func TestRunValidate_Sources(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"bambu.csv":     "Bambu Labs,PLA,Red,200-220,60\n",
+		"polymaker.csv": "Polymaker,PETG,Blue,hot,70\nPolymaker,PLA,Grey,200,60\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	original := stdin
+	stdin = strings.NewReader("Elegoo,PLA,White,200,60\n")
+	t.Cleanup(func() { stdin = original })
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"validate", "-csv", dir + ",-", "-csv-mode", "lenient", "-format", "json"}, &stdout, &stderr, envFrom(nil))
+	if code != exitOK {
+		t.Fatalf("validate = %d, want %d (stderr: %s)", code, exitOK, stderr.String())
+	}
+	var report validateReport
+	if err := json.Unmarshal(stdout.Bytes(), &report); err != nil {
+		t.Fatalf("invalid JSON output: %v\n%s", err, stdout.String())
+	}
+	if report.Samples != 3 || len(report.Sources) != 3 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if src := report.Sources[2]; src.File != "<stdin>" || src.Samples != 1 {
+		t.Errorf("stdin source = %+v", src)
+	}
+	if len(report.Skipped) != 1 || report.Skipped[0].File != filepath.Join(dir, "polymaker.csv") || report.Skipped[0].Line != 1 {
+		t.Errorf("skipped = %+v, want polymaker.csv line 1", report.Skipped)
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	code = run([]string{"validate", "-csv", dir}, &stdout, &stderr, envFrom(nil))
+	if code != exitParseError {
+		t.Fatalf("strict validate = %d, want %d", code, exitParseError)
+	}
+	for _, want := range []string{filepath.Join(dir, "polymaker.csv") + ": line 1, column TempHotend", "Error: 2 files: 1 error in 1 row"} {
+		if !strings.Contains(stderr.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, stderr.String())
+		}
+	}
+}
+
+func TestRunList(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	csvFile := writeCSV(t, "Test,PLA,Red,200-220,60\nTest,PETG,Blue,240-260,70\n")
+
+	t.Run("table", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		code := run([]string{"list", "-csv", csvFile}, &stdout, &stderr, envFrom(nil))
+		if code != exitOK {
+			t.Fatalf("list = %d, want %d (stderr: %s)", code, exitOK, stderr.String())
+		}
+		if !strings.Contains(stdout.String(), "Test_PETG_Blue_240-260_70.stl") {
+			t.Errorf("table output missing filename:\n%s", stdout.String())
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		code := run([]string{"list", "-csv", csvFile, "-format", "json"}, &stdout, &stderr, envFrom(nil))
+		if code != exitOK {
+			t.Fatalf("list = %d, want %d (stderr: %s)", code, exitOK, stderr.String())
+		}
+
+		var entries []listEntry
+		if err := json.Unmarshal(stdout.Bytes(), &entries); err != nil {
+			t.Fatalf("invalid JSON output: %v", err)
+		}
+		if len(entries) != 2 || entries[0].Color != "Red" {
+			t.Errorf("unexpected entries: %+v", entries)
+		}
+	})
+
+	t.Run("inferred temperatures", func(t *testing.T) {
+		csvFile := writeCSV(t, "Bambu Labs,PETG,Red,,80\n")
+
+		var stdout, stderr bytes.Buffer
+		code := run([]string{"list", "-csv", csvFile}, &stdout, &stderr, envFrom(nil))
+		if code != exitOK {
+			t.Fatalf("list = %d, want %d (stderr: %s)", code, exitOK, stderr.String())
+		}
+		if !strings.Contains(stdout.String(), "240-270*") || strings.Contains(stdout.String(), "80*") ||
+			!strings.Contains(stdout.String(), "* inferred from a material profile") {
+			t.Errorf("inferred temperature not marked:\n%s", stdout.String())
+		}
+
+		stdout.Reset()
+		code = run([]string{"list", "-csv", csvFile, "-format", "json"}, &stdout, &stderr, envFrom(nil))
+		if code != exitOK {
+			t.Fatalf("list = %d, want %d (stderr: %s)", code, exitOK, stderr.String())
+		}
+		var entries []listEntry
+		if err := json.Unmarshal(stdout.Bytes(), &entries); err != nil {
+			t.Fatalf("invalid JSON output: %v", err)
+		}
+		if len(entries) != 1 || len(entries[0].Inferred) != 1 || entries[0].Inferred[0] != "temp_hotend" {
+			t.Errorf("unexpected entries: %+v", entries)
+		}
+	})
+
+	t.Run("bad format", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		code := run([]string{"list", "-csv", csvFile, "-format", "xml"}, &stdout, &stderr, envFrom(nil))
+		if code != exitUsage {
+			t.Errorf("list = %d, want %d", code, exitUsage)
+		}
+	})
+}
+
+func TestRunInit(t *testing.T) {
+	dir := t.TempDir()
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"init", "-dir", dir}, &stdout, &stderr, envFrom(nil))
+	if code != exitOK {
+		t.Fatalf("init = %d, want %d (stderr: %s)", code, exitOK, stderr.String())
+	}
+
+	cfg, err := config.LoadConfig(filepath.Join(dir, localConfigFile))
+	if err != nil {
+		t.Fatalf("init wrote an unreadable config: %v", err)
+	}
+	if cfg.CSVFile != "samples.csv" {
+		t.Errorf("CSVFile = %s, want samples.csv", cfg.CSVFile)
+	}
+
+	scad, err := os.ReadFile(filepath.Join(dir, "FilamentSamples.scad"))
+	if err != nil || !bytes.Contains(scad, []byte("module Card()")) {
+		t.Errorf("init did not write the OpenSCAD template: %v", err)
+	}
+
+	t.Setenv("HOME", t.TempDir())
+	code = run([]string{"validate", "-csv", filepath.Join(dir, "samples.csv")}, &stdout, &stderr, envFrom(nil))
+	if code != exitOK {
+		t.Errorf("starter CSV does not validate: %s", stderr.String())
+	}
+
+	stderr.Reset()
+	code = run([]string{"init", "-dir", dir}, &stdout, &stderr, envFrom(nil))
+	if code != exitError {
+		t.Errorf("init over existing files = %d, want %d", code, exitError)
+	}
+
+	code = run([]string{"init", "-dir", dir, "-force"}, &stdout, &stderr, envFrom(nil))
+	if code != exitOK {
+		t.Errorf("init -force = %d, want %d", code, exitOK)
+	}
+}
+
+func TestRunDoctor(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("OpenSCAD lookup through PATH is only used on Linux")
+	}
+
+	tempDir := t.TempDir()
+	t.Setenv("HOME", tempDir)
+	t.Setenv("PATH", tempDir)
+
+	csvFile := filepath.Join(tempDir, "samples.csv")
+	if err := os.WriteFile(csvFile, []byte("Test,PLA,Red,200-220,60\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "FilamentSamples.scad"), []byte("cube(1);\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"doctor", "-csv", csvFile}, &stdout, &stderr, envFrom(nil))
+	if code != exitError {
+		t.Errorf("doctor without OpenSCAD = %d, want %d", code, exitError)
+	}
+	for _, want := range []string{"[FAIL] OpenSCAD", "hint:", "[SKIP] OpenSCAD version", "[PASS] Template parameters: 0 parameters", "[PASS] Output directory", "[PASS] CSV file"} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("doctor output missing %q:\n%s", want, stdout.String())
+		}
+	}
+
+	// A missing output directory is checked where it would be created,
+	// and not created.
+	outputDir := filepath.Join(tempDir, "out", "stl")
+	stdout.Reset()
+	run([]string{"doctor", "-csv", csvFile, "-output", outputDir}, &stdout, &stderr, envFrom(nil))
+	if want := "[PASS] Output directory: " + outputDir + " will be created in " + tempDir; !strings.Contains(stdout.String(), want) {
+		t.Errorf("doctor output missing %q:\n%s", want, stdout.String())
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "out")); !os.IsNotExist(err) {
+		t.Errorf("doctor created the output directory: %v", err)
+	}
+
+	fakeOpenSCAD := filepath.Join(tempDir, "openscad")
+	if err := os.WriteFile(fakeOpenSCAD, []byte("#!/bin/sh\necho \"OpenSCAD version 2021.01\" >&2\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout.Reset()
+	code = run([]string{"doctor", "-csv", csvFile}, &stdout, &stderr, envFrom(nil))
+	if code != exitOK {
+		t.Errorf("doctor = %d, want %d:\n%s", code, exitOK, stdout.String())
+	}
+	if !strings.Contains(stdout.String(), "[PASS] OpenSCAD version: 2021.01") {
+		t.Errorf("doctor did not report the OpenSCAD version:\n%s", stdout.String())
+	}
+}
+
+func TestRunParams(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	scadFile := filepath.Join(dir, "template.scad")
+	content := "BRAND=\"x\";\n// card thickness in mm\nCARD_THICKNESS=2.2; // [1:4]\nINSET_DEPTHS=[1.0,\n  0.8];\n"
+	if err := os.WriteFile(scadFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"params", "-scad", scadFile, "-D", "CARD_THICKNESS=3"}, &stdout, &stderr, envFrom(nil))
+	if code != exitOK {
+		t.Fatalf("params = %d, want %d (stderr: %s)", code, exitOK, stderr.String())
+	}
+	for _, want := range []string{"BRAND*", "VALUE", "card thickness in mm", "[1.0, 0.8]", "* set from each sample"} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, stdout.String())
+		}
+	}
+
+	stdout.Reset()
+	code = run([]string{"params", "-scad", scadFile, "-format", "json"}, &stdout, &stderr, envFrom(nil))
+	if code != exitOK {
+		t.Fatalf("params = %d, want %d (stderr: %s)", code, exitOK, stderr.String())
+	}
+	var entries []paramEntry
+	if err := json.Unmarshal(stdout.Bytes(), &entries); err != nil {
+		t.Fatalf("invalid JSON output: %v", err)
+	}
+	if len(entries) != 3 || entries[1].Name != "CARD_THICKNESS" || entries[1].Type != "number" || *entries[1].Max != 4 {
+		t.Errorf("unexpected entries: %+v", entries)
+	}
+
+	code = run([]string{"params", "-scad", scadFile, "-D", "CARD_THICKNESS=thick"}, &stdout, &stderr, envFrom(nil))
+	if code != exitUsage || !strings.Contains(stderr.String(), "CARD_THICKNESS must be a number") {
+		t.Errorf("params with a mistyped -D = %d, want %d (stderr: %s)", code, exitUsage, stderr.String())
+	}
+}
+
+func TestRunCustomizer(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	csvFile := writeCSV(t, "Brand,Type,Color,TempHotend,TempBed,BrandSize,TypeSize,ColorSize,FONT\n"+
+		"A,PLA,Red,200-220,60,5,,,Noto Sans\n"+
+		"B,PETG,Blue,240,70,,,,\n")
+	dir := filepath.Dir(csvFile)
+	template := "BRAND=\"x\";\nTYPE=\"y\";\nCOLOR=\"z\";\nTEMP_HOTEND=\"200\";\nTEMP_BED=\"60\";\n" +
+		"BRAND_SIZE=4.2;\nFONT=\"Liberation Sans\";\nCARD_LENGTH=80;\n"
+	if err := os.WriteFile(filepath.Join(dir, "FilamentSamples.scad"), []byte(template), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"customizer"}, &stdout, &stderr, envFrom(nil)); code != exitUsage {
+		t.Errorf("customizer without an action = %d, want %d", code, exitUsage)
+	}
+
+	paramFile := filepath.Join(dir, "FilamentSamples.json")
+	args := []string{"customizer", "export", "-csv", csvFile, "-out", paramFile}
+	if code := run(args, &stdout, &stderr, envFrom(nil)); code != exitOK {
+		t.Fatalf("customizer export = %d, want %d (stderr: %s)", code, exitOK, stderr.String())
+	}
+	if !strings.Contains(stderr.String(), "Wrote 2 parameter sets") {
+		t.Errorf("stderr = %q, want a summary", stderr.String())
+	}
+	data, err := os.ReadFile(paramFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var f struct {
+		ParameterSets map[string]map[string]string `json:"parameterSets"`
+	}
+	if err := json.Unmarshal(data, &f); err != nil {
+		t.Fatalf("invalid parameter file: %v", err)
+	}
+	want := map[string]string{
+		"BRAND": "A", "TYPE": "PLA", "COLOR": "Red", "TEMP_HOTEND": "200-220", "TEMP_BED": "60",
+		"BRAND_SIZE": "5", "FONT": "Noto Sans", "CARD_LENGTH": "80",
+	}
+	if got := f.ParameterSets["A_PLA_Red_200-220_60"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("set = %v, want %v", got, want)
+	}
+
+	stdout.Reset()
+	if code := run([]string{"customizer", "import", "-csv", csvFile}, &stdout, &stderr, envFrom(nil)); code != exitOK {
+		t.Fatalf("customizer import = %d, want %d (stderr: %s)", code, exitOK, stderr.String())
+	}
+	wantCSV := "Brand,Type,Color,TempHotend,TempBed,BrandSize,TypeSize,ColorSize,FONT\n" +
+		"A,PLA,Red,200-220,60,5,,,Noto Sans\n" +
+		"B,PETG,Blue,240,70,,,,\n"
+	if stdout.String() != wantCSV {
+		t.Errorf("import =\n%s\nwant\n%s", stdout.String(), wantCSV)
+	}
+
+	stdout.Reset()
+	args = []string{"customizer", "import", "-csv", csvFile, "-P", "B_PETG_Blue_240_70"}
+	if code := run(args, &stdout, &stderr, envFrom(nil)); code != exitOK {
+		t.Fatalf("customizer import -P = %d, want %d (stderr: %s)", code, exitOK, stderr.String())
+	}
+	if want := "Brand,Type,Color,TempHotend,TempBed\nB,PETG,Blue,240,70\n"; stdout.String() != want {
+		t.Errorf("import -P = %q, want %q", stdout.String(), want)
+	}
+	if code := run([]string{"customizer", "import", "-csv", csvFile, "-P", "Nope"}, &stdout, &stderr, envFrom(nil)); code != exitUsage {
+		t.Errorf("customizer import with an unknown -P = %d, want %d", code, exitUsage)
+	}
+}
+
+func TestRunCache(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := filepath.Join(t.TempDir(), "cache")
+
+	for _, name := range []string{"aa", "bb"} {
+		entry := filepath.Join(dir, name, name+strings.Repeat("0", 62)+".stl")
+		os.MkdirAll(filepath.Dir(entry), 0755)
+		if err := os.WriteFile(entry, make([]byte, 1024), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		wantCode int
+		wantOut  string
+	}{
+		{name: "missing action", args: []string{"cache"}, wantCode: exitUsage},
+		{name: "unknown action", args: []string{"cache", "clean"}, wantCode: exitUsage},
+		{name: "stats", args: []string{"cache", "stats", "-cache-dir", dir}, wantCode: exitOK, wantOut: "Renders:   2\nSize:      2KB of 1GB"},
+		{name: "prune to limit", args: []string{"cache", "prune", "-cache-dir", dir, "-cache-max-size", "1KB"}, wantCode: exitOK, wantOut: "Removed 1 renders, freed 1KB"},
+		{name: "prune all", args: []string{"cache", "prune", "-all", "-cache-dir", dir}, wantCode: exitOK, wantOut: "Removed 1 renders"},
+		{name: "empty", args: []string{"cache", "stats", "-cache-dir", dir}, wantCode: exitOK, wantOut: "Renders:   0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := run(tt.args, &stdout, &stderr, envFrom(nil))
+			if code != tt.wantCode {
+				t.Fatalf("exit code = %d, want %d; stderr: %s", code, tt.wantCode, stderr.String())
+			}
+			if !strings.Contains(stdout.String(), tt.wantOut) {
+				t.Errorf("output %q does not contain %q", stdout.String(), tt.wantOut)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/guntharp/go-filamentsamples/internal/config"
+	"github.com/guntharp/go-filamentsamples/internal/generator"
+	"github.com/guntharp/go-filamentsamples/internal/openscad"
+)
+
+var version = "dev"
+
+// stdin is read for the sample list "-". Tests replace it.
+var stdin io.Reader = os.Stdin
+
+// Exit codes returned by the CLI.
+const (
+	exitOK              = 0
+	exitError           = 1
+	exitUsage           = 2
+	exitParseError      = 3
+	exitOpenSCADMissing = 4
+	exitPartialFailure  = 5
+	exitInterrupted     = 130
+)
+
+// defaultCommand runs when no command name is given, which keeps the
+// original flag-only invocation working.
+const defaultCommand = "generate"
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr, os.Getenv))
+}
+
+func run(args []string, stdout, stderr io.Writer, getenv func(string) string) int {
+	env := &cmdEnv{stdout: stdout, stderr: stderr, getenv: getenv}
+
+	name := defaultCommand
+	if len(args) > 0 {
+		switch args[0] {
+		case "-h", "-help", "--help":
+			showHelp(stdout)
+			return exitOK
+		case "-version", "--version":
+			fmt.Fprintf(stdout, "filament-samples %s\n", version)
+			return exitOK
+		}
+		if !strings.HasPrefix(args[0], "-") {
+			name, args = args[0], args[1:]
+		}
+	}
+
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(stderr, "Error: unknown command %q\n\n", name)
+		showHelp(stderr)
+		return exitUsage
+	}
+
+	return cmd.run(env, args)
+}
+
+func exitCodeFor(err error) int {
+	var genErr *generator.GenerationError
+
+	switch {
+	case errors.Is(err, openscad.ErrNotFound), errors.Is(err, generator.ErrOpenSCADUnavailable):
+		return exitOpenSCADMissing
+	case errors.Is(err, generator.ErrParse):
+		return exitParseError
+	case errors.Is(err, generator.ErrParams):
+		return exitUsage
+	case errors.As(err, &genErr):
+		return exitPartialFailure
+	case errors.Is(err, context.Canceled):
+		return exitInterrupted
+	default:
+		return exitError
+	}
+}
+
+func showHelp(w io.Writer) {
+	fmt.Fprintf(w, `filament-samples %s - generate filament sample cards with OpenSCAD
+
+Usage:
+  filament-samples [command] [options]
+
+Commands:
+`, version)
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-10s %s\n", name, commands[name].summary)
+	}
+
+	fmt.Fprintf(w, `
+Without a command, %q is run. Use "filament-samples help <command>" for
+the options of a command.
+
+Settings are merged in this order, later sources winning:
+  built-in defaults < config file < environment variables < command-line flags
+
+The config file is read from -config, $%s, ./%s or %s,
+whichever is found first.
+
+Environment variables:
+  %s, %s, %s,
+  %s, %s, %s,
+  %s, %s, %s,
+  %s, %s, %s,
+  %s, %s
+
+Exit codes:
+  %d  success
+  %d  unexpected error
+  %d  invalid command-line usage or environment
+  %d  config or CSV parse error
+  %d  OpenSCAD not found
+  %d  one or more samples failed to generate
+  %d  interrupted by a signal
+`,
+		defaultCommand,
+		envConfig, localConfigFile, config.DefaultConfigPath(),
+		envCSV, envOutput, envScad, envWorkers, envVerbose, envDryRun, envTimeout, envWarnings, envForce, envCache, envCacheDir, envConflict,
+		envCSVMode, envInput,
+		exitOK, exitError, exitUsage, exitParseError, exitOpenSCADMissing, exitPartialFailure, exitInterrupted)
+}
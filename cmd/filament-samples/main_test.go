@@ -0,0 +1,364 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/guntharp/go-filamentsamples/internal/config"
+	"github.com/guntharp/go-filamentsamples/internal/generator"
+	"github.com/guntharp/go-filamentsamples/internal/openscad"
+)
+
+type stubRunner struct {
+	err error
+}
+
+func (s *stubRunner) Generate(ctx context.Context) error {
+	return s.err
+}
+
+func envFrom(vars map[string]string) func(string) string {
+	return func(key string) string {
+		return vars[key]
+	}
+}
+
+func stubNewRunner(t *testing.T, fn func(cfg *config.Config) (runner, error)) {
+	t.Helper()
+	original := newRunner
+	newRunner = fn
+	t.Cleanup(func() { newRunner = original })
+}
+
+func TestRun_Version(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"-version"}, &stdout, &stderr, envFrom(nil))
+	if code != exitOK {
+		t.Errorf("run(-version) = %d, want %d", code, exitOK)
+	}
+	if !strings.Contains(stdout.String(), version) {
+		t.Errorf("Expected version in output, got %q", stdout.String())
+	}
+}
+
+func TestRun_Help(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"-help"}, &stdout, &stderr, envFrom(nil))
+	if code != exitOK {
+		t.Errorf("run(-help) = %d, want %d", code, exitOK)
+	}
+	for _, want := range []string{"generate", "validate", "list", "init", "doctor", envWorkers, "Exit codes"} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("Help output missing %q", want)
+		}
+	}
+}
+
+func TestRun_CommandHelp(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"help", "generate"}, &stdout, &stderr, envFrom(nil))
+	if code != exitOK {
+		t.Errorf("run(help generate) = %d, want %d", code, exitOK)
+	}
+	for _, want := range []string{"-csv", "-dry-run", "-workers"} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("Command help missing %q", want)
+		}
+	}
+}
+
+func TestRun_UsageErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		env  map[string]string
+	}{
+		{name: "unknown flag", args: []string{"-bogus"}},
+		{name: "bad flag value", args: []string{"-workers", "many"}},
+		{name: "unknown command", args: []string{"extra"}},
+		{name: "positional argument", args: []string{"generate", "extra"}},
+		{name: "bad env workers", env: map[string]string{envWorkers: "many"}},
+		{name: "bad env bool", env: map[string]string{envVerbose: "sometimes"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("HOME", t.TempDir())
+			stubNewRunner(t, func(cfg *config.Config) (runner, error) {
+				t.Fatal("generator should not be created on usage errors")
+				return nil, nil
+			})
+
+			var stdout, stderr bytes.Buffer
+			code := run(tt.args, &stdout, &stderr, envFrom(tt.env))
+			if code != exitUsage {
+				t.Errorf("run() = %d, want %d (stderr: %s)", code, exitUsage, stderr.String())
+			}
+		})
+	}
+}
+
+func TestRun_ExitCodes(t *testing.T) {
+	tests := []struct {
+		name     string
+		newErr   error
+		generate error
+		wantCode int
+	}{
+		{name: "success", wantCode: exitOK},
+		{name: "OpenSCAD not found", newErr: fmt.Errorf("failed to initialize OpenSCAD executor: %w", openscad.ErrNotFound), wantCode: exitOpenSCADMissing},
+		{name: "OpenSCAD check failed", generate: fmt.Errorf("%w: gone", generator.ErrOpenSCADUnavailable), wantCode: exitOpenSCADMissing},
+		{name: "CSV parse error", generate: fmt.Errorf("%w: bad row", generator.ErrParse), wantCode: exitParseError},
+		{name: "partial failure", generate: &generator.GenerationError{Failed: 1, Total: 3}, wantCode: exitPartialFailure},
+		{name: "interrupted", generate: fmt.Errorf("generation interrupted: %w", context.Canceled), wantCode: exitInterrupted},
+		{name: "other error", generate: errors.New("disk full"), wantCode: exitError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("HOME", t.TempDir())
+			stubNewRunner(t, func(cfg *config.Config) (runner, error) {
+				if tt.newErr != nil {
+					return nil, tt.newErr
+				}
+				return &stubRunner{err: tt.generate}, nil
+			})
+
+			var stdout, stderr bytes.Buffer
+			code := run(nil, &stdout, &stderr, envFrom(nil))
+			if code != tt.wantCode {
+				t.Errorf("run() = %d, want %d (stderr: %s)", code, tt.wantCode, stderr.String())
+			}
+		})
+	}
+}
+
+// interruptRunner interrupts the test process twice while it runs.
+type interruptRunner struct {
+	t *testing.T
+}
+
+func (r *interruptRunner) Generate(ctx context.Context) error {
+	self, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	self.Signal(os.Interrupt)
+	<-ctx.Done()
+
+	// Had the second signal its default behavior, the test binary would
+	// die here.
+	self.Signal(os.Interrupt)
+	time.Sleep(50 * time.Millisecond)
+	return fmt.Errorf("generation interrupted: %w", ctx.Err())
+}
+
+func TestRun_InterruptTwice(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sending os.Interrupt is not supported on Windows")
+	}
+	t.Setenv("HOME", t.TempDir())
+	stubNewRunner(t, func(cfg *config.Config) (runner, error) {
+		return &interruptRunner{t: t}, nil
+	})
+
+	var stdout, stderr bytes.Buffer
+	code := run(nil, &stdout, &stderr, envFrom(nil))
+	if code != exitInterrupted {
+		t.Errorf("run() = %d, want %d (stderr: %s)", code, exitInterrupted, stderr.String())
+	}
+	if !strings.Contains(stderr.String(), "Still stopping") {
+		t.Errorf("second interrupt not acknowledged, stderr: %s", stderr.String())
+	}
+}
+
+func TestRun_ConfigParseError(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	configFile := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(configFile, []byte("{not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"-config", configFile}, &stdout, &stderr, envFrom(nil))
+	if code != exitParseError {
+		t.Errorf("run() = %d, want %d", code, exitParseError)
+	}
+}
+
+func TestLoadSettings_Precedence(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	tempDir := t.TempDir()
+	configFile := filepath.Join(tempDir, "config.json")
+
+	fileConfig := config.Config{
+		CSVFile:    "from-file.csv",
+		OutputDir:  "file-output",
+		MaxWorkers: 3,
+		Verbose:    true,
+	}
+	data, err := json.Marshal(fileConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(configFile, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		args        []string
+		env         map[string]string
+		wantCSV     string
+		wantOutput  string
+		wantWorkers int
+		wantVerbose bool
+	}{
+		{
+			name:        "config file only",
+			args:        []string{"-config", configFile},
+			wantCSV:     "from-file.csv",
+			wantOutput:  "file-output",
+			wantWorkers: 3,
+			wantVerbose: true,
+		},
+		{
+			name:        "environment overrides config file",
+			env:         map[string]string{envConfig: configFile, envCSV: "from-env.csv", envWorkers: "5"},
+			wantCSV:     "from-env.csv",
+			wantOutput:  "file-output",
+			wantWorkers: 5,
+			wantVerbose: true,
+		},
+		{
+			name:        "flags override environment",
+			args:        []string{"-config", configFile, "-csv", "from-flag.csv", "-workers", "7", "-verbose=false"},
+			env:         map[string]string{envCSV: "from-env.csv", envWorkers: "5"},
+			wantCSV:     "from-flag.csv",
+			wantOutput:  "file-output",
+			wantWorkers: 7,
+			wantVerbose: false,
+		},
+		{
+			name:        "defaults relative to CSV file",
+			args:        []string{"-csv", filepath.Join("data", "samples.csv"), "-workers", "2"},
+			wantCSV:     filepath.Join("data", "samples.csv"),
+			wantOutput:  filepath.Join("data", "stl"),
+			wantWorkers: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			flags := registerSettingsFlags(fs)
+			if err := fs.Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, _, err := loadSettings(fs, flags, envFrom(tt.env))
+			if err != nil {
+				t.Fatalf("loadSettings() error = %v", err)
+			}
+
+			if cfg.CSVFile != tt.wantCSV {
+				t.Errorf("CSVFile = %s, want %s", cfg.CSVFile, tt.wantCSV)
+			}
+			if cfg.OutputDir != tt.wantOutput {
+				t.Errorf("OutputDir = %s, want %s", cfg.OutputDir, tt.wantOutput)
+			}
+			if cfg.MaxWorkers != tt.wantWorkers {
+				t.Errorf("MaxWorkers = %d, want %d", cfg.MaxWorkers, tt.wantWorkers)
+			}
+			if cfg.Verbose != tt.wantVerbose {
+				t.Errorf("Verbose = %v, want %v", cfg.Verbose, tt.wantVerbose)
+			}
+		})
+	}
+}
+
+func TestLoadSettings_ScadParams(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	configFile := filepath.Join(t.TempDir(), "config.json")
+	content := `{"csv_file": "samples.csv", "scad_params": {"FONT": "Noto Sans", "$fn": 100, "CARD_THICKNESS": 2.5}}`
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags := registerSettingsFlags(fs)
+	if err := fs.Parse([]string{"-config", configFile, "-D", "$fn=20", "-D", "INSET_DEPTHS=[0.4, 0.2]"}); err != nil {
+		t.Fatal(err)
+	}
+	cfg, _, err := loadSettings(fs, flags, envFrom(nil))
+	if err != nil {
+		t.Fatalf("loadSettings() error = %v", err)
+	}
+
+	literals, err := cfg.ParamLiterals()
+	if err != nil {
+		t.Fatalf("ParamLiterals() error = %v", err)
+	}
+	want := map[string]string{"FONT": `"Noto Sans"`, "$fn": "20", "CARD_THICKNESS": "2.5", "INSET_DEPTHS": "[0.4, 0.2]"}
+	if !reflect.DeepEqual(literals, want) {
+		t.Errorf("ParamLiterals() = %v, want %v", literals, want)
+	}
+
+	fs = flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	registerSettingsFlags(fs)
+	if err := fs.Parse([]string{"-D", "2nd=1"}); err == nil {
+		t.Error("-D with an invalid name should be rejected")
+	}
+}
+
+func TestRun_DryRunIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+	if runtime.GOOS == "windows" {
+		t.Skip("fake OpenSCAD script requires a POSIX shell")
+	}
+
+	tempDir := t.TempDir()
+	t.Setenv("HOME", tempDir)
+
+	fakeOpenSCAD := filepath.Join(tempDir, "openscad")
+	if err := os.WriteFile(fakeOpenSCAD, []byte("#!/bin/sh\necho \"OpenSCAD 2021.01\"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", tempDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	if runtime.GOOS == "darwin" {
+		if _, err := os.Stat("/Applications/OpenSCAD.app"); err == nil {
+			t.Skip("system OpenSCAD takes precedence over PATH on macOS")
+		}
+	}
+
+	csvFile := filepath.Join(tempDir, "samples.csv")
+	csvContent := "Brand,Type,Color,TempHotend,TempBed\nTest,PLA,Red,200-220,60\n"
+	if err := os.WriteFile(csvFile, []byte(csvContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"-csv", csvFile, "-dry-run"}, &stdout, &stderr, envFrom(nil))
+	if code != exitOK {
+		t.Fatalf("run() = %d, want %d (stderr: %s)", code, exitOK, stderr.String())
+	}
+
+	if _, err := os.Stat(filepath.Join(tempDir, "stl", "Test_PLA_Red_200-220_60.stl")); !os.IsNotExist(err) {
+		t.Error("dry run should not create STL files")
+	}
+}
@@ -0,0 +1,342 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/guntharp/go-filamentsamples/internal/config"
+	"github.com/guntharp/go-filamentsamples/internal/csv"
+	"github.com/guntharp/go-filamentsamples/internal/generator"
+	"github.com/guntharp/go-filamentsamples/internal/input"
+	"github.com/guntharp/go-filamentsamples/internal/openscad"
+	"github.com/guntharp/go-filamentsamples/pkg/models"
+	"github.com/guntharp/go-filamentsamples/pkg/scad"
+)
+
+// Environment variables read by the CLI. They override the config file and
+// are overridden by command-line flags.
+const (
+	envConfig   = "FILAMENT_SAMPLES_CONFIG"
+	envCSV      = "FILAMENT_SAMPLES_CSV"
+	envOutput   = "FILAMENT_SAMPLES_OUTPUT"
+	envScad     = "FILAMENT_SAMPLES_SCAD"
+	envWorkers  = "FILAMENT_SAMPLES_WORKERS"
+	envVerbose  = "FILAMENT_SAMPLES_VERBOSE"
+	envDryRun   = "FILAMENT_SAMPLES_DRY_RUN"
+	envTimeout  = "FILAMENT_SAMPLES_TIMEOUT"
+	envWarnings = "FILAMENT_SAMPLES_WARNING_POLICY"
+	envForce    = "FILAMENT_SAMPLES_FORCE"
+	envCache    = "FILAMENT_SAMPLES_CACHE"
+	envCacheDir = "FILAMENT_SAMPLES_CACHE_DIR"
+	envConflict = "FILAMENT_SAMPLES_ON_CONFLICT"
+	envCSVMode  = "FILAMENT_SAMPLES_CSV_MODE"
+	envInput    = "FILAMENT_SAMPLES_INPUT_FORMAT"
+)
+
+const (
+	localConfigFile = "filament-samples.json"
+	defaultCSVFile  = "samples.csv"
+	defaultScadFile = "FilamentSamples.scad"
+	defaultSTLDir   = "stl"
+)
+
+// settingsFlags are the flags shared by every command that needs a config.
+type settingsFlags struct {
+	configPath string
+	csvFile    string
+	outputDir  string
+	scadFile   string
+	openscad   string
+	workers    int
+	timeout    time.Duration
+	warnings   string
+	onConflict string
+	csvMode    string
+	input      string
+	verbose    bool
+	dryRun     bool
+	force      bool
+	cache      bool
+	cacheDir   string
+	cacheSize  config.ByteSize
+	defines    defineFlags
+	paramFile  string
+	paramSet   string
+}
+
+// defineFlags collects repeated -D NAME=value flags in order.
+type defineFlags []string
+
+func (d *defineFlags) String() string {
+	return strings.Join(*d, " ")
+}
+
+func (d *defineFlags) Set(value string) error {
+	name, _, found := strings.Cut(value, "=")
+	if !found {
+		return fmt.Errorf("expected NAME=value, got %q", value)
+	}
+	if !scad.ValidName(strings.TrimSpace(name)) {
+		return fmt.Errorf("invalid parameter name %q", strings.TrimSpace(name))
+	}
+	*d = append(*d, value)
+	return nil
+}
+
+func registerSettingsFlags(fs *flag.FlagSet) *settingsFlags {
+	f := &settingsFlags{}
+	fs.StringVar(&f.configPath, "config", "", "Path to JSON config file")
+	fs.StringVar(&f.csvFile, "csv", defaultCSVFile, `Sample list: a file, a directory, "-" for stdin, or a comma separated list of them`)
+	fs.StringVar(&f.outputDir, "output", "", `Output directory for STL files (default "stl" relative to CSV file)`)
+	fs.StringVar(&f.scadFile, "scad", "", `Path to OpenSCAD file (default "FilamentSamples.scad" relative to CSV file)`)
+	fs.StringVar(&f.openscad, "openscad", "", "Path to the OpenSCAD executable (default $OPENSCAD or auto-detected)")
+	fs.IntVar(&f.workers, "workers", 0, "Maximum concurrent workers (default number of CPU cores)")
+	fs.DurationVar(&f.timeout, "timeout", 0, "Maximum time to render a single sample, e.g. 2m (default no limit)")
+	fs.StringVar(&f.warnings, "warning-policy", "", `What to do when OpenSCAD prints warnings: "report", "fail" or "ignore" (default "report")`)
+	fs.StringVar(&f.onConflict, "on-conflict", "", `What to do with existing STL files: "overwrite", "skip" or "keep-both" (default "overwrite")`)
+	fs.StringVar(&f.csvMode, "csv-mode", "", `How to treat invalid CSV rows: "strict" rejects the file, "lenient" skips them (default "strict")`)
+	fs.StringVar(&f.input, "input-format", "", `Format of the sample list: "csv", "json", "yaml" or "toml" (default: by file extension)`)
+	fs.BoolVar(&f.verbose, "verbose", false, "Enable verbose logging")
+	fs.BoolVar(&f.dryRun, "dry-run", false, "Show what would be generated without creating files")
+	fs.BoolVar(&f.force, "force", false, "Rebuild every sample, even if its STL file is up to date")
+	fs.BoolVar(&f.cache, "cache", false, "Reuse renders from the shared render cache")
+	fs.StringVar(&f.cacheDir, "cache-dir", "", "Render cache directory (default filament-samples in the user cache directory)")
+	fs.Var(&f.cacheSize, "cache-max-size", "Prune the render cache to this `size` after a run, e.g. 500MB (default 1GB)")
+	fs.Var(&f.defines, "D", "Override a template parameter for every sample, as `NAME=value`; repeatable")
+	fs.StringVar(&f.paramFile, "p", "", `Customizer parameter file (default the template's name with ".json")`)
+	fs.StringVar(&f.paramSet, "P", "", "Customizer parameter set every sample starts from")
+	return f
+}
+
+// loadSettings merges the config file, environment and flags into a single
+// validated config. On failure it also returns the exit code to use.
+func loadSettings(fs *flag.FlagSet, f *settingsFlags, getenv func(string) string) (*config.Config, int, error) {
+	set := map[string]bool{}
+	fs.Visit(func(fl *flag.Flag) { set[fl.Name] = true })
+
+	configPath := getenv(envConfig)
+	if set["config"] {
+		configPath = f.configPath
+	}
+	if configPath == "" {
+		for _, candidate := range []string{localConfigFile, config.DefaultConfigPath()} {
+			if _, err := os.Stat(candidate); err == nil {
+				configPath = candidate
+				break
+			}
+		}
+	}
+
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		return nil, exitParseError, err
+	}
+
+	if err := applyEnv(cfg, getenv); err != nil {
+		return nil, exitUsage, err
+	}
+
+	if set["csv"] {
+		cfg.CSVFile = f.csvFile
+	}
+	if set["output"] {
+		cfg.OutputDir = f.outputDir
+	}
+	if set["scad"] {
+		cfg.ScadFile = f.scadFile
+	}
+	if set["openscad"] {
+		cfg.OpenSCADPath = f.openscad
+	}
+	if set["workers"] {
+		cfg.MaxWorkers = f.workers
+	}
+	if set["timeout"] {
+		cfg.SampleTimeout = config.Duration(f.timeout)
+	}
+	if set["warning-policy"] {
+		cfg.WarningPolicy = f.warnings
+	}
+	if set["on-conflict"] {
+		cfg.OnConflict = f.onConflict
+	}
+	if set["csv-mode"] {
+		cfg.CSVMode = f.csvMode
+	}
+	if set["input-format"] {
+		cfg.InputFormat = f.input
+	}
+	if set["verbose"] {
+		cfg.Verbose = f.verbose
+	}
+	if set["dry-run"] {
+		cfg.DryRun = f.dryRun
+	}
+	if set["force"] {
+		cfg.Force = f.force
+	}
+	if set["cache"] {
+		cfg.Cache = f.cache
+	}
+	if set["cache-dir"] {
+		cfg.CacheDir = f.cacheDir
+	}
+	if set["cache-max-size"] {
+		cfg.CacheMaxSize = f.cacheSize
+	}
+	if set["p"] {
+		cfg.ParameterFile = f.paramFile
+	}
+	if set["P"] {
+		cfg.ParameterSet = f.paramSet
+	}
+	if len(f.defines) > 0 {
+		params := make(map[string]any, len(cfg.ScadParams)+len(f.defines))
+		for name, value := range cfg.ScadParams {
+			params[name] = value
+		}
+		for _, define := range f.defines {
+			name, value, _ := strings.Cut(define, "=")
+			params[strings.TrimSpace(name)] = scad.ParseValue(value)
+		}
+		cfg.ScadParams = params
+	}
+
+	applyDefaults(cfg)
+
+	if err := cfg.Validate(); err != nil {
+		return nil, exitUsage, err
+	}
+
+	return cfg, exitOK, nil
+}
+
+func applyEnv(cfg *config.Config, getenv func(string) string) error {
+	if v := getenv(envCSV); v != "" {
+		cfg.CSVFile = v
+	}
+	if v := getenv(envOutput); v != "" {
+		cfg.OutputDir = v
+	}
+	if v := getenv(envScad); v != "" {
+		cfg.ScadFile = v
+	}
+	if v := getenv(envWorkers); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q: must be an integer", envWorkers, v)
+		}
+		cfg.MaxWorkers = n
+	}
+	if v := getenv(envTimeout); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q: must be a duration such as 90s", envTimeout, v)
+		}
+		cfg.SampleTimeout = config.Duration(d)
+	}
+	if v := getenv(envWarnings); v != "" {
+		cfg.WarningPolicy = v
+	}
+	if v := getenv(envConflict); v != "" {
+		cfg.OnConflict = v
+	}
+	if v := getenv(envCSVMode); v != "" {
+		cfg.CSVMode = v
+	}
+	if v := getenv(envInput); v != "" {
+		cfg.InputFormat = v
+	}
+	if v := getenv(envVerbose); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q: must be a boolean", envVerbose, v)
+		}
+		cfg.Verbose = b
+	}
+	if v := getenv(envDryRun); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q: must be a boolean", envDryRun, v)
+		}
+		cfg.DryRun = b
+	}
+	if v := getenv(envForce); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q: must be a boolean", envForce, v)
+		}
+		cfg.Force = b
+	}
+	if v := getenv(envCache); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q: must be a boolean", envCache, v)
+		}
+		cfg.Cache = b
+	}
+	if v := getenv(envCacheDir); v != "" {
+		cfg.CacheDir = v
+	}
+	return nil
+}
+
+// applyDefaults fills in paths that are relative to the CSV file when no
+// other source provided them.
+func applyDefaults(cfg *config.Config) {
+	if cfg.CSVFile == "" {
+		cfg.CSVFile = defaultCSVFile
+	}
+
+	csvDir := input.BaseDir(cfg.CSVFile)
+	if cfg.OutputDir == "" {
+		cfg.OutputDir = filepath.Join(csvDir, defaultSTLDir)
+	}
+	if cfg.ScadFile == "" {
+		cfg.ScadFile = filepath.Join(csvDir, defaultScadFile)
+	}
+}
+
+// argOptions returns how samples are passed to the template under cfg: the
+// run-wide parameters, which Validate has checked, and the Customizer
+// parameter sets typed by schema, which may be nil.
+func argOptions(cfg *config.Config, schema *scad.Schema) (models.ArgOptions, error) {
+	params, _ := cfg.ParamLiterals()
+	sets, err := generator.ParameterSets(cfg, schema)
+	if err != nil {
+		return models.ArgOptions{}, err
+	}
+	return models.ArgOptions{
+		TemperatureFormat: cfg.TemperatureFormat,
+		Params:            params,
+		ParameterSets:     sets,
+		ParameterSet:      cfg.ParameterSet,
+	}, nil
+}
+
+// newParser returns a sample list parser that fills in missing temperatures
+// from the built-in and configured material profiles. CSV columns the
+// template does not define are ignored with a warning to w, and so are the
+// rows skipped in lenient mode.
+func newParser(cfg *config.Config, w io.Writer) *input.Parser {
+	parser := input.NewParser()
+	parser.Format = cfg.InputFormat
+	parser.Stdin = stdin
+	parser.Profiles = cfg.ProfileLibrary()
+	parser.Lenient = cfg.CSVMode == config.CSVModeLenient
+	parser.Skipped = func(err *csv.RowError) {
+		fmt.Fprintf(w, "Warning: skipping %v\n", err)
+	}
+	// An unreadable template keeps every column.
+	parser.Schema, _ = openscad.TemplateSchema(cfg.ScadFile)
+	parser.Ignored = func(source, column string) {
+		fmt.Fprintf(w, "Warning: %s: ignoring column %s, which the template does not define\n", source, column)
+	}
+	return parser
+}
@@ -1,3 +1,8 @@
 module github.com/guntharp/go-filamentsamples
 
 go 1.23.2
+
+require (
+	github.com/BurntSushi/toml v1.6.0
+	gopkg.in/yaml.v3 v3.0.1
+)
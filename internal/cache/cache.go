@@ -0,0 +1,260 @@
+// Package cache stores rendered outputs under the hash of everything they
+// were rendered from, so identical samples are rendered once and shared
+// between projects.
+package cache
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/guntharp/go-filamentsamples/internal/fsutil"
+	"github.com/guntharp/go-filamentsamples/internal/openscad"
+)
+
+// DefaultMaxSize is the size the cache is pruned to when no limit is set.
+const DefaultMaxSize = 1 << 30
+
+// tempPrefix marks files that are still being written into the cache.
+const tempPrefix = ".tmp-"
+
+// diagnosticsExt is appended to the path of an entry to name the file that
+// holds the diagnostics OpenSCAD printed while rendering it.
+const diagnosticsExt = ".json"
+
+// Cache is a directory of rendered outputs addressed by content hash.
+type Cache struct {
+	Dir string
+}
+
+// New returns a cache rooted at dir.
+func New(dir string) *Cache {
+	return &Cache{Dir: dir}
+}
+
+// DefaultDir returns filament-samples inside the user's cache directory,
+// which is $XDG_CACHE_HOME or ~/.cache on Linux.
+func DefaultDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot determine cache directory: %w", err)
+	}
+	return filepath.Join(dir, "filament-samples"), nil
+}
+
+// path returns where the entry for key is stored. The extension keeps
+// outputs of different formats rendered from the same inputs apart.
+func (c *Cache) path(key, ext string) string {
+	return filepath.Join(c.Dir, key[:2], key+ext)
+}
+
+// Get copies the cached entry for key to dst and returns the diagnostics
+// stored with it. The entry is copied rather than linked, so dst can be
+// changed without touching the cache. It reports false if there is no such
+// entry, or if it was stored without diagnostics.
+func (c *Cache) Get(key, ext, dst string) ([]openscad.Diagnostic, bool, error) {
+	src := c.path(key, ext)
+	data, err := os.ReadFile(src + diagnosticsExt)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	var diagnostics []openscad.Diagnostic
+	if err := json.Unmarshal(data, &diagnostics); err != nil {
+		return nil, false, fmt.Errorf("failed to read cache entry: %w", err)
+	}
+
+	if err := copyFile(src, dst); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("failed to copy from cache: %w", err)
+	}
+
+	// The modification time records the last use for eviction.
+	now := time.Now()
+	os.Chtimes(src, now, now)
+
+	return diagnostics, true, nil
+}
+
+// Put stores a copy of src as the entry for key, together with the
+// diagnostics OpenSCAD printed while rendering it.
+func (c *Cache) Put(key, ext, src string, diagnostics []openscad.Diagnostic) error {
+	dst := c.path(key, ext)
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	defer in.Close()
+	if err := replaceFile(dst, in); err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+
+	if diagnostics == nil {
+		diagnostics = []openscad.Diagnostic{}
+	}
+	data, err := json.Marshal(diagnostics)
+	if err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if err := replaceFile(dst+diagnosticsExt, bytes.NewReader(data)); err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	return nil
+}
+
+// replaceFile replaces path with the content of r atomically.
+func replaceFile(path string, r io.Reader) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), tempPrefix+"*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = io.Copy(tmp, r)
+	if err == nil {
+		err = tmp.Chmod(fsutil.FileMode)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Stats describes the content of the cache.
+type Stats struct {
+	Entries int
+	Size    int64
+	Oldest  time.Time
+	Newest  time.Time
+}
+
+// Stats reports the number and total size of the cached entries.
+func (c *Cache) Stats() (Stats, error) {
+	entries, err := c.entries()
+	if err != nil {
+		return Stats{}, err
+	}
+
+	var stats Stats
+	for _, e := range entries {
+		stats.Entries++
+		stats.Size += e.size
+		if stats.Oldest.IsZero() || e.used.Before(stats.Oldest) {
+			stats.Oldest = e.used
+		}
+		if e.used.After(stats.Newest) {
+			stats.Newest = e.used
+		}
+	}
+	return stats, nil
+}
+
+// PruneResult reports what Prune removed.
+type PruneResult struct {
+	Removed int
+	Freed   int64
+}
+
+// Prune removes the least recently used entries until the cache is no
+// larger than maxSize bytes. A maxSize of zero empties the cache.
+func (c *Cache) Prune(maxSize int64) (PruneResult, error) {
+	entries, err := c.entries()
+	if err != nil {
+		return PruneResult{}, err
+	}
+
+	var size int64
+	for _, e := range entries {
+		size += e.size
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].used.Before(entries[j].used) })
+
+	var result PruneResult
+	for _, e := range entries {
+		if size <= maxSize {
+			break
+		}
+		if err := os.Remove(e.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return result, fmt.Errorf("failed to remove cache entry: %w", err)
+		}
+		if err := os.Remove(e.path + diagnosticsExt); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return result, fmt.Errorf("failed to remove cache entry: %w", err)
+		}
+		size -= e.size
+		result.Removed++
+		result.Freed += e.size
+	}
+	return result, nil
+}
+
+type entry struct {
+	path string
+	size int64
+	used time.Time
+}
+
+// entries lists the cached renders, leaving out their diagnostics. A cache
+// directory that does not exist yet is empty.
+func (c *Cache) entries() ([]entry, error) {
+	var entries []entry
+	err := filepath.WalkDir(c.Dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == c.Dir && errors.Is(err, fs.ErrNotExist) {
+				return fs.SkipAll
+			}
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), tempPrefix) || strings.HasSuffix(d.Name(), diagnosticsExt) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		entries = append(entries, entry{path: path, size: info.Size(), used: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache: %w", err)
+	}
+	return entries, nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,149 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/guntharp/go-filamentsamples/internal/fsutil"
+	"github.com/guntharp/go-filamentsamples/internal/openscad"
+)
+
+const testKey = "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCache_PutGet(t *testing.T) {
+	c := New(filepath.Join(t.TempDir(), "cache"))
+	work := t.TempDir()
+	dst := filepath.Join(work, "out.stl")
+
+	_, hit, err := c.Get(testKey, ".stl", dst)
+	if err != nil || hit {
+		t.Fatalf("Get() on empty cache = %v, %v; want miss", hit, err)
+	}
+
+	src := filepath.Join(work, "rendered.stl")
+	writeFile(t, src, "solid card")
+	warning := openscad.Diagnostic{Severity: openscad.SeverityWarning, Message: "scaled to zero", Line: 12}
+	if err := c.Put(testKey, ".stl", src, []openscad.Diagnostic{warning}); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	// A stale output at the destination is replaced.
+	writeFile(t, dst, "old")
+	diagnostics, hit, err := c.Get(testKey, ".stl", dst)
+	if err != nil || !hit {
+		t.Fatalf("Get() = %v, %v; want hit", hit, err)
+	}
+	if data, _ := os.ReadFile(dst); string(data) != "solid card" {
+		t.Errorf("Get() wrote %q", data)
+	}
+	if len(diagnostics) != 1 || diagnostics[0] != warning {
+		t.Errorf("Get() diagnostics = %v, want %v", diagnostics, warning)
+	}
+
+	// The output is a copy, not a link to the cached file.
+	cached, _ := os.Stat(c.path(testKey, ".stl"))
+	if runtime.GOOS != "windows" && cached.Mode().Perm() != fsutil.FileMode {
+		t.Errorf("cache entry mode = %v, want %v", cached.Mode().Perm(), fsutil.FileMode)
+	}
+	out, _ := os.Stat(dst)
+	if os.SameFile(cached, out) {
+		t.Error("Get() linked the output to the cache entry")
+	}
+
+	// Another format rendered from the same inputs is a separate entry.
+	if _, hit, _ := c.Get(testKey, ".3mf", filepath.Join(work, "out.3mf")); hit {
+		t.Error("Get() with a different extension should miss")
+	}
+
+	// Changing the source after Put does not change the cached copy.
+	writeFile(t, src, "changed")
+	os.Remove(dst)
+	c.Get(testKey, ".stl", dst)
+	if data, _ := os.ReadFile(dst); string(data) != "solid card" {
+		t.Errorf("cached entry changed to %q", data)
+	}
+
+	// An entry stored without diagnostics is a miss.
+	os.Remove(c.path(testKey, ".stl") + diagnosticsExt)
+	if _, hit, err := c.Get(testKey, ".stl", dst); err != nil || hit {
+		t.Errorf("Get() without diagnostics = %v, %v; want miss", hit, err)
+	}
+}
+
+func TestCache_StatsPrune(t *testing.T) {
+	c := New(filepath.Join(t.TempDir(), "cache"))
+
+	stats, err := c.Stats()
+	if err != nil || stats.Entries != 0 {
+		t.Fatalf("Stats() on missing directory = %+v, %v", stats, err)
+	}
+
+	work := t.TempDir()
+	keys := []string{"aa" + testKey[2:], "bb" + testKey[2:], "cc" + testKey[2:]}
+	for i, key := range keys {
+		src := filepath.Join(work, key)
+		writeFile(t, src, "0123456789")
+		if err := c.Put(key, ".stl", src, nil); err != nil {
+			t.Fatal(err)
+		}
+		used := time.Now().Add(time.Duration(i-len(keys)) * time.Hour)
+		os.Chtimes(c.path(key, ".stl"), used, used)
+	}
+
+	// Using the oldest entry makes it the most recently used.
+	if _, _, err := c.Get(keys[0], ".stl", filepath.Join(work, "out.stl")); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err = c.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Entries != 3 || stats.Size != 30 {
+		t.Errorf("Stats() = %+v, want 3 entries of 30 bytes", stats)
+	}
+
+	result, err := c.Prune(15)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Removed != 2 || result.Freed != 20 {
+		t.Errorf("Prune(15) = %+v, want 2 removed, 20 freed", result)
+	}
+	if _, err := os.Stat(c.path(keys[0], ".stl")); err != nil {
+		t.Error("the most recently used entry should be kept")
+	}
+	if _, err := os.Stat(c.path(keys[1], ".stl") + diagnosticsExt); err == nil {
+		t.Error("the diagnostics of an evicted entry should be removed")
+	}
+
+	result, err = c.Prune(0)
+	if err != nil || result.Removed != 1 {
+		t.Errorf("Prune(0) = %+v, %v; want the last entry removed", result, err)
+	}
+}
+
+func TestDefaultDir(t *testing.T) {
+	if os.Getenv("HOME") == "" {
+		t.Skip("HOME not set")
+	}
+	t.Setenv("XDG_CACHE_HOME", "/tmp/xdg-cache")
+
+	dir, err := DefaultDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(dir) != "filament-samples" {
+		t.Errorf("DefaultDir() = %s", dir)
+	}
+}
@@ -0,0 +1,89 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ByteSize is a size in bytes that is written to JSON as a string such as
+// "512MB" and read from either that form or a plain number of bytes.
+type ByteSize int64
+
+var byteUnits = []struct {
+	suffix string
+	size   int64
+}{
+	{"GiB", 1 << 30}, {"MiB", 1 << 20}, {"KiB", 1 << 10},
+	{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10},
+	{"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10},
+	{"B", 1},
+}
+
+// ParseByteSize parses sizes such as "1GB", "512MiB", "100K" or "4096".
+// Units are binary: 1KB is 1024 bytes.
+func ParseByteSize(s string) (ByteSize, error) {
+	value := strings.TrimSpace(s)
+	multiplier := int64(1)
+	for _, u := range byteUnits {
+		if len(value) > len(u.suffix) && strings.EqualFold(value[len(value)-len(u.suffix):], u.suffix) {
+			value = strings.TrimSpace(value[:len(value)-len(u.suffix)])
+			multiplier = u.size
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return ByteSize(n * float64(multiplier)), nil
+}
+
+func (b ByteSize) String() string {
+	for _, u := range []struct {
+		suffix string
+		size   int64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}} {
+		if int64(b) >= u.size {
+			return strconv.FormatFloat(float64(b)/float64(u.size), 'f', -1, 64) + u.suffix
+		}
+	}
+	return strconv.FormatInt(int64(b), 10) + "B"
+}
+
+// Set implements flag.Value.
+func (b *ByteSize) Set(s string) error {
+	parsed, err := ParseByteSize(s)
+	if err != nil {
+		return err
+	}
+	*b = parsed
+	return nil
+}
+
+func (b ByteSize) MarshalJSON() ([]byte, error) {
+	return json.Marshal(b.String())
+}
+
+func (b *ByteSize) UnmarshalJSON(data []byte) error {
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+
+	switch v := value.(type) {
+	case float64:
+		if v < 0 {
+			return fmt.Errorf("invalid size %s", data)
+		}
+		*b = ByteSize(v)
+	case string:
+		return b.Set(v)
+	default:
+		return fmt.Errorf("invalid size %s", data)
+	}
+
+	return nil
+}
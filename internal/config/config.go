@@ -6,18 +6,93 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"sort"
+	"time"
+
+	"github.com/guntharp/go-filamentsamples/internal/input"
+	"github.com/guntharp/go-filamentsamples/internal/materials"
+	"github.com/guntharp/go-filamentsamples/internal/profiles"
+	"github.com/guntharp/go-filamentsamples/pkg/models"
+	"github.com/guntharp/go-filamentsamples/pkg/scad"
 )
 
 type Config struct {
-	CSVFile      string `json:"csv_file"`
-	OutputDir    string `json:"output_dir"`
-	ScadFile     string `json:"scad_file"`
-	MaxWorkers   int    `json:"max_workers"`
-	Verbose      bool   `json:"verbose"`
-	DryRun       bool   `json:"dry_run"`
+	CSVFile    string `json:"csv_file"`
+	OutputDir  string `json:"output_dir"`
+	ScadFile   string `json:"scad_file"`
+	MaxWorkers int    `json:"max_workers"`
+	Verbose    bool   `json:"verbose"`
+	DryRun     bool   `json:"dry_run"`
+	// Force rebuilds every sample, ignoring the build manifest.
+	Force        bool   `json:"force"`
 	OpenSCADPath string `json:"openscad_path"`
+	// SampleTimeout limits how long a single OpenSCAD render may run.
+	// Zero disables the limit.
+	SampleTimeout Duration `json:"sample_timeout"`
+	// WarningPolicy decides what OpenSCAD warnings do to a sample: "report"
+	// logs them and fails only samples that reference undefined variables,
+	// modules or functions, "fail" fails a sample on any warning and
+	// "ignore" hides the other warnings unless verbose. Undefined references
+	// fail a sample under every policy.
+	WarningPolicy string `json:"warning_policy"`
+	// OnConflict decides what happens to an existing output file:
+	// "overwrite" replaces it, "skip" keeps it and does not render the
+	// sample, and "keep-both" renames it to a numbered name and writes the
+	// new render in its place.
+	OnConflict string `json:"on_conflict"`
+	// CSVMode decides what invalid CSV rows do: "strict" rejects the file
+	// listing every invalid row, "lenient" skips them with a warning.
+	CSVMode string `json:"csv_mode"`
+	// InputFormat reads the sample list as "csv", "json", "yaml" or "toml".
+	// Empty chooses by file extension.
+	InputFormat string `json:"input_format,omitempty"`
+	// TemperatureFormat controls how temperatures are printed on the card.
+	TemperatureFormat models.TemperatureFormat `json:"temperature_format"`
+	// Cache enables the render cache shared between projects. CacheDir
+	// defaults to filament-samples in the user's cache directory and
+	// CacheMaxSize, the size the cache is pruned to after each run, to 1GB.
+	Cache        bool     `json:"cache"`
+	CacheDir     string   `json:"cache_dir"`
+	CacheMaxSize ByteSize `json:"cache_max_size"`
+	// Materials overrides or extends the built-in temperature limits used
+	// to check samples, keyed by material such as "PLA" or "PA-CF".
+	Materials map[string]materials.Rule `json:"materials,omitempty"`
+	// Profiles adds to the built-in material profiles that fill in
+	// temperatures left empty in the CSV file.
+	Profiles []profiles.Profile `json:"profiles,omitempty"`
+	// ScadParams overrides template defaults for every sample, keyed by
+	// variable name such as "FONT" or "$fn". Values are numbers, strings,
+	// booleans, null for undef, or lists of those. Parameters of a sample
+	// take precedence.
+	ScadParams map[string]any `json:"scad_params,omitempty"`
+	// ParameterFile is an OpenSCAD Customizer parameter file, defaulting to
+	// the .json file named like the template. ParameterSet names the set
+	// of it every sample starts from unless it chooses its own; its
+	// values give way to ScadParams.
+	ParameterFile string `json:"parameter_file,omitempty"`
+	ParameterSet  string `json:"parameter_set,omitempty"`
 }
 
+// Warning policies accepted in Config.WarningPolicy.
+const (
+	WarningPolicyReport = "report"
+	WarningPolicyFail   = "fail"
+	WarningPolicyIgnore = "ignore"
+)
+
+// CSV modes accepted in Config.CSVMode.
+const (
+	CSVModeStrict  = "strict"
+	CSVModeLenient = "lenient"
+)
+
+// Conflict policies accepted in Config.OnConflict.
+const (
+	OnConflictOverwrite = "overwrite"
+	OnConflictSkip      = "skip"
+	OnConflictKeepBoth  = "keep-both"
+)
+
 func LoadConfig(configPath string) (*Config, error) {
 	config := &Config{
 		MaxWorkers: runtime.NumCPU(),
@@ -68,6 +143,129 @@ func (c *Config) Validate() error {
 		c.MaxWorkers = 32
 	}
 
+	if c.SampleTimeout < 0 {
+		return fmt.Errorf("sample_timeout must not be negative")
+	}
+
+	switch c.WarningPolicy {
+	case "":
+		c.WarningPolicy = WarningPolicyReport
+	case WarningPolicyReport, WarningPolicyFail, WarningPolicyIgnore:
+	default:
+		return fmt.Errorf("warning_policy must be %q, %q or %q, got %q",
+			WarningPolicyReport, WarningPolicyFail, WarningPolicyIgnore, c.WarningPolicy)
+	}
+
+	switch c.OnConflict {
+	case "":
+		c.OnConflict = OnConflictOverwrite
+	case OnConflictOverwrite, OnConflictSkip, OnConflictKeepBoth:
+	default:
+		return fmt.Errorf("on_conflict must be %q, %q or %q, got %q",
+			OnConflictOverwrite, OnConflictSkip, OnConflictKeepBoth, c.OnConflict)
+	}
+
+	switch c.CSVMode {
+	case "":
+		c.CSVMode = CSVModeStrict
+	case CSVModeStrict, CSVModeLenient:
+	default:
+		return fmt.Errorf("csv_mode must be %q or %q, got %q", CSVModeStrict, CSVModeLenient, c.CSVMode)
+	}
+
+	if !input.ValidFormat(c.InputFormat) {
+		return fmt.Errorf("input_format must be %q, %q, %q or %q, got %q",
+			input.FormatCSV, input.FormatJSON, input.FormatYAML, input.FormatTOML, c.InputFormat)
+	}
+
+	if err := c.TemperatureFormat.Validate(); err != nil {
+		return fmt.Errorf("temperature_format: %w", err)
+	}
+
+	if err := c.MaterialRules().Validate(); err != nil {
+		return fmt.Errorf("materials: %w", err)
+	}
+
+	for i, p := range c.Profiles {
+		if err := p.Validate(); err != nil {
+			return fmt.Errorf("profiles[%d]: %w", i, err)
+		}
+	}
+
+	if _, err := c.ParamLiterals(); err != nil {
+		return fmt.Errorf("scad_params: %w", err)
+	}
+
+	if c.OpenSCADPath != "" {
+		if err := validateExecutable(c.OpenSCADPath); err != nil {
+			return fmt.Errorf("openscad_path: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// MaterialRules returns the built-in material limits with the overrides
+// from Materials applied.
+func (c *Config) MaterialRules() materials.Rules {
+	return materials.Default().Merge(c.Materials)
+}
+
+// ProfileLibrary returns the built-in material profiles extended by
+// Profiles.
+func (c *Config) ProfileLibrary() *profiles.Library {
+	return profiles.New(c.Profiles)
+}
+
+// ScadParamNames returns the names in ScadParams in sorted order.
+func (c *Config) ScadParamNames() []string {
+	names := make([]string, 0, len(c.ScadParams))
+	for name := range c.ScadParams {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParamLiterals returns ScadParams encoded as OpenSCAD literals. It fails
+// for names -D cannot assign, names every sample sets itself such as BRAND,
+// and values with no literal form.
+func (c *Config) ParamLiterals() (map[string]string, error) {
+	literals := make(map[string]string, len(c.ScadParams))
+	for _, name := range c.ScadParamNames() {
+		value := c.ScadParams[name]
+		if !scad.ValidName(name) {
+			return nil, fmt.Errorf("invalid parameter name %q", name)
+		}
+		if models.IsFieldVariable(name) {
+			return nil, fmt.Errorf("%s is set from each sample and cannot be overridden", name)
+		}
+		literal, err := scad.Literal(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		literals[name] = literal
+	}
+	return literals, nil
+}
+
+func validateExecutable(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("%s does not exist", path)
+		}
+		return err
+	}
+
+	if info.IsDir() {
+		return fmt.Errorf("%s is a directory, not the OpenSCAD executable", path)
+	}
+
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0111 == 0 {
+		return fmt.Errorf("%s is not executable", path)
+	}
+
 	return nil
 }
 
@@ -81,11 +279,15 @@ func DefaultConfigPath() string {
 
 func ExampleConfig() *Config {
 	return &Config{
-		CSVFile:    "samples.csv",
-		OutputDir:  "stl",
-		ScadFile:   "FilamentSamples.scad",
-		MaxWorkers: 4,
-		Verbose:    false,
-		DryRun:     false,
-	}
-}
\ No newline at end of file
+		CSVFile:       "samples.csv",
+		OutputDir:     "stl",
+		ScadFile:      "FilamentSamples.scad",
+		MaxWorkers:    4,
+		Verbose:       false,
+		DryRun:        false,
+		SampleTimeout: Duration(5 * time.Minute),
+		WarningPolicy: WarningPolicyReport,
+		OnConflict:    OnConflictOverwrite,
+		CacheMaxSize:  ByteSize(1 << 30),
+	}
+}
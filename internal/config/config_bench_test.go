@@ -0,0 +1,35 @@
+package config
+
+import "testing"
+
+func BenchmarkConfig_Validate(b *testing.B) {
+	cfg := Config{
+		CSVFile:    "test.csv",
+		OutputDir:  "output",
+		MaxWorkers: 4,
+		Verbose:    false,
+		DryRun:     false,
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = cfg.Validate()
+	}
+}
+
+func BenchmarkConfig_WorkerValidation(b *testing.B) {
+	configs := []Config{
+		{CSVFile: "test.csv", MaxWorkers: 0},
+		{CSVFile: "test.csv", MaxWorkers: -5},
+		{CSVFile: "test.csv", MaxWorkers: 100},
+		{CSVFile: "test.csv", MaxWorkers: 4},
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, cfg := range configs {
+			cfgCopy := cfg // Avoid modifying the original
+			_ = cfgCopy.Validate()
+		}
+	}
+}
@@ -5,7 +5,13 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/guntharp/go-filamentsamples/internal/materials"
+	"github.com/guntharp/go-filamentsamples/internal/profiles"
+	"github.com/guntharp/go-filamentsamples/pkg/models"
 )
 
 func TestLoadConfig(t *testing.T) {
@@ -208,6 +214,107 @@ func TestConfig_Validate(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "unknown temperature unit",
+			config: Config{
+				CSVFile:           "test.csv",
+				MaxWorkers:        4,
+				TemperatureFormat: models.TemperatureFormat{Unit: "K"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown conflict policy",
+			config: Config{
+				CSVFile:    "test.csv",
+				MaxWorkers: 4,
+				OnConflict: "rename",
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown CSV mode",
+			config: Config{
+				CSVFile:    "test.csv",
+				MaxWorkers: 4,
+				CSVMode:    "forgiving",
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown input format",
+			config: Config{
+				CSVFile:     "test.csv",
+				MaxWorkers:  4,
+				InputFormat: "xml",
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown warning policy",
+			config: Config{
+				CSVFile:       "test.csv",
+				MaxWorkers:    4,
+				WarningPolicy: "explode",
+			},
+			wantErr: true,
+		},
+		{
+			name: "contradictory material limits",
+			config: Config{
+				CSVFile:    "test.csv",
+				MaxWorkers: 4,
+				Materials: map[string]materials.Rule{
+					"PLA": {Hotend: &materials.Limits{Min: 250, Max: 200}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "profile without type",
+			config: Config{
+				CSVFile:    "test.csv",
+				MaxWorkers: 4,
+				Profiles:   []profiles.Profile{{Brand: "Test", Hotend: "200"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative sample timeout",
+			config: Config{
+				CSVFile:       "test.csv",
+				MaxWorkers:    4,
+				SampleTimeout: Duration(-time.Second),
+			},
+			wantErr: true,
+		},
+		{
+			name: "scad params",
+			config: Config{
+				CSVFile:    "test.csv",
+				MaxWorkers: 4,
+				ScadParams: map[string]any{"FONT": "Noto Sans", "$fn": 100.0, "INSET_DEPTHS": []any{0.4, 0.2}, "INVERT_CARD": false},
+			},
+			wantErr: false,
+		},
+		{
+			name: "scad param set by each sample",
+			config: Config{
+				CSVFile:    "test.csv",
+				MaxWorkers: 4,
+				ScadParams: map[string]any{"BRAND": "Mine"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "scad param without a literal",
+			config: Config{
+				CSVFile:    "test.csv",
+				MaxWorkers: 4,
+				ScadParams: map[string]any{"CARD": map[string]any{"x": 1.0}},
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -230,6 +337,52 @@ func TestConfig_Validate(t *testing.T) {
 	}
 }
 
+func TestConfig_Validate_OpenSCADPath(t *testing.T) {
+	tempDir := t.TempDir()
+	executable := filepath.Join(tempDir, "openscad")
+	if err := os.WriteFile(executable, []byte("fake"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	notExecutable := filepath.Join(tempDir, "openscad.txt")
+	if err := os.WriteFile(notExecutable, []byte("fake"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		wantErr string
+	}{
+		{name: "unset", path: ""},
+		{name: "executable", path: executable},
+		{name: "missing", path: filepath.Join(tempDir, "missing"), wantErr: "does not exist"},
+		{name: "directory", path: tempDir, wantErr: "is a directory"},
+	}
+	if runtime.GOOS != "windows" {
+		tests = append(tests, struct {
+			name    string
+			path    string
+			wantErr string
+		}{name: "not executable", path: notExecutable, wantErr: "is not executable"})
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{CSVFile: "test.csv", MaxWorkers: 1, OpenSCADPath: tt.path}
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
 func TestDefaultConfigPath(t *testing.T) {
 	path := DefaultConfigPath()
 	if path == "" {
@@ -346,4 +499,84 @@ func TestConfig_JSONMarshaling(t *testing.T) {
 	if restored.OpenSCADPath != original.OpenSCADPath {
 		t.Errorf("OpenSCADPath mismatch: expected %s, got %s", original.OpenSCADPath, restored.OpenSCADPath)
 	}
-}
\ No newline at end of file
+}
+func TestDuration_JSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		json    string
+		want    Duration
+		wantErr bool
+	}{
+		{name: "string", json: `{"sample_timeout": "90s"}`, want: Duration(90 * time.Second)},
+		{name: "seconds", json: `{"sample_timeout": 120}`, want: Duration(2 * time.Minute)},
+		{name: "invalid string", json: `{"sample_timeout": "soon"}`, wantErr: true},
+		{name: "invalid type", json: `{"sample_timeout": true}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{}
+			err := json.Unmarshal([]byte(tt.json), cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Unmarshal() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && cfg.SampleTimeout != tt.want {
+				t.Errorf("SampleTimeout = %v, want %v", time.Duration(cfg.SampleTimeout), time.Duration(tt.want))
+			}
+		})
+	}
+
+	data, err := json.Marshal(&Config{SampleTimeout: Duration(5 * time.Minute)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"sample_timeout":"5m0s"`) {
+		t.Errorf("Marshal() = %s, want sample_timeout as a duration string", data)
+	}
+}
+
+func TestByteSize(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    ByteSize
+		wantErr bool
+	}{
+		{input: "4096", want: 4096},
+		{input: "100K", want: 100 << 10},
+		{input: "512MiB", want: 512 << 20},
+		{input: "1.5 GB", want: 3 << 29},
+		{input: "2g", want: 2 << 30},
+		{input: "10B", want: 10},
+		{input: "lots", wantErr: true},
+		{input: "-1MB", wantErr: true},
+		{input: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseByteSize(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseByteSize() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseByteSize() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+
+	cfg := &Config{}
+	if err := json.Unmarshal([]byte(`{"cache_max_size": "256MB"}`), cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.CacheMaxSize != 256<<20 {
+		t.Errorf("CacheMaxSize = %d, want %d", cfg.CacheMaxSize, 256<<20)
+	}
+	if err := json.Unmarshal([]byte(`{"cache_max_size": 1048576}`), cfg); err != nil || cfg.CacheMaxSize != 1<<20 {
+		t.Errorf("CacheMaxSize from bytes = %d, %v", cfg.CacheMaxSize, err)
+	}
+
+	data, _ := json.Marshal(&Config{CacheMaxSize: ByteSize(1 << 30)})
+	if !strings.Contains(string(data), `"cache_max_size":"1GB"`) {
+		t.Errorf("Marshal() = %s, want cache_max_size as a size string", data)
+	}
+}
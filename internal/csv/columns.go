@@ -0,0 +1,194 @@
+package csv
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/guntharp/go-filamentsamples/pkg/models"
+	"github.com/guntharp/go-filamentsamples/pkg/scad"
+)
+
+// Sample fields a CSV column can map to.
+const (
+	colBrand      = models.FieldBrand
+	colType       = models.FieldType
+	colColor      = models.FieldColor
+	colTempHotend = models.FieldTempHotend
+	colTempBed    = models.FieldTempBed
+	colBrandSize  = models.FieldBrandSize
+	colTypeSize   = models.FieldTypeSize
+	colColorSize  = models.FieldColorSize
+	colNotes      = "Notes"
+	colQuantity   = models.FieldQuantity
+	colParams     = models.FieldParams
+	colSet        = models.FieldParameterSet
+)
+
+// positionalFields is the column order of files without a header.
+var positionalFields = []string{
+	colBrand, colType, colColor, colTempHotend, colTempBed, colBrandSize, colTypeSize, colColorSize,
+}
+
+// requiredFields must have a column in a header. Temperatures may be left
+// out entirely and taken from material profiles.
+var requiredFields = []string{colBrand, colType, colColor}
+
+// columnAliases maps normalized header names to sample fields. Headers are
+// normalized by lower-casing them and dropping everything but letters and
+// digits, so "Nozzle Temp", "nozzle_temp" and "NozzleTemp" are the same.
+var columnAliases = map[string]string{
+	"brand":        colBrand,
+	"manufacturer": colBrand,
+	"vendor":       colBrand,
+
+	"type":         colType,
+	"material":     colType,
+	"filament":     colType,
+	"filamenttype": colType,
+
+	"color":  colColor,
+	"colour": colColor,
+
+	"temphotend":   colTempHotend,
+	"hotend":       colTempHotend,
+	"hotendtemp":   colTempHotend,
+	"nozzle":       colTempHotend,
+	"nozzletemp":   colTempHotend,
+	"printtemp":    colTempHotend,
+	"extrudertemp": colTempHotend,
+
+	"tempbed":   colTempBed,
+	"bed":       colTempBed,
+	"bedtemp":   colTempBed,
+	"platetemp": colTempBed,
+
+	"brandsize":        colBrandSize,
+	"brandfontsize":    colBrandSize,
+	"typesize":         colTypeSize,
+	"typefontsize":     colTypeSize,
+	"materialsize":     colTypeSize,
+	"materialfontsize": colTypeSize,
+	"colorsize":        colColorSize,
+	"colorfontsize":    colColorSize,
+	"colourfontsize":   colColorSize,
+}
+
+// otherColumns maps normalized header names to the columns that hold no
+// template field: free text notes, a quantity, a list of template
+// parameters written as NAME=value;NAME=value and the name of a Customizer
+// parameter set.
+var otherColumns = map[string]string{
+	"notes":      colNotes,
+	"note":       colNotes,
+	"quantity":   colQuantity,
+	"qty":        colQuantity,
+	"params":     colParams,
+	"parameters": colParams,
+
+	"parameterset": colSet,
+	"preset":       colSet,
+}
+
+func normalizeHeader(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, name)
+}
+
+// FieldForName returns the sample field, such as models.FieldTempHotend,
+// that a column or key name refers to, accepting the same aliases as CSV
+// headers.
+func FieldForName(name string) (string, bool) {
+	field, ok := columnAliases[normalizeHeader(name)]
+	return field, ok
+}
+
+// columns maps sample fields to record positions. Other columns named like
+// an OpenSCAD variable, such as CARD_THICKNESS, are template parameters
+// kept in extra; any other column is ignored.
+type columns struct {
+	fields map[string]int
+	extra  map[int]string
+	header []string
+}
+
+// positional returns the mapping of files without a header.
+func positional() columns {
+	c := columns{fields: make(map[string]int, len(positionalFields))}
+	for i, field := range positionalFields {
+		c.fields[field] = i
+	}
+	return c
+}
+
+// mapHeader maps the columns of a header row by name. It fails when a
+// required column is missing or a field is named twice.
+func mapHeader(header []string) (columns, error) {
+	c := columns{fields: make(map[string]int), extra: make(map[int]string), header: header}
+	for i, name := range header {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		field, ok := columnAliases[normalizeHeader(name)]
+		if !ok {
+			field, ok = otherColumns[normalizeHeader(name)]
+		}
+		if !ok {
+			if scad.ValidName(name) {
+				c.extra[i] = name
+			}
+			continue
+		}
+		if prev, dup := c.fields[field]; dup {
+			return columns{}, fmt.Errorf("columns %q and %q both map to %s", header[prev], name, field)
+		}
+		c.fields[field] = i
+	}
+
+	var missing []string
+	for _, field := range requiredFields {
+		if _, ok := c.fields[field]; !ok {
+			missing = append(missing, field)
+		}
+	}
+	if len(missing) > 0 {
+		return columns{}, fmt.Errorf("header is missing required columns: %s", strings.Join(missing, ", "))
+	}
+	return c, nil
+}
+
+// get returns the trimmed value of field in record, or "" when the column
+// is absent.
+func (c columns) get(record []string, field string) string {
+	i, ok := c.fields[field]
+	if !ok || i >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
+
+// name returns the column name of field as written in the header, or the
+// field name for files without a header.
+func (c columns) name(field string) string {
+	if i, ok := c.fields[field]; ok && i < len(c.header) {
+		return strings.TrimSpace(c.header[i])
+	}
+	return field
+}
+
+// isHeader reports whether record names at least two sample fields, which
+// no data row does.
+func isHeader(record []string) bool {
+	known := 0
+	for _, name := range record {
+		if _, ok := columnAliases[normalizeHeader(name)]; ok {
+			known++
+		}
+	}
+	return known >= 2
+}
@@ -0,0 +1,75 @@
+package csv
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMapHeader(t *testing.T) {
+	tests := []struct {
+		name      string
+		header    []string
+		want      map[string]int
+		wantExtra map[int]string
+		wantErr   string
+	}{
+		{
+			name:   "canonical names",
+			header: []string{"Brand", "Type", "Color", "TempHotend", "TempBed"},
+			want:   map[string]int{colBrand: 0, colType: 1, colColor: 2, colTempHotend: 3, colTempBed: 4},
+		},
+		{
+			name:   "swatches aliases",
+			header: []string{"Brand", "Material", "Color", "NozzleTemp", "BedTemp", "BrandFontSize", "MaterialFontSize", "ColorFontSize"},
+			want: map[string]int{colBrand: 0, colType: 1, colColor: 2, colTempHotend: 3, colTempBed: 4,
+				colBrandSize: 5, colTypeSize: 6, colColorSize: 7},
+		},
+		{
+			name:      "reordered with spacing and extra columns",
+			header:    []string{"colour", "Nozzle Temp", "card_thickness", "Manufacturer", "bed_temp", "material", ""},
+			want:      map[string]int{colColor: 0, colTempHotend: 1, colBrand: 3, colTempBed: 4, colType: 5},
+			wantExtra: map[int]string{2: "card_thickness"},
+		},
+		{
+			name:    "missing required columns",
+			header:  []string{"Brand", "NozzleTemp", "BedTemp"},
+			wantErr: "missing required columns: Type, Color",
+		},
+		{
+			name:    "duplicate field",
+			header:  []string{"Brand", "Type", "Material", "Color"},
+			wantErr: `columns "Type" and "Material" both map to Type`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cols, err := mapHeader(tt.header)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("mapHeader() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("mapHeader() error = %v", err)
+			}
+			if len(cols.fields) != len(tt.want) {
+				t.Errorf("fields = %v, want %v", cols.fields, tt.want)
+			}
+			for field, i := range tt.want {
+				if cols.fields[field] != i {
+					t.Errorf("%s at %d, want %d", field, cols.fields[field], i)
+				}
+			}
+			if len(cols.extra) != len(tt.wantExtra) {
+				t.Errorf("extra = %v, want %v", cols.extra, tt.wantExtra)
+			}
+			for i, name := range tt.wantExtra {
+				if cols.extra[i] != name {
+					t.Errorf("extra[%d] = %q, want %q", i, cols.extra[i], name)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package csv
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/guntharp/go-filamentsamples/pkg/models"
+)
+
+// RowError is an invalid row of the input, or an invalid value in it when
+// Column is set.
+//
+// The same type reports problems in JSON, YAML and TOML files, where Column
+// is the key and Item the position of the sample in the list, used when the
+// line is not known. File names the input the row came from when it was
+// read through the input package.
+type RowError struct {
+	File   string `json:"file,omitempty"`
+	Line   int    `json:"line,omitempty"`
+	Item   int    `json:"item,omitempty"`
+	Column string `json:"column,omitempty"`
+	Value  string `json:"value,omitempty"`
+	Reason string `json:"reason"`
+}
+
+func (e *RowError) Error() string {
+	location := fmt.Sprintf("line %d", e.Line)
+	if e.Line == 0 && e.Item > 0 {
+		location = fmt.Sprintf("sample %d", e.Item)
+	}
+	if e.File != "" {
+		location = e.File + ": " + location
+	}
+	if e.Column == "" {
+		return fmt.Sprintf("%s: %s", location, e.Reason)
+	}
+	return fmt.Sprintf("%s, column %s: %s", location, e.Column, e.Reason)
+}
+
+// ParseError lists every invalid row found in one pass over the input.
+type ParseError struct {
+	Errors []*RowError
+}
+
+func (e *ParseError) Error() string {
+	if len(e.Errors) == 1 {
+		return e.Errors[0].Error()
+	}
+	lines := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		lines[i] = err.Error()
+	}
+	return fmt.Sprintf("%d errors:\n  %s", len(e.Errors), strings.Join(lines, "\n  "))
+}
+
+// Unwrap returns the row errors, so errors.As finds a *RowError.
+func (e *ParseError) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, err := range e.Errors {
+		errs[i] = err
+	}
+	return errs
+}
+
+// rowErrors turns the error of a row into row errors, one for each invalid
+// field reported by FilamentSample.Validate or the parser.
+func rowErrors(line int, cols columns, err error) []*RowError {
+	errs := flatten(err)
+	rows := make([]*RowError, 0, len(errs))
+	for _, err := range errs {
+		var fieldErr *models.FieldError
+		if errors.As(err, &fieldErr) {
+			rows = append(rows, &RowError{
+				Line:   line,
+				Column: cols.name(fieldErr.Field),
+				Value:  fieldErr.Value,
+				Reason: fieldErr.Err.Error(),
+			})
+			continue
+		}
+		rows = append(rows, &RowError{Line: line, Reason: err.Error()})
+	}
+	return rows
+}
+
+// flatten returns the errors joined in err, including those of nested
+// joins, or err itself.
+func flatten(err error) []error {
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		return []error{err}
+	}
+	var errs []error
+	for _, err := range joined.Unwrap() {
+		errs = append(errs, flatten(err)...)
+	}
+	return errs
+}
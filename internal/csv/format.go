@@ -0,0 +1,224 @@
+package csv
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// sniffSize is how much of the input is examined to detect its encoding and
+// delimiter.
+const sniffSize = 64 << 10
+
+// Encodings reported in Format.Encoding.
+const (
+	EncodingUTF8        = "UTF-8"
+	EncodingWindows1252 = "Windows-1252"
+)
+
+// Format describes how an input file was read, as detected from its content.
+type Format struct {
+	Encoding string `json:"encoding"`
+	// BOM is set when the file started with a UTF-8 byte order mark.
+	BOM       bool   `json:"bom"`
+	Delimiter string `json:"delimiter"`
+	// DecimalComma is set when size values such as 4,2 were read as 4.2.
+	DecimalComma bool `json:"decimal_comma"`
+	// JoinedLines are lines without a delimiter that continue the comment
+	// line above them, such as a header comment wrapped by an editor.
+	JoinedLines []int `json:"joined_lines,omitempty"`
+}
+
+// Plain reports whether the file is a plain UTF-8, comma separated file
+// that needed no special handling.
+func (f Format) Plain() bool {
+	return f.Encoding == EncodingUTF8 && !f.BOM && f.Delimiter == "," && !f.DecimalComma && len(f.JoinedLines) == 0
+}
+
+func (f Format) String() string {
+	parts := []string{f.Encoding}
+	if f.BOM {
+		parts[0] += " with BOM"
+	}
+	parts = append(parts, delimiterName(f.Delimiter)+" delimited")
+	if f.DecimalComma {
+		parts = append(parts, "decimal commas")
+	}
+	for _, line := range f.JoinedLines {
+		parts = append(parts, fmt.Sprintf("line %d joined to the comment above", line))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func delimiterName(d string) string {
+	if d == "\t" {
+		return "tab"
+	}
+	return fmt.Sprintf("%q", d)
+}
+
+var (
+	utf8BOM    = []byte{0xEF, 0xBB, 0xBF}
+	utf16LEBOM = []byte{0xFF, 0xFE}
+	utf16BEBOM = []byte{0xFE, 0xFF}
+)
+
+// sniff detects the format of r from its first sniffSize bytes and returns a
+// reader of the content as UTF-8 with the BOM removed and wrapped comment
+// lines turned into comments.
+func sniff(r io.Reader) (io.Reader, *Format, error) {
+	src := bufio.NewReaderSize(r, sniffSize)
+	prefix, err := src.Peek(sniffSize)
+	if err != nil && err != io.EOF {
+		return nil, nil, err
+	}
+
+	f := &Format{Encoding: EncodingUTF8, Delimiter: ","}
+	switch {
+	case bytes.HasPrefix(prefix, utf8BOM):
+		f.BOM = true
+		src.Discard(len(utf8BOM))
+		prefix = prefix[len(utf8BOM):]
+	case bytes.HasPrefix(prefix, utf16LEBOM), bytes.HasPrefix(prefix, utf16BEBOM):
+		return nil, f, errors.New("file is UTF-16 encoded, save it as UTF-8 or Windows-1252 CSV instead")
+	}
+
+	if !utf8.Valid(trimPartialRune(prefix)) {
+		f.Encoding = EncodingWindows1252
+		prefix = decodeWindows1252(prefix)
+	}
+	f.Delimiter = detectDelimiter(prefix)
+
+	return &lineReader{src: src, format: f}, f, nil
+}
+
+// trimPartialRune drops a UTF-8 sequence cut off at the end of b.
+func trimPartialRune(b []byte) []byte {
+	for i := 1; i < utf8.UTFMax && i <= len(b); i++ {
+		if utf8.RuneStart(b[len(b)-i]) {
+			if !utf8.FullRune(b[len(b)-i:]) {
+				return b[:len(b)-i]
+			}
+			break
+		}
+	}
+	return b
+}
+
+// detectDelimiter picks the candidate found on the most data lines of text,
+// breaking ties by the total count and then in favor of commas. A European
+// export with decimal commas still has semicolons on more lines.
+func detectDelimiter(text []byte) string {
+	candidates := []string{";", "\t", ","}
+	lines, total := make(map[string]int), make(map[string]int)
+
+	sampled := 0
+	for _, line := range strings.Split(string(text), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		for _, c := range candidates {
+			if n := countOutsideQuotes(line, c); n > 0 {
+				lines[c]++
+				total[c] += n
+			}
+		}
+		if sampled++; sampled == 20 {
+			break
+		}
+	}
+
+	best := ","
+	for _, c := range candidates {
+		if lines[c] > lines[best] || (lines[c] == lines[best] && total[c] > total[best]) {
+			best = c
+		}
+	}
+	return best
+}
+
+func countOutsideQuotes(line, sep string) int {
+	n, quoted := 0, false
+	for _, r := range line {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case !quoted && string(r) == sep:
+			n++
+		}
+	}
+	return n
+}
+
+// lineReader converts the input line by line: Windows-1252 is decoded to
+// UTF-8 and a line continuing a comment is commented out, keeping every line
+// where it was so that line numbers stay correct.
+type lineReader struct {
+	src    *bufio.Reader
+	format *Format
+	buf    []byte
+	line   int
+	// continues is set after a comment line ending with a delimiter.
+	continues bool
+	err       error
+}
+
+func (l *lineReader) Read(p []byte) (int, error) {
+	for len(l.buf) == 0 {
+		if l.err != nil {
+			return 0, l.err
+		}
+		var line []byte
+		line, l.err = l.src.ReadBytes('\n')
+		if len(line) > 0 {
+			l.buf = l.convert(line)
+		}
+	}
+	n := copy(p, l.buf)
+	l.buf = l.buf[n:]
+	return n, nil
+}
+
+func (l *lineReader) convert(line []byte) []byte {
+	l.line++
+	if l.format.Encoding == EncodingWindows1252 {
+		line = decodeWindows1252(line)
+	}
+
+	text := strings.TrimSpace(string(line))
+	comment := strings.HasPrefix(text, "#")
+	if l.continues && !comment && text != "" && !strings.Contains(text, l.format.Delimiter) {
+		l.format.JoinedLines = append(l.format.JoinedLines, l.line)
+		line = append([]byte("#"), line...)
+		comment = true
+	}
+	l.continues = comment && strings.HasSuffix(text, l.format.Delimiter)
+	return line
+}
+
+// windows1252 maps the bytes 0x80-0x9F, where Windows-1252 differs from
+// Latin-1, to Unicode. Unassigned bytes map to U+FFFD.
+var windows1252 = [32]rune{
+	'€', '�', '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', '�', 'Ž', '�',
+	'�', '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', '�', 'ž', 'Ÿ',
+}
+
+func decodeWindows1252(b []byte) []byte {
+	out := make([]byte, 0, len(b)+len(b)/8)
+	for _, c := range b {
+		switch {
+		case c < 0x80:
+			out = append(out, c)
+		case c < 0xA0:
+			out = utf8.AppendRune(out, windows1252[c-0x80])
+		default:
+			out = utf8.AppendRune(out, rune(c))
+		}
+	}
+	return out
+}
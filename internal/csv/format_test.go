@@ -0,0 +1,97 @@
+package csv
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSniff(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    Format
+		wantOut string
+	}{
+		{
+			name:    "plain",
+			input:   "Brand,Type,Color\nTest,PLA,Red\n",
+			want:    Format{Encoding: EncodingUTF8, Delimiter: ","},
+			wantOut: "Brand,Type,Color\nTest,PLA,Red\n",
+		},
+		{
+			name:    "BOM",
+			input:   "\xEF\xBB\xBFBrand,Type\n",
+			want:    Format{Encoding: EncodingUTF8, BOM: true, Delimiter: ","},
+			wantOut: "Brand,Type\n",
+		},
+		{
+			name:    "semicolons with decimal commas",
+			input:   "Brand;Type;Color;200;60;4,2\nTest;PLA;Blue;200;60;3,8\n",
+			want:    Format{Encoding: EncodingUTF8, Delimiter: ";"},
+			wantOut: "Brand;Type;Color;200;60;4,2\nTest;PLA;Blue;200;60;3,8\n",
+		},
+		{
+			name:    "tabs",
+			input:   "Brand\tType\tColor\n# a, comment; here\nTest\tPLA\t\"Red, dark\"\n",
+			want:    Format{Encoding: EncodingUTF8, Delimiter: "\t"},
+			wantOut: "Brand\tType\tColor\n# a, comment; here\nTest\tPLA\t\"Red, dark\"\n",
+		},
+		{
+			name:    "Windows-1252",
+			input:   "Test,PLA,Gr\xfcn \x96 dunkel \x80\n",
+			want:    Format{Encoding: EncodingWindows1252, Delimiter: ","},
+			wantOut: "Test,PLA,Grün – dunkel €\n",
+		},
+		{
+			name:    "wrapped comment",
+			input:   "# Brand, Material, Color,\nColorFontSize\n\nTest,PLA,Red\n",
+			want:    Format{Encoding: EncodingUTF8, Delimiter: ",", JoinedLines: []int{2}},
+			wantOut: "# Brand, Material, Color,\n#ColorFontSize\n\nTest,PLA,Red\n",
+		},
+		{
+			name:    "comment without continuation",
+			input:   "# Brand, Material, Color\nTest\n",
+			want:    Format{Encoding: EncodingUTF8, Delimiter: ","},
+			wantOut: "# Brand, Material, Color\nTest\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, format, err := sniff(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("sniff() error = %v", err)
+			}
+			out, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(out) != tt.wantOut {
+				t.Errorf("content = %q, want %q", out, tt.wantOut)
+			}
+			if !reflect.DeepEqual(*format, tt.want) {
+				t.Errorf("format = %+v, want %+v", *format, tt.want)
+			}
+		})
+	}
+
+	if _, _, err := sniff(strings.NewReader("\xFF\xFEB\x00r\x00")); err == nil || !strings.Contains(err.Error(), "UTF-16") {
+		t.Errorf("expected a UTF-16 error, got %v", err)
+	}
+}
+
+func TestFormat_String(t *testing.T) {
+	f := Format{Encoding: EncodingWindows1252, BOM: false, Delimiter: ";", DecimalComma: true, JoinedLines: []int{3}}
+	want := `Windows-1252, ";" delimited, decimal commas, line 3 joined to the comment above`
+	if got := f.String(); got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	if f.Plain() {
+		t.Error("Plain() should be false")
+	}
+	if !(Format{Encoding: EncodingUTF8, Delimiter: ","}).Plain() {
+		t.Error("UTF-8 with commas should be plain")
+	}
+}
@@ -2,21 +2,46 @@ package csv
 
 import (
 	"encoding/csv"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 
+	"github.com/guntharp/go-filamentsamples/internal/profiles"
 	"github.com/guntharp/go-filamentsamples/pkg/models"
+	"github.com/guntharp/go-filamentsamples/pkg/scad"
 )
 
+var decimalComma = regexp.MustCompile(`^[+-]?[0-9]+,[0-9]+$`)
+
 type Parser struct {
 	SkipHeader bool
+	// Profiles fills in temperatures left empty in the CSV file. A nil
+	// library leaves them empty, which fails validation.
+	Profiles *profiles.Library
+	// Lenient skips invalid rows instead of rejecting the file. Each
+	// problem of a skipped row is passed to Skipped when it is set.
+	Lenient bool
+	Skipped func(*RowError)
+	// Detected, when set, is called with the format detected for each
+	// input once it has been read.
+	Detected func(Format)
+	// Schema, when set, is the template the samples are rendered with.
+	// Header columns it does not define, such as a Supplier column kept
+	// for bookkeeping, are ignored and passed to Ignored instead of
+	// becoming parameters the template rejects.
+	Schema  *scad.Schema
+	Ignored func(column string)
 }
 
 func NewParser() *Parser {
 	return &Parser{
 		SkipHeader: true,
+		Profiles:   profiles.New(nil),
 	}
 }
 
@@ -30,13 +55,57 @@ func (p *Parser) ParseFile(filename string) ([]*models.FilamentSample, error) {
 	return p.Parse(file)
 }
 
+// Parse reads every sample from reader. Invalid rows do not stop parsing:
+// in strict mode all of them are returned together as a *ParseError, in
+// lenient mode they are skipped. A header without the required columns is
+// always an error.
 func (p *Parser) Parse(reader io.Reader) ([]*models.FilamentSample, error) {
-	csvReader := csv.NewReader(reader)
+	var samples []*models.FilamentSample
+	err := p.Stream(reader, func(sample *models.FilamentSample) error {
+		samples = append(samples, sample)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return samples, nil
+}
+
+// Stream reads reader row by row and passes each valid sample to fn as soon
+// as it is read, so memory use does not grow with the file. Invalid rows are
+// handled as by Parse, except that in strict mode no sample is passed on
+// after the first invalid row; the rest of the file is still read to report
+// every invalid row. An error returned by fn stops reading and is returned.
+func (p *Parser) Stream(reader io.Reader, fn func(*models.FilamentSample) error) error {
+	input, format, err := sniff(reader)
+	if err != nil {
+		return fmt.Errorf("failed to read CSV: %w", err)
+	}
+	if p.Detected != nil {
+		defer func() { p.Detected(*format) }()
+	}
+
+	csvReader := csv.NewReader(input)
+	csvReader.Comma = rune(format.Delimiter[0])
 	csvReader.Comment = '#'
 	csvReader.TrimLeadingSpace = true
+	// Rows may omit trailing empty columns; short rows are reported below.
+	csvReader.FieldsPerRecord = -1
 
-	var samples []*models.FilamentSample
-	lineNum := 0
+	var invalid []*RowError
+	reject := func(errs ...*RowError) {
+		if !p.Lenient {
+			invalid = append(invalid, errs...)
+			return
+		}
+		if p.Skipped != nil {
+			for _, err := range errs {
+				p.Skipped(err)
+			}
+		}
+	}
+	cols := positional()
+	records := 0
 
 	for {
 		record, err := csvReader.Read()
@@ -44,67 +113,202 @@ func (p *Parser) Parse(reader io.Reader) ([]*models.FilamentSample, error) {
 			break
 		}
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse CSV at line %d: %w", lineNum+1, err)
+			var csvErr *csv.ParseError
+			if !errors.As(err, &csvErr) {
+				return fmt.Errorf("failed to read CSV: %w", err)
+			}
+			reject(&RowError{Line: csvErr.StartLine, Reason: csvErr.Err.Error()})
+			continue
 		}
+		// Field positions are only known for rows that were read.
+		line, _ := csvReader.FieldPos(0)
 
-		lineNum++
+		records++
 
-		if p.SkipHeader && lineNum == 1 {
+		if p.SkipHeader && records == 1 {
 			if p.isHeaderRow(record) {
+				if cols, err = mapHeader(record); err != nil {
+					return &ParseError{Errors: []*RowError{{Line: line, Reason: err.Error()}}}
+				}
+				p.dropUnknownColumns(cols)
 				continue
 			}
 		}
 
-		if len(record) == 0 || strings.TrimSpace(record[0]) == "" {
+		if cols.get(record, colBrand) == "" {
 			continue
 		}
 
-		sample, err := p.parseRecord(record, lineNum)
+		if reason := checkWidth(record, cols, format); reason != "" {
+			reject(&RowError{Line: line, Reason: reason})
+			continue
+		}
+		if fixDecimalCommas(record, cols) {
+			format.DecimalComma = true
+		}
+
+		sample, err := p.parseRecord(record, cols)
 		if err != nil {
-			return nil, fmt.Errorf("error at line %d: %w", lineNum, err)
+			reject(rowErrors(line, cols, err)...)
+			continue
 		}
 
-		samples = append(samples, sample)
+		sample.Line = line
+		if len(invalid) > 0 {
+			continue
+		}
+		if err := fn(sample); err != nil {
+			return err
+		}
 	}
 
-	return samples, nil
+	if len(invalid) > 0 {
+		return &ParseError{Errors: invalid}
+	}
+	return nil
+}
+
+// dropUnknownColumns removes the extra columns of cols that the template
+// does not define, in column order.
+func (p *Parser) dropUnknownColumns(cols columns) {
+	if p.Schema == nil {
+		return
+	}
+	indexes := make([]int, 0, len(cols.extra))
+	for i := range cols.extra {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+	for _, i := range indexes {
+		name := cols.extra[i]
+		if p.Schema.Has(name) {
+			continue
+		}
+		delete(cols.extra, i)
+		if p.Ignored != nil {
+			p.Ignored(name)
+		}
+	}
 }
 
+// isHeaderRow reports whether record is a header naming the columns rather
+// than a sample.
 func (p *Parser) isHeaderRow(record []string) bool {
 	if len(record) == 0 {
 		return false
 	}
-	
+
 	header := strings.ToLower(strings.TrimSpace(record[0]))
-	return header == "brand" || header == "manufacturer"
+	return header == "brand" || header == "manufacturer" || isHeader(record)
 }
 
-func (p *Parser) parseRecord(record []string, lineNum int) (*models.FilamentSample, error) {
-	if len(record) < 5 {
-		return nil, fmt.Errorf("insufficient columns, expected at least 5, got %d", len(record))
+func (p *Parser) parseRecord(record []string, cols columns) (*models.FilamentSample, error) {
+	sample := &models.FilamentSample{
+		Brand:        cols.get(record, colBrand),
+		Type:         cols.get(record, colType),
+		Color:        cols.get(record, colColor),
+		TempHotend:   cols.get(record, colTempHotend),
+		TempBed:      cols.get(record, colTempBed),
+		BrandSize:    cols.get(record, colBrandSize),
+		TypeSize:     cols.get(record, colTypeSize),
+		ColorSize:    cols.get(record, colColorSize),
+		Notes:        cols.get(record, colNotes),
+		ParameterSet: cols.get(record, colSet),
 	}
 
-	sample := &models.FilamentSample{
-		Brand:      strings.TrimSpace(record[0]),
-		Type:       strings.TrimSpace(record[1]),
-		Color:      strings.TrimSpace(record[2]),
-		TempHotend: strings.TrimSpace(record[3]),
-		TempBed:    strings.TrimSpace(record[4]),
+	var errs []error
+	fail := func(field, value string, err error) {
+		errs = append(errs, &models.FieldError{Field: field, Value: value, Err: err})
 	}
 
-	if len(record) > 5 && strings.TrimSpace(record[5]) != "" {
-		sample.BrandSize = strings.TrimSpace(record[5])
+	if value := cols.get(record, colQuantity); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			fail(models.FieldQuantity, value, fmt.Errorf("quantity must be a whole number, got %q", value))
+		}
+		sample.Quantity = n
 	}
-	if len(record) > 6 && strings.TrimSpace(record[6]) != "" {
-		sample.TypeSize = strings.TrimSpace(record[6])
+
+	for i, name := range cols.extra {
+		if i >= len(record) || strings.TrimSpace(record[i]) == "" {
+			continue
+		}
+		if sample.Params == nil {
+			sample.Params = make(map[string]string)
+		}
+		sample.Params[name] = strings.TrimSpace(record[i])
 	}
-	if len(record) > 7 && strings.TrimSpace(record[7]) != "" {
-		sample.ColorSize = strings.TrimSpace(record[7])
+
+	if value := cols.get(record, colParams); value != "" {
+		params, err := scad.ParseAssignments(value)
+		if err != nil {
+			fail(models.FieldParams, value, err)
+		}
+		for _, name := range sortedKeys(params) {
+			if _, dup := sample.Params[name]; dup {
+				fail(models.FieldParams, value, fmt.Errorf("parameter %s is also set by its own column", name))
+				continue
+			}
+			if sample.Params == nil {
+				sample.Params = make(map[string]string)
+			}
+			sample.Params[name] = params[name]
+		}
 	}
 
+	p.Profiles.Fill(sample)
+
 	if err := sample.Validate(); err != nil {
-		return nil, fmt.Errorf("validation failed: %w", err)
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
 	}
+	sample.NormalizeTemperatures()
 
 	return sample, nil
-}
\ No newline at end of file
+}
+
+// checkWidth returns why record has the wrong number of columns, or "". Files
+// without a header need the five columns up to the bed temperature; rows of
+// files with one may not have values beyond the header's columns.
+func checkWidth(record []string, cols columns, format *Format) string {
+	if cols.header == nil {
+		if len(record) < 5 {
+			return fmt.Sprintf("expected at least 5 columns, got %d (read as %s)", len(record), format)
+		}
+		return ""
+	}
+	for _, value := range record[min(len(record), len(cols.header)):] {
+		if strings.TrimSpace(value) != "" {
+			return fmt.Sprintf("expected %d columns as in the header, got %d (read as %s)", len(cols.header), len(record), format)
+		}
+	}
+	return ""
+}
+
+// fixDecimalCommas rewrites size values written with a decimal comma, such
+// as 4,2, to use a point. It reports whether any value was changed.
+func fixDecimalCommas(record []string, cols columns) bool {
+	changed := false
+	for _, field := range []string{colBrandSize, colTypeSize, colColorSize} {
+		i, ok := cols.fields[field]
+		if !ok || i >= len(record) {
+			continue
+		}
+		if value := strings.TrimSpace(record[i]); decimalComma.MatchString(value) {
+			record[i] = strings.Replace(value, ",", ".", 1)
+			changed = true
+		}
+	}
+	return changed
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -96,10 +96,11 @@ Test Brand,PETG,Blue,240-260,70`
 func BenchmarkParser_ParseRecord(b *testing.B) {
 	parser := NewParser()
 	record := []string{"Test Brand", "PLA", "Red", "200-220", "60", "12", "8", "10"}
+	cols := positional()
 	
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_, err := parser.parseRecord(record, 1)
+		_, err := parser.parseRecord(record, cols)
 		if err != nil {
 			b.Fatal(err)
 		}
@@ -109,10 +110,11 @@ func BenchmarkParser_ParseRecord(b *testing.B) {
 func BenchmarkParser_ParseRecord_Minimal(b *testing.B) {
 	parser := NewParser()
 	record := []string{"Test Brand", "PLA", "Red", "200-220", "60"}
+	cols := positional()
 	
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_, err := parser.parseRecord(record, 1)
+		_, err := parser.parseRecord(record, cols)
 		if err != nil {
 			b.Fatal(err)
 		}
@@ -1,10 +1,15 @@
 package csv
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
+
+	"github.com/guntharp/go-filamentsamples/pkg/models"
+	"github.com/guntharp/go-filamentsamples/pkg/scad"
 )
 
 func TestParser_Parse(t *testing.T) {
@@ -96,6 +101,11 @@ func TestParser_isHeaderRow(t *testing.T) {
 			record: []string{"Manufacturer", "Type", "Color"},
 			want:   true,
 		},
+		{
+			name:   "aliased header",
+			record: []string{"Material", "Colour", "Manufacturer", "NozzleTemp"},
+			want:   true,
+		},
 		{
 			name:   "data row",
 			record: []string{"Test Brand", "PLA", "Red"},
@@ -208,7 +218,7 @@ func TestParser_parseRecord_EdgeCases(t *testing.T) {
 	
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			sample, err := parser.parseRecord(tt.record, tt.lineNum)
+			sample, err := parser.parseRecord(tt.record, positional())
 			
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Parser.parseRecord() error = %v, wantErr %v", err, tt.wantErr)
@@ -220,4 +230,371 @@ func TestParser_parseRecord_EdgeCases(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+func TestParser_Parse_NormalizesTemperatures(t *testing.T) {
+	csvData := "Brand,Type,Color,TempHotend,TempBed\n" +
+		"Test,PLA,Red,200–220,60\n" +
+		"Test,ABS,Black,482°F,212 ± 10 F\n"
+
+	samples, err := NewParser().Parse(strings.NewReader(csvData))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	want := [][2]string{{"200-220", "60"}, {"250", "94-106"}}
+	for i, sample := range samples {
+		if sample.TempHotend != want[i][0] || sample.TempBed != want[i][1] {
+			t.Errorf("sample %d temperatures = %s, %s; want %s, %s",
+				i, sample.TempHotend, sample.TempBed, want[i][0], want[i][1])
+		}
+	}
+}
+
+func TestParser_Parse_RecordsLines(t *testing.T) {
+	csvData := "Brand,Type,Color,TempHotend,TempBed\n" +
+		"# a comment\n" +
+		"Test,PLA,Red,200,60\n" +
+		"\n" +
+		"Test,PLA,\"Blue\nGreen\",200,60\n" +
+		"Test,PLA,White,200,60\n"
+
+	samples, err := NewParser().Parse(strings.NewReader(csvData))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	want := []int{3, 5, 7}
+	for i, sample := range samples {
+		if sample.Line != want[i] {
+			t.Errorf("sample %d line = %d, want %d", i, sample.Line, want[i])
+		}
+	}
+}
+
+func TestParser_Parse_FillsTemperaturesFromProfiles(t *testing.T) {
+	csvData := "Brand,Type,Color,TempHotend,TempBed\n" +
+		"Bambu Labs,PLA Matte,Ivory,,\n" +
+		"Bambu Labs,PLA Matte,Red,200,\n" +
+		"Test,Wood,Oak,,\n"
+
+	parser := NewParser()
+	if _, err := parser.Parse(strings.NewReader(csvData)); err == nil || !strings.Contains(err.Error(), "line 4") {
+		t.Fatalf("expected an error for the row without a profile, got %v", err)
+	}
+
+	samples, err := parser.Parse(strings.NewReader(strings.TrimSuffix(csvData, "Test,Wood,Oak,,\n")))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if samples[0].TempHotend != "190-230" || samples[0].TempBed != "34-45" || len(samples[0].Inferred) != 2 {
+		t.Errorf("first sample = %+v, want temperatures from the Bambu Labs PLA Matte profile", samples[0])
+	}
+	if samples[1].TempHotend != "200" || !samples[1].IsInferred(models.FieldTempBed) || samples[1].IsInferred(models.FieldTempHotend) {
+		t.Errorf("second sample = %+v, want only the bed temperature inferred", samples[1])
+	}
+
+	parser.Profiles = nil
+	if _, err := parser.Parse(strings.NewReader(csvData)); err == nil {
+		t.Error("without profiles empty temperatures should fail")
+	}
+}
+
+func TestParser_Parse_Params(t *testing.T) {
+	csvData := "Brand,Type,Color,Hotend,Bed,INVERT_CARD,Spool weight,Params,Notes,Qty\n" +
+		"Test,PLA,Red,200,60,0,1kg,\"CARD_THICKNESS=3;FONT=\"\"Noto Sans; Bold\"\"\",spare,2\n"
+
+	samples, err := NewParser().Parse(strings.NewReader(csvData))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	s := samples[0]
+	want := map[string]string{"INVERT_CARD": "0", "CARD_THICKNESS": "3", "FONT": `"Noto Sans; Bold"`}
+	if !reflect.DeepEqual(s.Params, want) {
+		t.Errorf("Params = %v, want %v", s.Params, want)
+	}
+	if s.Notes != "spare" || s.Quantity != 2 {
+		t.Errorf("Notes = %q, Quantity = %d, want spare and 2", s.Notes, s.Quantity)
+	}
+
+	tests := []struct {
+		name    string
+		row     string
+		wantErr string
+	}{
+		{"unbalanced", `Test,PLA,Red,200,60,,,"FONT=""Noto",,`, `line 2, column Params: unbalanced quotes`},
+		{"set twice", `Test,PLA,Red,200,60,1,,INVERT_CARD=0,,`, `column Params: parameter INVERT_CARD is also set by its own column`},
+		{"reserved", `Test,PLA,Red,200,60,,,BRAND_SIZE=5,,`, `column Params: parameter BRAND_SIZE is set from the sample's own columns`},
+		{"quantity", `Test,PLA,Red,200,60,,,,,two`, `column Qty: quantity must be a whole number`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := "Brand,Type,Color,Hotend,Bed,INVERT_CARD,Spool weight,Params,Notes,Qty\n"
+			_, err := NewParser().Parse(strings.NewReader(header + tt.row + "\n"))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Parse() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParser_Parse_IgnoresUnknownColumns(t *testing.T) {
+	csvData := "Brand,Type,Color,Hotend,Bed,Supplier,CARD_THICKNESS,$fn,Params\n" +
+		"Test,PLA,Red,200,60,ACME,3,20,NOTCH=1\n"
+	schema, err := scad.ParseTemplate([]byte("CARD_THICKNESS=2.2;\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ignored []string
+	parser := NewParser()
+	parser.Schema = schema
+	parser.Ignored = func(column string) { ignored = append(ignored, column) }
+	samples, err := parser.Parse(strings.NewReader(csvData))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	// Params are kept, for the template checks to report.
+	want := map[string]string{"CARD_THICKNESS": "3", "$fn": "20", "NOTCH": "1"}
+	if !reflect.DeepEqual(samples[0].Params, want) {
+		t.Errorf("Params = %v, want %v", samples[0].Params, want)
+	}
+	if !reflect.DeepEqual(ignored, []string{"Supplier"}) {
+		t.Errorf("ignored = %v, want [Supplier]", ignored)
+	}
+}
+
+func TestParser_Parse_ParameterSet(t *testing.T) {
+	csvData := "Brand,Type,Color,Hotend,Bed,Parameter Set\n" +
+		"Test,PLA,Red,200,60,Standardwerte des Designs\n" +
+		"Test,PETG,Blue,240,70,\n"
+
+	samples, err := NewParser().Parse(strings.NewReader(csvData))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if samples[0].ParameterSet != "Standardwerte des Designs" || samples[1].ParameterSet != "" {
+		t.Errorf("ParameterSet = %q, %q; want the named set and none", samples[0].ParameterSet, samples[1].ParameterSet)
+	}
+	if samples[0].Params != nil {
+		t.Errorf("Params = %v, want none", samples[0].Params)
+	}
+}
+
+func TestParser_Parse_HeaderMapping(t *testing.T) {
+	csvData := "Material,Colour,Manufacturer,BedTemp,NozzleTemp,ColorFontSize,CARD_THICKNESS\n" +
+		"PETG,Blue,Test,70,240-260,9,2.2\n" +
+		"PLA,Red,Test,60,200,,\n"
+
+	samples, err := NewParser().Parse(strings.NewReader(csvData))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(samples) != 2 {
+		t.Fatalf("got %d samples, want 2", len(samples))
+	}
+
+	s := samples[0]
+	if s.Brand != "Test" || s.Type != "PETG" || s.Color != "Blue" || s.TempHotend != "240-260" ||
+		s.TempBed != "70" || s.ColorSize != "9" {
+		t.Errorf("columns mapped wrongly: %+v", s)
+	}
+	if s.Params["CARD_THICKNESS"] != "2.2" {
+		t.Errorf("extra column not kept as a parameter: %v", s.Params)
+	}
+	if samples[1].Params != nil {
+		t.Errorf("empty extra values should be left out, got %v", samples[1].Params)
+	}
+
+	_, err = NewParser().Parse(strings.NewReader("Brand,NozzleTemp,BedTemp\nTest,200,60\n"))
+	if err == nil || !strings.Contains(err.Error(), "line 1: header is missing required columns: Type, Color") {
+		t.Errorf("expected one error naming the missing columns, got %v", err)
+	}
+}
+
+func TestParser_Parse_CollectsAllErrors(t *testing.T) {
+	csvData := "Brand,Material,Color,NozzleTemp,BedTemp,BrandFontSize\n" +
+		"A,PLA,Red,hot,60,x\n" +
+		"B,PLA,Blue,200,60,4,extra\n" +
+		"C,PLA,,200,60,\n" +
+		"D,PLA,Green,200,60,\n"
+
+	_, err := NewParser().Parse(strings.NewReader(csvData))
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("expected a *ParseError, got %v", err)
+	}
+
+	want := []RowError{
+		{Line: 2, Column: "NozzleTemp", Value: "hot"},
+		{Line: 2, Column: "BrandFontSize", Value: "x"},
+		{Line: 3},
+		{Line: 4, Column: "Color"},
+	}
+	if len(parseErr.Errors) != len(want) {
+		t.Fatalf("got %d errors, want %d: %v", len(parseErr.Errors), len(want), err)
+	}
+	for i, w := range want {
+		got := parseErr.Errors[i]
+		if got.Line != w.Line || got.Column != w.Column || got.Value != w.Value || got.Reason == "" {
+			t.Errorf("error %d = %+v, want line %d column %q value %q", i, got, w.Line, w.Column, w.Value)
+		}
+	}
+	if !strings.Contains(err.Error(), "line 2, column NozzleTemp: invalid temperature") {
+		t.Errorf("error text does not name the column: %v", err)
+	}
+
+	var rowErr *RowError
+	if !errors.As(err, &rowErr) || rowErr.Line != 2 {
+		t.Errorf("errors.As should find the first row error, got %v", rowErr)
+	}
+}
+
+func TestParser_Parse_Lenient(t *testing.T) {
+	csvData := "Brand,Type,Color,TempHotend,TempBed\n" +
+		"A,PLA,Red,hot,60\n" +
+		"B,PLA,Blue,200,60\n"
+
+	var skipped []*RowError
+	parser := NewParser()
+	parser.Lenient = true
+	parser.Skipped = func(err *RowError) { skipped = append(skipped, err) }
+
+	samples, err := parser.Parse(strings.NewReader(csvData))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(samples) != 1 || samples[0].Brand != "B" {
+		t.Errorf("expected only the valid row, got %v", samples)
+	}
+	if len(skipped) != 1 || skipped[0].Line != 2 || skipped[0].Column != "TempHotend" {
+		t.Errorf("unexpected skipped rows: %v", skipped)
+	}
+
+	_, err = parser.Parse(strings.NewReader("Brand,TempHotend\nA,200\n"))
+	if err == nil {
+		t.Error("a header missing required columns should fail even in lenient mode")
+	}
+}
+
+func TestParser_Parse_MalformedQuotes(t *testing.T) {
+	csvData := "Brand,Type,Color,TempHotend,TempBed\n" +
+		"X\"y,PLA,Red,200,60\n" +
+		"\"abc\"x,PLA,Blue,200,60\n" +
+		"B,PLA,Green,200,60\n"
+
+	_, err := NewParser().Parse(strings.NewReader(csvData))
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) || len(parseErr.Errors) != 2 {
+		t.Fatalf("Parse() error = %v, want both malformed rows", err)
+	}
+	for i, line := range []int{2, 3} {
+		if got := parseErr.Errors[i].Line; got != line {
+			t.Errorf("error %d line = %d, want %d", i, got, line)
+		}
+	}
+
+	var skipped []*RowError
+	parser := NewParser()
+	parser.Lenient = true
+	parser.Skipped = func(err *RowError) { skipped = append(skipped, err) }
+	samples, err := parser.Parse(strings.NewReader(csvData))
+	if err != nil {
+		t.Fatalf("lenient Parse() error = %v", err)
+	}
+	if len(samples) != 1 || samples[0].Brand != "B" || len(skipped) != 2 {
+		t.Errorf("got samples %v and skipped %v, want B and both malformed rows", samples, skipped)
+	}
+}
+
+func TestParser_Stream(t *testing.T) {
+	csvData := "A,PLA,Red,200,60\n" +
+		"B,PLA,Blue,200,60\n" +
+		"C,PLA,Green,hot,60\n" +
+		"D,PLA,White,200,60\n" +
+		"E,PLA,Black,200,bed\n"
+
+	var brands []string
+	err := NewParser().Stream(strings.NewReader(csvData), func(sample *models.FilamentSample) error {
+		brands = append(brands, sample.Brand)
+		return nil
+	})
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) || len(parseErr.Errors) != 2 {
+		t.Fatalf("Stream() error = %v, want both invalid rows", err)
+	}
+	if strings.Join(brands, ",") != "A,B" {
+		t.Errorf("streamed %v, want only the rows before the first invalid one", brands)
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	err = NewParser().Stream(strings.NewReader(csvData), func(*models.FilamentSample) error {
+		calls++
+		return stop
+	})
+	if err != stop || calls != 1 {
+		t.Errorf("Stream() = %v after %d calls, want the callback's error after 1", err, calls)
+	}
+}
+
+func TestParser_Parse_MessyExports(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		wantColor  string
+		wantSize   string
+		wantFormat string
+	}{
+		{
+			name:       "Excel UTF-8 with BOM",
+			input:      "\xEF\xBB\xBFBrand,Type,Color,TempHotend,TempBed\r\nTest,PLA,Red,200,60\r\n",
+			wantColor:  "Red",
+			wantFormat: `UTF-8 with BOM, "," delimited`,
+		},
+		{
+			name:       "European LibreOffice export",
+			input:      "Brand;Material;Color;NozzleTemp;BedTemp;BrandFontSize\nTest;PLA;Grün;200;60;4,2\n",
+			wantColor:  "Grün",
+			wantSize:   "4.2",
+			wantFormat: `UTF-8, ";" delimited, decimal commas`,
+		},
+		{
+			name:       "Windows-1252",
+			input:      "Test;PLA;Gr\xfcn;200;60;3,5\n",
+			wantColor:  "Grün",
+			wantSize:   "3.5",
+			wantFormat: `Windows-1252, ";" delimited, decimal commas`,
+		},
+		{
+			name: "swatches with wrapped header comment",
+			input: "# Brand, Material, Color, NozzleTemp, BedTemp, BrandFontSize, MaterialFontSize,\n" +
+				"ColorFontSize\n\nOverture,PLA,Black,190-220,25-60\nPolyMaker,PolyTerra PLA,White,190-230,25-60,,3.8\n",
+			wantColor:  "Black",
+			wantFormat: `UTF-8, "," delimited, line 2 joined to the comment above`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var format Format
+			parser := NewParser()
+			parser.Detected = func(f Format) { format = f }
+
+			samples, err := parser.Parse(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if len(samples) == 0 || samples[0].Color != tt.wantColor || samples[0].BrandSize != tt.wantSize {
+				t.Errorf("unexpected samples: %+v", samples)
+			}
+			if format.String() != tt.wantFormat {
+				t.Errorf("format = %q, want %q", format.String(), tt.wantFormat)
+			}
+		})
+	}
+
+	_, err := NewParser().Parse(strings.NewReader("Test;PLA\n"))
+	if err == nil || !strings.Contains(err.Error(), `expected at least 5 columns, got 2 (read as UTF-8, ";" delimited)`) {
+		t.Errorf("short rows should report the detected format, got %v", err)
+	}
+}
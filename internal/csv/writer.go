@@ -0,0 +1,122 @@
+package csv
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/guntharp/go-filamentsamples/pkg/models"
+	"github.com/guntharp/go-filamentsamples/pkg/scad"
+)
+
+// Write writes samples as a CSV file that Parser reads back into the same
+// samples, apart from quotes added around parameter text holding a
+// semicolon. The header names the required fields and the temperatures,
+// then the sizes, a column per template parameter, Params, ParameterSet,
+// Notes and Quantity as far as any sample uses them. Parameters whose
+// column would be taken for a field, such as one called BED, are written
+// to the Params column instead.
+func Write(w io.Writer, samples []*models.FilamentSample) error {
+	var sizes, listed, sets, notes, quantities bool
+	names := map[string]bool{}
+	for _, s := range samples {
+		sizes = sizes || s.BrandSize != "" || s.TypeSize != "" || s.ColorSize != ""
+		sets = sets || s.ParameterSet != ""
+		notes = notes || s.Notes != ""
+		quantities = quantities || s.Quantity != 0
+		for name := range s.Params {
+			if ownColumn(name) {
+				names[name] = true
+			} else {
+				listed = true
+			}
+		}
+	}
+
+	header := []string{colBrand, colType, colColor, colTempHotend, colTempBed}
+	if sizes {
+		header = append(header, colBrandSize, colTypeSize, colColorSize)
+	}
+	columns := make([]string, 0, len(names))
+	for name := range names {
+		columns = append(columns, name)
+	}
+	sort.Strings(columns)
+	header = append(header, columns...)
+	if listed {
+		header = append(header, colParams)
+	}
+	if sets {
+		header = append(header, colSet)
+	}
+	if notes {
+		header = append(header, colNotes)
+	}
+	if quantities {
+		header = append(header, colQuantity)
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, s := range samples {
+		record := []string{s.Brand, s.Type, s.Color, s.TempHotend, s.TempBed}
+		if sizes {
+			record = append(record, s.BrandSize, s.TypeSize, s.ColorSize)
+		}
+		for _, name := range columns {
+			record = append(record, s.Params[name])
+		}
+		if listed {
+			record = append(record, listedParams(s))
+		}
+		if sets {
+			record = append(record, s.ParameterSet)
+		}
+		if notes {
+			record = append(record, s.Notes)
+		}
+		if quantities {
+			quantity := ""
+			if s.Quantity != 0 {
+				quantity = strconv.Itoa(s.Quantity)
+			}
+			record = append(record, quantity)
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// ownColumn reports whether a template parameter can have a column named
+// after it, which mapHeader does not take for another column.
+func ownColumn(name string) bool {
+	normalized := normalizeHeader(name)
+	_, field := columnAliases[normalized]
+	_, other := otherColumns[normalized]
+	return !field && !other
+}
+
+// listedParams returns the parameters of s that have no column of their own
+// as NAME=value pairs for the Params column. Text holding a semicolon is
+// quoted so that it is not split there.
+func listedParams(s *models.FilamentSample) string {
+	var pairs []string
+	for _, name := range s.ParamNames() {
+		if ownColumn(name) {
+			continue
+		}
+		value := s.Params[name]
+		if strings.Contains(value, ";") && scad.LiteralType(value) == scad.TypeExpression {
+			value = scad.String(value)
+		}
+		pairs = append(pairs, name+"="+value)
+	}
+	return strings.Join(pairs, ";")
+}
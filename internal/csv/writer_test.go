@@ -0,0 +1,64 @@
+package csv
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/guntharp/go-filamentsamples/pkg/models"
+)
+
+func TestWrite_RoundTrip(t *testing.T) {
+	samples := []*models.FilamentSample{
+		{Brand: "Bambu Labs", Type: "PLA Matte", Color: "Charcoal, dark", TempHotend: "190-230", TempBed: "55"},
+		{
+			Brand: "Polymaker", Type: "PETG", Color: "Blue", TempHotend: "240", TempBed: "70", BrandSize: "5",
+			Params: map[string]string{
+				"CARD_THICKNESS": "3", "FONT": "Noto Sans; Bold", "TEXT": `"240"`,
+				"bed": "1", "notes": "a;b",
+			},
+			ParameterSet: "Standardwerte des Designs",
+			Notes:        `second "spool"`,
+			Quantity:     2,
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, samples); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	header, _, _ := strings.Cut(buf.String(), "\n")
+	want := "Brand,Type,Color,TempHotend,TempBed,BrandSize,TypeSize,ColorSize,CARD_THICKNESS,FONT,TEXT,Params,ParameterSet,Notes,Quantity"
+	if header != want {
+		t.Errorf("header = %s, want %s", header, want)
+	}
+
+	got, err := NewParser().Parse(&buf)
+	if err != nil {
+		t.Fatalf("Parse() error = %v\n%s", err, buf.String())
+	}
+	if len(got) != len(samples) {
+		t.Fatalf("got %d samples, want %d", len(got), len(samples))
+	}
+	// Text with a semicolon comes back quoted, which OpenSCAD reads the
+	// same.
+	samples[1].Params["notes"] = `"a;b"`
+	for i, s := range got {
+		s.Line, s.Inferred = 0, nil
+		if !reflect.DeepEqual(s, samples[i]) {
+			t.Errorf("sample %d = %+v, want %+v", i, s, samples[i])
+		}
+	}
+}
+
+func TestWrite_Minimal(t *testing.T) {
+	var buf bytes.Buffer
+	samples := []*models.FilamentSample{{Brand: "A", Type: "PLA", Color: "Red", TempHotend: "200", TempBed: "60"}}
+	if err := Write(&buf, samples); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if want := "Brand,Type,Color,TempHotend,TempBed\nA,PLA,Red,200,60\n"; buf.String() != want {
+		t.Errorf("Write() = %q, want %q", buf.String(), want)
+	}
+}
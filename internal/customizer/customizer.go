@@ -0,0 +1,148 @@
+// Package customizer converts between sample lists and OpenSCAD Customizer
+// parameter files, so that every sample can be picked from the Customizer's
+// preset drop-down and presets made there can be rendered in bulk.
+package customizer
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/guntharp/go-filamentsamples/pkg/models"
+	"github.com/guntharp/go-filamentsamples/pkg/scad"
+)
+
+// fileFormatVersion is the version OpenSCAD writes into parameter files.
+const fileFormatVersion = "1"
+
+// fieldVariables are the template variables every sample sets from its own
+// fields.
+var fieldVariables = []string{"BRAND", "TYPE", "COLOR", "TEMP_HOTEND", "TEMP_BED"}
+
+// SetName returns the name of the parameter set of sample: its file name
+// without the extension.
+func SetName(sample *models.FilamentSample) string {
+	name := sample.Filename()
+	return name[:len(name)-len(filepath.Ext(name))]
+}
+
+// Export returns a parameter file with a set per sample, named by SetName.
+// Each set holds the defaults of the template's parameters, except those
+// computed from expressions, overridden by every variable the sample is
+// rendered with under opts. It fails when two samples have the same name.
+func Export(samples []*models.FilamentSample, opts models.ArgOptions, schema *scad.Schema) (*scad.ParameterFile, error) {
+	defaults := make(scad.ParameterSet, len(schema.Params))
+	for _, p := range schema.Params {
+		if p.Type != scad.TypeExpression && p.Type != scad.TypeUndef {
+			defaults[p.Name] = scad.CustomizerValue(p.Default)
+		}
+	}
+
+	f := &scad.ParameterFile{
+		ParameterSets:     make(map[string]scad.ParameterSet, len(samples)),
+		FileFormatVersion: fileFormatVersion,
+	}
+	for _, sample := range samples {
+		name := SetName(sample)
+		if _, dup := f.ParameterSets[name]; dup {
+			return nil, fmt.Errorf("two samples are named %s", name)
+		}
+		set := make(scad.ParameterSet, len(defaults))
+		for param, value := range defaults {
+			set[param] = value
+		}
+		for param, literal := range sample.Defines(opts) {
+			set[param] = scad.CustomizerValue(literal)
+		}
+		f.ParameterSets[name] = set
+	}
+	return f, nil
+}
+
+// Import converts every parameter set of f into a sample, in set name
+// order. See ImportSet.
+func Import(f *scad.ParameterFile, schema *scad.Schema) ([]*models.FilamentSample, error) {
+	samples := make([]*models.FilamentSample, 0, len(f.ParameterSets))
+	for _, name := range f.SetNames() {
+		sample, err := ImportSet(name, f.ParameterSets[name], schema)
+		if err != nil {
+			return nil, err
+		}
+		samples = append(samples, sample)
+	}
+	return samples, nil
+}
+
+// ImportSet converts a parameter set into a sample. BRAND, TYPE, COLOR and
+// the temperatures fill in its fields, from the template defaults when the
+// set lacks them, and every other value that differs from the template
+// default becomes a field such as BrandSize or one of its Params. So a set
+// written by Export gives back the sample it was written from. schema may
+// be nil, which keeps every value.
+func ImportSet(name string, set scad.ParameterSet, schema *scad.Schema) (*models.FilamentSample, error) {
+	literals, err := set.Literals(schema)
+	if err != nil {
+		return nil, fmt.Errorf("parameter set %q: %w", name, err)
+	}
+
+	sample := &models.FilamentSample{}
+	for _, variable := range fieldVariables {
+		if value, ok := set[variable]; ok {
+			sample.SetVariable(variable, value)
+		} else if p, ok := lookup(schema, variable); ok {
+			sample.SetVariable(variable, scad.CustomizerValue(p.Default))
+		}
+	}
+
+	params := make([]string, 0, len(set))
+	for param := range set {
+		params = append(params, param)
+	}
+	sort.Strings(params)
+	for _, param := range params {
+		literal := literals[param]
+		if models.IsFieldVariable(param) || isDefault(schema, param, literal) {
+			continue
+		}
+		if sample.SetVariable(param, scad.CustomizerValue(literal)) {
+			continue
+		}
+		if sample.Params == nil {
+			sample.Params = make(map[string]string)
+		}
+		sample.Params[param] = cell(set[param], literal)
+	}
+
+	if err := sample.Validate(); err != nil {
+		return nil, fmt.Errorf("parameter set %q: %w", name, err)
+	}
+	sample.NormalizeTemperatures()
+	return sample, nil
+}
+
+func lookup(schema *scad.Schema, name string) (scad.Param, bool) {
+	if schema == nil {
+		return scad.Param{}, false
+	}
+	return schema.Lookup(name)
+}
+
+// isDefault reports whether literal is the value the template assigns to
+// name.
+func isDefault(schema *scad.Schema, name, literal string) bool {
+	p, ok := lookup(schema, name)
+	if !ok || scad.LiteralType(p.Default) == scad.TypeExpression {
+		return false
+	}
+	return scad.Infer(p.Default) == scad.Infer(literal)
+}
+
+// cell returns the text a sample list holds for a parameter: the
+// Customizer's text when it reads back as literal, and the literal itself
+// otherwise, such as "240" in quotes for a string parameter.
+func cell(text, literal string) string {
+	if scad.Infer(text) == literal {
+		return text
+	}
+	return literal
+}
@@ -0,0 +1,115 @@
+package customizer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/guntharp/go-filamentsamples/pkg/models"
+	"github.com/guntharp/go-filamentsamples/pkg/scad"
+)
+
+const testTemplate = `BRAND="extrudr";
+TYPE="Biofusion";
+COLOR="Metallic Grey";
+TEMP_HOTEND="225";
+TEMP_BED="60";
+BRAND_SIZE=4.2;
+TEMP_HOTEND_FIRST_LAYER="240";
+CARD_LENGTH=80.0;
+INSET_DEPTHS=[1.0, 0.8,
+    0.6];
+FONT="Liberation Sans:style=Bold";
+NOTCH_Y=CARD_LENGTH - 6.75;
+`
+
+func testSchema(t *testing.T) *scad.Schema {
+	t.Helper()
+	schema, err := scad.ParseTemplate([]byte(testTemplate))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return schema
+}
+
+func TestExport(t *testing.T) {
+	samples := []*models.FilamentSample{
+		{Brand: "Bambu Labs", Type: "PLA", Color: "Red", TempHotend: "200-220", TempBed: "60", BrandSize: "5"},
+		{Brand: "Polymaker", Type: "PETG", Color: "Blue", TempHotend: "240", TempBed: "70",
+			Params: map[string]string{"FONT": "Noto Sans", "TEMP_HOTEND_FIRST_LAYER": `"250"`, "$fn": "20"}},
+	}
+	opts := models.ArgOptions{Params: map[string]string{"CARD_LENGTH": "90"}}
+
+	f, err := Export(samples, opts, testSchema(t))
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if names := f.SetNames(); !reflect.DeepEqual(names, []string{"Bambu Labs_PLA_Red_200-220_60", "Polymaker_PETG_Blue_240_70"}) {
+		t.Fatalf("SetNames() = %v", names)
+	}
+
+	want := scad.ParameterSet{
+		"BRAND": "Polymaker", "TYPE": "PETG", "COLOR": "Blue", "TEMP_HOTEND": "240", "TEMP_BED": "70",
+		"BRAND_SIZE": "4.2", "TEMP_HOTEND_FIRST_LAYER": "250", "CARD_LENGTH": "90",
+		"INSET_DEPTHS": "[1, 0.8, 0.6]", "FONT": "Noto Sans", "$fn": "20",
+	}
+	if got := f.ParameterSets["Polymaker_PETG_Blue_240_70"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("set = %v, want %v", got, want)
+	}
+	if got := f.ParameterSets["Bambu Labs_PLA_Red_200-220_60"]["BRAND_SIZE"]; got != "5" {
+		t.Errorf("BRAND_SIZE = %s, want 5", got)
+	}
+
+	if _, err := Export(append(samples, samples[0]), opts, testSchema(t)); err == nil {
+		t.Error("Export() should fail for two samples with the same name")
+	}
+}
+
+func TestImport_RoundTrip(t *testing.T) {
+	schema := testSchema(t)
+	samples := []*models.FilamentSample{
+		{Brand: "Bambu Labs", Type: "PLA", Color: "Red", TempHotend: "200-220", TempBed: "60", BrandSize: "5"},
+		{Brand: "Polymaker", Type: "PETG", Color: "Blue", TempHotend: "240", TempBed: "70",
+			Params: map[string]string{"FONT": "Noto Sans", "TEMP_HOTEND_FIRST_LAYER": `"250"`, "$fn": "20", "INSET_DEPTHS": "[1, 0.5]"}},
+	}
+
+	f, err := Export(samples, models.ArgOptions{}, schema)
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	got, err := Import(f, schema)
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, samples) {
+		t.Errorf("Import() = %+v, want %+v", got, samples)
+	}
+
+	again, err := Export(got, models.ArgOptions{}, schema)
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if !reflect.DeepEqual(again, f) {
+		t.Errorf("Export(Import()) = %+v, want %+v", again, f)
+	}
+}
+
+func TestImportSet(t *testing.T) {
+	schema := testSchema(t)
+	set := scad.ParameterSet{"COLOR": "Green", "CARD_LENGTH": "80", "NOTCH_Y": "20", "TEXT_X": "4"}
+
+	got, err := ImportSet("Green", set, schema)
+	if err != nil {
+		t.Fatalf("ImportSet() error = %v", err)
+	}
+	want := &models.FilamentSample{
+		Brand: "extrudr", Type: "Biofusion", Color: "Green", TempHotend: "225", TempBed: "60",
+		Params: map[string]string{"NOTCH_Y": "20", "TEXT_X": "4"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ImportSet() = %+v, want %+v", got, want)
+	}
+
+	if _, err := ImportSet("Empty", scad.ParameterSet{"COLOR": "Green"}, nil); err == nil {
+		t.Error("ImportSet() should fail without a brand or type")
+	}
+}
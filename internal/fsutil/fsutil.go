@@ -0,0 +1,10 @@
+// Package fsutil holds helpers for writing files atomically.
+package fsutil
+
+import "os"
+
+// FileMode is the mode given to files that are written to a temporary file
+// and renamed into place: 0644 less the process umask, as os.WriteFile would
+// create them. os.CreateTemp makes its files 0600, which would leave them
+// unreadable to other users.
+var FileMode = os.FileMode(0644) &^ umask()
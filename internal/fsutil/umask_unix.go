@@ -0,0 +1,17 @@
+//go:build !windows
+
+package fsutil
+
+import (
+	"os"
+	"syscall"
+)
+
+// umask returns the umask of the process. Reading it means setting it, so
+// it is only read once, while the package is initialized and before any
+// goroutine can create files.
+func umask() os.FileMode {
+	mask := syscall.Umask(0)
+	syscall.Umask(mask)
+	return os.FileMode(mask)
+}
@@ -0,0 +1,10 @@
+//go:build windows
+
+package fsutil
+
+import "os"
+
+// umask returns 0: Windows has no umask and only honors the write bits.
+func umask() os.FileMode {
+	return 0
+}
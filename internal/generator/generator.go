@@ -1,132 +1,548 @@
 package generator
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/guntharp/go-filamentsamples/internal/cache"
+	"github.com/guntharp/go-filamentsamples/internal/config"
 	"github.com/guntharp/go-filamentsamples/internal/csv"
+	"github.com/guntharp/go-filamentsamples/internal/fsutil"
+	"github.com/guntharp/go-filamentsamples/internal/input"
+	"github.com/guntharp/go-filamentsamples/internal/materials"
 	"github.com/guntharp/go-filamentsamples/internal/openscad"
+	"github.com/guntharp/go-filamentsamples/internal/textutil"
 	"github.com/guntharp/go-filamentsamples/pkg/models"
+	"github.com/guntharp/go-filamentsamples/pkg/scad"
 )
 
-type Config struct {
-	CSVFile      string
-	OutputDir    string
-	ScadFile     string
-	MaxWorkers   int
-	Verbose      bool
-	DryRun       bool
-}
-
-func (c *Config) Validate() error {
-	if c.CSVFile == "" {
-		return fmt.Errorf("CSVFile is required")
-	}
-
-	if c.MaxWorkers < 1 {
-		c.MaxWorkers = 1
-	}
+var (
+	// ErrOpenSCADUnavailable is returned when the OpenSCAD binary cannot be used.
+	ErrOpenSCADUnavailable = errors.New("OpenSCAD check failed")
+	// ErrParse is returned when the sample list cannot be parsed.
+	ErrParse = errors.New("failed to parse CSV file")
+	// ErrParams is returned when a run-wide template parameter does not
+	// fit the template.
+	ErrParams = errors.New("invalid template parameters")
+)
 
-	if c.MaxWorkers > 32 {
-		c.MaxWorkers = 32
-	}
+// GenerationError reports that some samples failed to generate.
+type GenerationError struct {
+	Failed int
+	Total  int
+}
 
-	return nil
+func (e *GenerationError) Error() string {
+	return fmt.Sprintf("generation completed with %d errors", e.Failed)
 }
 
 type Generator struct {
-	config   *Config
+	config   *config.Config
 	executor Executor
 	parser   Parser
 	logger   *log.Logger
+	// cache holds renders shared between runs and projects, nil when the
+	// cache is disabled.
+	cache *cache.Cache
+	// build is the incremental build state of the current run, nil when
+	// nothing is recorded in the manifest.
+	build *build
+	// parameterSets are the Customizer parameter sets of the current run
+	// as OpenSCAD literals, keyed by set name.
+	parameterSets map[string]map[string]string
+	// rendering holds the output files of the samples handed to the
+	// workers.
+	rendering outputs
+}
+
+// build tracks which samples of a run were already up to date and what the
+// manifest records for the samples rendered.
+type build struct {
+	template []byte
+	version  string
+	// mu guards manifest, which the reader of the sample list, the workers
+	// and the result loop share.
+	mu       sync.Mutex
+	manifest *Manifest
+	skipped  int
+	cached   atomic.Int64
 }
 
+// outputs tracks the output files being rendered, so that a sample with the
+// same output file as one in flight, such as a row repeated in two merged
+// sample lists, waits for it instead of writing the file at the same time.
+type outputs struct {
+	mu      sync.Mutex
+	pending map[string]chan struct{}
+}
+
+// wait returns once no render of name is in flight, or with ctx's error.
+func (o *outputs) wait(ctx context.Context, name string) error {
+	o.mu.Lock()
+	done := o.pending[name]
+	o.mu.Unlock()
+	if done == nil {
+		return nil
+	}
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// start marks a render of name as in flight.
+func (o *outputs) start(name string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.pending == nil {
+		o.pending = make(map[string]chan struct{})
+	}
+	o.pending[name] = make(chan struct{})
+}
+
+// finish marks the render of name as done, releasing the samples waiting
+// for it.
+func (o *outputs) finish(name string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if done, ok := o.pending[name]; ok {
+		close(done)
+		delete(o.pending, name)
+	}
+}
+
+// The input parser streams samples to the workers as it reads them.
+var _ StreamParser = (*input.Parser)(nil)
+
 type GenerationResult struct {
 	Sample *models.FilamentSample
-	Error  error
+	// Output is the path the STL file was written to. It is empty when the
+	// skip conflict policy kept an existing file.
+	Output      string
+	Diagnostics []openscad.Diagnostic
+	Error       error
 }
 
-func NewGenerator(config *Config) (*Generator, error) {
-	scadPath := config.ScadFile
-	if scadPath == "" {
-		scadPath = filepath.Join(filepath.Dir(config.CSVFile), "FilamentSamples.scad")
+func NewGenerator(cfg *config.Config) (*Generator, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
 	}
 
-	executor, err := openscad.NewExecutor(scadPath)
+	executor, err := openscad.NewExecutorWithPath(scadPath(cfg), cfg.OpenSCADPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize OpenSCAD executor: %w", err)
 	}
 
-	parser := csv.NewParser()
+	var renderCache *cache.Cache
+	if cfg.Cache {
+		dir := cfg.CacheDir
+		if dir == "" {
+			if dir, err = cache.DefaultDir(); err != nil {
+				return nil, err
+			}
+		}
+		renderCache = cache.New(dir)
+	}
 
 	logger := log.New(os.Stdout, "", log.LstdFlags)
-	if !config.Verbose {
+	if !cfg.Verbose {
 		logger.SetOutput(os.Stderr)
 	}
 
+	parser := input.NewParser()
+	parser.Format = cfg.InputFormat
+	parser.Profiles = cfg.ProfileLibrary()
+	parser.Lenient = cfg.CSVMode == config.CSVModeLenient
+	parser.Skipped = func(err *csv.RowError) {
+		logger.Printf("Warning: skipping %v", err)
+	}
+	parser.Detected = func(source string, f csv.Format) {
+		if !f.Plain() || cfg.Verbose {
+			logger.Printf("Reading %s as %s", source, f)
+		}
+	}
+	// An unreadable template keeps every column; the render reports it.
+	parser.Schema, _ = openscad.TemplateSchema(scadPath(cfg))
+	parser.Ignored = func(source, column string) {
+		logger.Printf("Warning: %s: ignoring column %s, which the template does not define", source, column)
+	}
+
 	return &Generator{
-		config:   config,
+		config:   cfg,
 		executor: executor,
 		parser:   parser,
 		logger:   logger,
+		cache:    renderCache,
 	}, nil
 }
 
-func (g *Generator) Generate() error {
+// scadPath returns the template to render, defaulting to FilamentSamples.scad
+// next to the first input file.
+func scadPath(cfg *config.Config) string {
+	if cfg.ScadFile != "" {
+		return cfg.ScadFile
+	}
+	return filepath.Join(input.BaseDir(cfg.CSVFile), "FilamentSamples.scad")
+}
+
+// Generate renders every sample whose output is missing or was built from
+// different inputs, unless Force is set. Canceling ctx stops the run: renders in
+// progress are killed, queued samples are not started, and a summary of what
+// finished is logged before returning an error wrapping ctx.Err().
+func (g *Generator) Generate(ctx context.Context) error {
 	if err := g.executor.CheckAvailable(); err != nil {
-		return fmt.Errorf("OpenSCAD check failed: %w", err)
+		return fmt.Errorf("%w: %w", ErrOpenSCADUnavailable, err)
 	}
 
-	if g.config.Verbose {
-		version, _ := g.executor.GetVersion()
+	version, err := g.executor.GetVersion()
+	if err != nil {
+		g.logger.Printf("Warning: cannot determine OpenSCAD version: %v", err)
+	} else if g.config.Verbose {
 		g.logger.Printf("Using OpenSCAD: %s", version)
 	}
 
-	samples, err := g.parser.ParseFile(g.config.CSVFile)
+	sets, err := ParameterSets(g.config, g.templateSchema())
 	if err != nil {
-		return fmt.Errorf("failed to parse CSV file: %w", err)
+		return err
 	}
-
-	g.logger.Printf("Found %d filament samples to process", len(samples))
+	g.parameterSets = sets
 
 	if err := os.MkdirAll(g.config.OutputDir, 0755); err != nil {
 		return fmt.Errorf("failed to create output directory: %w", err)
 	}
 
+	g.build = g.planBuild(version)
+	defer func() { g.build = nil }()
+
 	if g.config.DryRun {
-		g.logger.Println("Dry run mode - no files will be generated")
-		for _, sample := range samples {
-			g.logger.Printf("Would generate: %s", sample.Filename())
+		return g.dryRun()
+	}
+
+	return g.process(ctx, func(emit func(*models.FilamentSample) error) error {
+		return g.stream(func(sample *models.FilamentSample) error {
+			// A sample whose output is being rendered waits for it, and
+			// is then usually up to date.
+			if err := g.rendering.wait(ctx, sample.Filename()); err != nil {
+				return err
+			}
+			if !g.pending(sample) {
+				return nil
+			}
+			return emit(sample)
+		})
+	})
+}
+
+// dryRun logs which samples a run would render.
+func (g *Generator) dryRun() error {
+	g.logger.Println("Dry run mode - no files will be generated")
+
+	read, pending := 0, 0
+	err := g.stream(func(sample *models.FilamentSample) error {
+		read++
+		if g.pending(sample) {
+			pending++
+			g.logger.Printf("Would generate: %s%s", sample.Filename(), formatParams(sample.ParamLiterals(g.argOptions())))
 		}
 		return nil
+	})
+	if err != nil {
+		return err
 	}
 
-	return g.processParallel(samples)
+	if skipped := read - pending; skipped > 0 {
+		g.logger.Printf("Would skip %d samples that are up to date or kept", skipped)
+	}
+	return nil
+}
+
+// formatParams returns template parameters as " (NAME=value, ...)" in name
+// order, or "" when there are none.
+func formatParams(literals map[string]string) string {
+	if len(literals) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(literals))
+	for name := range literals {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for i, name := range names {
+		names[i] = name + "=" + literals[name]
+	}
+	return " (" + strings.Join(names, ", ") + ")"
 }
 
-func (g *Generator) processParallel(samples []*models.FilamentSample) error {
+// stream reads the sample list and passes each sample to fn as soon as it
+// is read, logging implausible temperatures. After a sample with a
+// temperature its material cannot be printed at, or with a template
+// parameter the template does not define or types differently, or with an
+// unknown parameter set, no further samples are passed on; the list is read
+// to the end and then rejected with every such sample. Invalid run-wide
+// parameters, including those of the run's parameter set, reject the run
+// before the list is read. An error returned by fn stops reading and is returned as
+// is.
+func (g *Generator) stream(fn func(*models.FilamentSample) error) error {
+	rules := g.config.MaterialRules()
+	schema := g.templateSchema()
+	opts := g.argOptions()
+	if schema != nil {
+		if err := schema.CheckAll(opts.Params); err != nil {
+			return fmt.Errorf("%w: scad_params: %w", ErrParams, err)
+		}
+		if err := schema.CheckAll(opts.ParameterSets[opts.ParameterSet]); err != nil {
+			return fmt.Errorf("%w: parameter set %q: %w", ErrParams, opts.ParameterSet, err)
+		}
+	}
+	var implausible []materials.Issue
+	var invalid []string
+	var fnErr error
+
+	err := g.readSamples(func(sample *models.FilamentSample) error {
+		if schema != nil {
+			if err := sample.CheckParams(schema); err != nil {
+				for _, err := range models.Unjoin(err) {
+					invalid = append(invalid, sample.Location()+err.Error())
+				}
+			}
+		}
+		if err := sample.CheckParameterSet(opts, schema); err != nil {
+			invalid = append(invalid, sample.Location()+err.Error())
+		}
+		issues := rules.CheckSample(sample)
+		for _, issue := range issues {
+			g.logger.Print(issue)
+		}
+		implausible = append(implausible, materials.Errors(issues)...)
+		if len(implausible) > 0 || len(invalid) > 0 {
+			return nil
+		}
+		fnErr = fn(sample)
+		return fnErr
+	})
+
+	switch {
+	case err != nil && err == fnErr:
+		return err
+	case err != nil:
+		return fmt.Errorf("%w: %w", ErrParse, err)
+	case len(invalid) > 0:
+		return fmt.Errorf("%w: %s:\n  %s", ErrParse, textutil.Plural(len(invalid), "invalid template parameter"), strings.Join(invalid, "\n  "))
+	case len(implausible) > 0:
+		lines := make([]string, len(implausible))
+		for i, issue := range implausible {
+			lines[i] = issue.String()
+		}
+		return fmt.Errorf("%w: %s:\n  %s", ErrParse, textutil.Plural(len(implausible), "implausible temperature"), strings.Join(lines, "\n  "))
+	}
+	return nil
+}
+
+// readSamples passes the samples of the sample list to fn, as they are read
+// when the parser supports streaming.
+func (g *Generator) readSamples(fn func(*models.FilamentSample) error) error {
+	if parser, ok := g.parser.(StreamParser); ok {
+		return parser.StreamFile(g.config.CSVFile, fn)
+	}
+
+	samples, err := g.parser.ParseFile(g.config.CSVFile)
+	if err != nil {
+		return err
+	}
+	for _, sample := range samples {
+		if err := fn(sample); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParameterSets loads the Customizer parameter sets of cfg as OpenSCAD
+// literals keyed by set name, typed by schema, which may be nil. Without a
+// parameter_file a missing default file means there are no sets. It fails
+// with ErrParams when the file cannot be read or has no set called
+// parameter_set.
+func ParameterSets(cfg *config.Config, schema *scad.Schema) (map[string]map[string]string, error) {
+	path := cfg.ParameterFile
+	if path == "" {
+		path = openscad.ParameterFilePath(scadPath(cfg))
+		if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) && cfg.ParameterSet == "" {
+			return nil, nil
+		}
+	}
+
+	file, err := openscad.LoadParameterFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrParams, err)
+	}
+	sets := make(map[string]map[string]string, len(file.ParameterSets))
+	for name, set := range file.ParameterSets {
+		literals, err := set.Literals(schema)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: parameter set %q: %w", ErrParams, path, name, err)
+		}
+		sets[name] = literals
+	}
+
+	if _, ok := sets[cfg.ParameterSet]; cfg.ParameterSet != "" && !ok {
+		return nil, fmt.Errorf("%w: %s has no parameter set %q (it has %s)",
+			ErrParams, path, cfg.ParameterSet, strings.Join(file.SetNames(), ", "))
+	}
+	return sets, nil
+}
+
+// templateSchema returns the parameters the template defines, or nil when
+// it cannot be read or parsed, which the render reports.
+func (g *Generator) templateSchema() *scad.Schema {
+	schema, err := openscad.TemplateSchema(scadPath(g.config))
+	if err != nil {
+		return nil
+	}
+	return schema
+}
+
+// planBuild loads the manifest and the template the samples are hashed
+// with. It returns nil, disabling incremental builds, when the template
+// cannot be read.
+func (g *Generator) planBuild(version string) *build {
+	template, err := os.ReadFile(scadPath(g.config))
+	if err != nil {
+		g.logger.Printf("Warning: cannot read template, rebuilding all samples: %v", err)
+		return nil
+	}
+
+	manifest, err := LoadManifest(g.config.OutputDir)
+	if err != nil {
+		g.logger.Printf("Warning: %v; rebuilding all samples", err)
+	}
+
+	return &build{template: template, version: version, manifest: manifest}
+}
+
+// pending hashes sample and reports whether it needs rendering. Samples
+// whose output is up to date, unless Force is set, and samples whose output
+// the skip conflict policy keeps are counted as skipped instead. An output
+// built with warnings the current policy rejects is not up to date.
+func (g *Generator) pending(sample *models.FilamentSample) bool {
+	name := sample.Filename()
+	if g.build != nil {
+		hash := g.hash(sample)
+		g.build.mu.Lock()
+		upToDate := !g.config.Force && g.build.manifest.UpToDate(g.config.OutputDir, name, hash)
+		entry := g.build.manifest.Entries[name]
+		g.build.mu.Unlock()
+
+		if upToDate {
+			if err := g.checkWarnings(entry.Warnings); err != nil {
+				upToDate = false
+				if g.config.Verbose {
+					g.logger.Printf("Rebuilding %s: %v", name, err)
+				}
+			}
+		}
+		if upToDate {
+			g.build.skipped++
+			if g.config.Verbose {
+				g.logger.Printf("Up to date: %s", name)
+			}
+			return false
+		}
+	}
+
+	if g.config.OnConflict == config.OnConflictSkip {
+		if _, err := os.Lstat(filepath.Join(g.config.OutputDir, name)); err == nil {
+			if g.build != nil {
+				g.build.skipped++
+			}
+			if g.config.Verbose {
+				g.logger.Printf("Keeping existing %s", name)
+			}
+			return false
+		}
+	}
+	return true
+}
+
+// record notes the outcome of a sample in the manifest.
+func (g *Generator) record(result GenerationResult) {
+	if g.build == nil {
+		return
+	}
+	name := result.Sample.Filename()
+	var entry ManifestEntry
+	if result.Error == nil && result.Output != "" {
+		entry = ManifestEntry{
+			Hash:     g.hash(result.Sample),
+			Warnings: openscad.Filter(result.Diagnostics, openscad.SeverityWarning),
+		}
+	}
+
+	g.build.mu.Lock()
+	defer g.build.mu.Unlock()
+	switch {
+	case result.Error != nil:
+		delete(g.build.manifest.Entries, name)
+	case result.Output != "":
+		g.build.manifest.Entries[name] = entry
+	}
+}
+
+// hash returns the build hash of sample. It is computed again when needed
+// rather than kept for every sample, so memory does not grow with the
+// number of samples.
+func (g *Generator) hash(sample *models.FilamentSample) string {
+	return SampleHash(g.args(sample), g.build.template, g.build.version)
+}
+
+// process renders the samples feed passes to emit on MaxWorkers workers.
+// The channels between the stages hold at most one sample per worker, so
+// emit blocks while the workers are busy: rendering starts with the first
+// sample read and memory use does not grow with the number of samples. An
+// error from feed, such as a rejected sample list, is returned once the
+// samples already handed out are finished.
+func (g *Generator) process(ctx context.Context, feed func(emit func(*models.FilamentSample) error) error) error {
 	maxWorkers := g.config.MaxWorkers
 	if maxWorkers <= 0 {
 		maxWorkers = 4
 	}
 
-	jobs := make(chan *models.FilamentSample, len(samples))
-	results := make(chan GenerationResult, len(samples))
+	jobs := make(chan *models.FilamentSample, maxWorkers)
+	results := make(chan GenerationResult, maxWorkers)
 
 	var wg sync.WaitGroup
 
 	for i := 0; i < maxWorkers; i++ {
 		wg.Add(1)
-		go g.worker(jobs, results, &wg)
+		go g.worker(ctx, jobs, results, &wg)
 	}
 
-	for _, sample := range samples {
-		jobs <- sample
-	}
-	close(jobs)
+	fed := make(chan error, 1)
+	go func() {
+		defer close(jobs)
+		fed <- feed(func(sample *models.FilamentSample) error {
+			g.rendering.start(sample.Filename())
+			select {
+			case jobs <- sample:
+				return nil
+			case <-ctx.Done():
+				g.rendering.finish(sample.Filename())
+				return ctx.Err()
+			}
+		})
+	}()
 
 	go func() {
 		wg.Wait()
@@ -135,45 +551,322 @@ func (g *Generator) processParallel(samples []*models.FilamentSample) error {
 
 	var errors []error
 	processed := 0
+	generated := 0
 
 	for result := range results {
 		processed++
-		if result.Error != nil {
+		g.logDiagnostics(result)
+		g.record(result)
+		g.rendering.finish(result.Sample.Filename())
+		switch {
+		case result.Error == nil:
+			generated++
+			if g.config.Verbose {
+				g.logger.Printf("Generated %s (%d done)", result.Sample.Filename(), generated)
+			}
+		case ctx.Err() != nil:
+			// Stopped by cancellation rather than a failure of its own.
+		default:
 			errors = append(errors, result.Error)
 			g.logger.Printf("Failed to generate %s: %v", result.Sample.Filename(), result.Error)
-		} else if g.config.Verbose {
-			g.logger.Printf("Generated %s (%d/%d)", result.Sample.Filename(), processed, len(samples))
 		}
 	}
+	feedErr := <-fed
 
+	skipped, cached := 0, 0
+	if g.build != nil {
+		skipped, cached = g.build.skipped, int(g.build.cached.Load())
+		if err := g.build.manifest.Save(g.config.OutputDir); err != nil {
+			g.logger.Printf("Warning: %v", err)
+		}
+	}
+	total := processed + skipped
+	g.pruneCache()
+
+	if ctx.Err() != nil {
+		g.logger.Printf("Interrupted: %d built, %d skipped, %d failed, %d not finished (of %d samples read)",
+			generated, skipped, len(errors), processed-generated-len(errors), total)
+		return fmt.Errorf("generation interrupted: %w", ctx.Err())
+	}
+	if feedErr != nil && processed == 0 {
+		return feedErr
+	}
+
+	built := fmt.Sprintf("%d", generated)
+	if cached > 0 {
+		built = fmt.Sprintf("%d (%d from cache)", generated, cached)
+	}
+	g.logger.Printf("Built %s, skipped %d, failed %d (of %d samples)", built, skipped, len(errors), total)
+
+	if feedErr != nil {
+		return feedErr
+	}
 	if len(errors) > 0 {
-		return fmt.Errorf("generation completed with %d errors", len(errors))
+		return &GenerationError{Failed: len(errors), Total: total}
 	}
 
-	g.logger.Printf("Successfully generated %d STL files", processed)
 	return nil
 }
 
-func (g *Generator) worker(jobs <-chan *models.FilamentSample, results chan<- GenerationResult, wg *sync.WaitGroup) {
+func (g *Generator) worker(ctx context.Context, jobs <-chan *models.FilamentSample, results chan<- GenerationResult, wg *sync.WaitGroup) {
 	defer wg.Done()
 
 	for sample := range jobs {
-		err := g.generateSample(sample)
-		results <- GenerationResult{
-			Sample: sample,
-			Error:  err,
+		result := GenerationResult{Sample: sample, Error: ctx.Err()}
+		if result.Error == nil {
+			result.Output, result.Diagnostics, result.Error = g.generateSample(ctx, sample)
 		}
+		results <- result
 	}
 }
 
-func (g *Generator) generateSample(sample *models.FilamentSample) error {
+// logDiagnostics prints the warnings and errors of a sample, prefixed with
+// its filename. ECHO and TRACE output is only shown in verbose mode, as are
+// warnings the ignore policy lets pass.
+func (g *Generator) logDiagnostics(result GenerationResult) {
+	for _, d := range result.Diagnostics {
+		if !g.config.Verbose && (d.Severity == openscad.SeverityEcho || d.Severity == openscad.SeverityTrace) {
+			continue
+		}
+		if _, _, undefined := d.UndefinedReference(); !g.config.Verbose && !undefined &&
+			g.config.WarningPolicy == config.WarningPolicyIgnore && d.Severity == openscad.SeverityWarning {
+			continue
+		}
+		g.logger.Printf("%s: %s", result.Sample.Filename(), d)
+	}
+}
+
+// generateSample renders sample into a temporary file next to its output,
+// checks that the result is a complete STL file and only then moves it into
+// place, so a crash or timeout never leaves a truncated STL behind. It
+// returns the path the render was placed at, which is empty when the
+// conflict policy kept an existing file instead.
+func (g *Generator) generateSample(ctx context.Context, sample *models.FilamentSample) (string, []openscad.Diagnostic, error) {
 	outputPath := filepath.Join(g.config.OutputDir, sample.Filename())
-	
-	args := sample.OpenSCADArgs()
-	
+	ext := filepath.Ext(outputPath)
+
+	tmp, err := os.CreateTemp(g.config.OutputDir, tempPrefix+strings.TrimSuffix(sample.Filename(), ext)+"-*"+ext)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create temporary output: %w", err)
+	}
+	tmp.Close()
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	key := g.cacheKey(sample)
+	if key != "" {
+		diagnostics, hit, err := g.cache.Get(key, ext, tmpPath)
+		if err != nil {
+			g.logger.Printf("Warning: render cache: %v", err)
+		} else if hit {
+			// A cached render is held to the same checks as a fresh one.
+			if err := g.checkWarnings(diagnostics); err != nil {
+				return "", diagnostics, err
+			}
+			if err := openscad.ValidateSTL(tmpPath); err != nil {
+				g.logger.Printf("Warning: render cache: %s: %v; rendering again", sample.Filename(), err)
+			} else {
+				g.build.cached.Add(1)
+				if g.config.Verbose {
+					g.logger.Printf("Copied %s from cache", sample.Filename())
+				}
+				output, err := g.place(tmpPath, outputPath)
+				return output, diagnostics, err
+			}
+		}
+	}
+
+	args := g.args(sample)
+
 	if g.config.Verbose {
 		g.logger.Printf("Generating %s", sample.Filename())
 	}
 
-	return g.executor.GenerateSTL(outputPath, args)
-}
\ No newline at end of file
+	if timeout := time.Duration(g.config.SampleTimeout); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	diagnostics, err := g.executor.GenerateSTL(ctx, tmpPath, args)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return "", diagnostics, fmt.Errorf("timed out after %s: %w", time.Duration(g.config.SampleTimeout), err)
+		}
+		return "", diagnostics, err
+	}
+
+	if err := g.checkWarnings(diagnostics); err != nil {
+		return "", diagnostics, err
+	}
+
+	if err := openscad.ValidateSTL(tmpPath); err != nil {
+		return "", diagnostics, fmt.Errorf("OpenSCAD output rejected: %w", err)
+	}
+
+	if key != "" {
+		if err := g.cache.Put(key, ext, tmpPath, diagnostics); err != nil {
+			g.logger.Printf("Warning: render cache: %v", err)
+		}
+	}
+
+	output, err := g.place(tmpPath, outputPath)
+	return output, diagnostics, err
+}
+
+// tempPrefix marks renders that have not been moved into place yet.
+const tempPrefix = ".tmp-"
+
+// place moves a finished render from tmpPath to outputPath according to the
+// conflict policy and returns outputPath, or "" if an existing file was kept
+// instead. The render gets the mode of a newly created file rather than the
+// 0600 of the temporary file.
+func (g *Generator) place(tmpPath, outputPath string) (string, error) {
+	switch g.config.OnConflict {
+	case config.OnConflictSkip:
+		if _, err := os.Lstat(outputPath); err == nil {
+			return "", nil
+		}
+	case config.OnConflictKeepBoth:
+		if err := keepAside(outputPath); err != nil {
+			return "", err
+		}
+	}
+
+	if err := os.Chmod(tmpPath, fsutil.FileMode); err != nil {
+		return "", fmt.Errorf("failed to move output into place: %w", err)
+	}
+	if err := os.Rename(tmpPath, outputPath); err != nil {
+		return "", fmt.Errorf("failed to move output into place: %w", err)
+	}
+	return outputPath, nil
+}
+
+// keepAside gives an existing file at path a second, numbered name such as
+// card-2.stl, so that it survives path being replaced.
+func keepAside(path string) error {
+	if _, err := os.Lstat(path); err != nil {
+		return nil
+	}
+
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	for n := 2; ; n++ {
+		candidate := fmt.Sprintf("%s-%d%s", base, n, ext)
+		// Both linking and the exclusive copy fail if the candidate exists,
+		// so two runs can never claim the same name.
+		err := os.Link(path, candidate)
+		if err != nil && !errors.Is(err, fs.ErrExist) {
+			err = copyExclusive(path, candidate)
+		}
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to keep existing output: %w", err)
+		}
+		return nil
+	}
+}
+
+// copyExclusive copies src to dst, which must not exist yet.
+func copyExclusive(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	return out.Close()
+}
+
+// args returns the OpenSCAD defines for sample.
+func (g *Generator) args(sample *models.FilamentSample) []string {
+	return sample.OpenSCADArgsWith(g.argOptions())
+}
+
+// argOptions returns how samples are passed to the template, with the
+// run-wide parameters of the config, which Validate has checked, and the
+// parameter sets of the run.
+func (g *Generator) argOptions() models.ArgOptions {
+	params, _ := g.config.ParamLiterals()
+	return models.ArgOptions{
+		TemperatureFormat: g.config.TemperatureFormat,
+		Params:            params,
+		ParameterSets:     g.parameterSets,
+		ParameterSet:      g.config.ParameterSet,
+	}
+}
+
+// cacheKey returns the render cache key of sample, or "" when the cache is
+// disabled or no build is recorded. The key is the build hash, which covers
+// the template, the parameters and the OpenSCAD version; the output format
+// is part of the cached file's extension.
+func (g *Generator) cacheKey(sample *models.FilamentSample) string {
+	if g.cache == nil || g.build == nil {
+		return ""
+	}
+	return g.hash(sample)
+}
+
+// pruneCache evicts the least recently used renders once the cache exceeds
+// its size limit.
+func (g *Generator) pruneCache() {
+	if g.cache == nil {
+		return
+	}
+
+	maxSize := int64(g.config.CacheMaxSize)
+	if maxSize <= 0 {
+		maxSize = cache.DefaultMaxSize
+	}
+
+	result, err := g.cache.Prune(maxSize)
+	if err != nil {
+		g.logger.Printf("Warning: render cache: %v", err)
+		return
+	}
+	if result.Removed > 0 && g.config.Verbose {
+		g.logger.Printf("Evicted %d renders (%s) from the cache", result.Removed, config.ByteSize(result.Freed))
+	}
+}
+
+// checkWarnings applies the configured warning policy to a render that
+// otherwise succeeded. References to undefined names fail the sample under
+// every policy, since OpenSCAD renders them as undef and the resulting card
+// is silently wrong; the policy only governs other warnings.
+func (g *Generator) checkWarnings(diagnostics []openscad.Diagnostic) error {
+	var undefined []string
+	for _, d := range diagnostics {
+		if kind, name, ok := d.UndefinedReference(); ok {
+			ref := fmt.Sprintf("%s %s", kind, name)
+			if d.Line > 0 {
+				ref = fmt.Sprintf("%s (line %d)", ref, d.Line)
+			}
+			undefined = append(undefined, ref)
+		}
+	}
+	if len(undefined) > 0 {
+		return fmt.Errorf("template references undefined %s", strings.Join(undefined, ", "))
+	}
+
+	if g.config.WarningPolicy != config.WarningPolicyFail {
+		return nil
+	}
+
+	warnings := openscad.Filter(diagnostics, openscad.SeverityWarning)
+	if len(warnings) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("OpenSCAD reported %d warnings: %s", len(warnings), warnings[0].Message)
+}
@@ -8,21 +8,6 @@ import (
 	"github.com/guntharp/go-filamentsamples/pkg/models"
 )
 
-func BenchmarkConfig_Validate(b *testing.B) {
-	config := Config{
-		CSVFile:    "test.csv",
-		OutputDir:  "output",
-		MaxWorkers: 4,
-		Verbose:    false,
-		DryRun:     false,
-	}
-
-	b.ResetTimer()
-	for i := 0; i < b.N; i++ {
-		_ = config.Validate()
-	}
-}
-
 func BenchmarkGenerationResult_Creation(b *testing.B) {
 	sample := &models.FilamentSample{
 		Brand:      "Test Brand",
@@ -162,20 +147,3 @@ func BenchmarkFileOperations(b *testing.B) {
 		_ = err
 	}
 }
-
-func BenchmarkConfig_WorkerValidation(b *testing.B) {
-	configs := []Config{
-		{CSVFile: "test.csv", MaxWorkers: 0},
-		{CSVFile: "test.csv", MaxWorkers: -5},
-		{CSVFile: "test.csv", MaxWorkers: 100},
-		{CSVFile: "test.csv", MaxWorkers: 4},
-	}
-
-	b.ResetTimer()
-	for i := 0; i < b.N; i++ {
-		for _, config := range configs {
-			configCopy := config // Avoid modifying the original
-			_ = configCopy.Validate()
-		}
-	}
-}
\ No newline at end of file
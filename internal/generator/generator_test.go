@@ -1,11 +1,14 @@
 package generator
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/guntharp/go-filamentsamples/internal/config"
+	"github.com/guntharp/go-filamentsamples/internal/openscad"
 	"github.com/guntharp/go-filamentsamples/pkg/models"
 )
 
@@ -23,7 +26,7 @@ func TestNewGenerator(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	config := &Config{
+	cfg := &config.Config{
 		CSVFile:    csvFile,
 		OutputDir:  filepath.Join(tempDir, "output"),
 		ScadFile:   scadFile,
@@ -33,10 +36,10 @@ func TestNewGenerator(t *testing.T) {
 	}
 
 	// This test may fail if OpenSCAD is not installed, which is expected
-	gen, err := NewGenerator(config)
+	gen, err := NewGenerator(cfg)
 	if err != nil {
 		// Check if it's an OpenSCAD availability issue
-		if err.Error() == "failed to initialize OpenSCAD executor: OpenSCAD not found in standard locations or PATH" {
+		if errors.Is(err, openscad.ErrNotFound) {
 			t.Skip("OpenSCAD not available for testing")
 		}
 		t.Fatalf("NewGenerator() error = %v", err)
@@ -46,72 +49,11 @@ func TestNewGenerator(t *testing.T) {
 		t.Fatal("NewGenerator() returned nil generator")
 	}
 
-	if gen.config != config {
+	if gen.config != cfg {
 		t.Error("Generator config not set correctly")
 	}
 }
 
-func TestConfig_Validate(t *testing.T) {
-	tests := []struct {
-		name    string
-		config  Config
-		wantErr bool
-	}{
-		{
-			name: "valid config",
-			config: Config{
-				CSVFile:    "test.csv",
-				OutputDir:  "output",
-				MaxWorkers: 4,
-			},
-			wantErr: false,
-		},
-		{
-			name: "missing CSV file",
-			config: Config{
-				OutputDir:  "output",
-				MaxWorkers: 4,
-			},
-			wantErr: true,
-		},
-		{
-			name: "zero workers gets corrected",
-			config: Config{
-				CSVFile:    "test.csv",
-				OutputDir:  "output",
-				MaxWorkers: 0,
-			},
-			wantErr: false,
-		},
-		{
-			name: "too many workers gets corrected",
-			config: Config{
-				CSVFile:    "test.csv",
-				OutputDir:  "output",
-				MaxWorkers: 100,
-			},
-			wantErr: false,
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			err := tt.config.Validate()
-			if (err != nil) != tt.wantErr {
-				t.Errorf("Config.Validate() error = %v, wantErr %v", err, tt.wantErr)
-			}
-
-			// Check worker count corrections
-			if tt.config.MaxWorkers == 0 && tt.config.MaxWorkers != 1 {
-				t.Error("Zero workers should be corrected to 1")
-			}
-			if tt.config.MaxWorkers > 32 {
-				t.Error("Worker count should be capped at 32")
-			}
-		})
-	}
-}
-
 func TestGenerationResult(t *testing.T) {
 	sample := &models.FilamentSample{
 		Brand:      "Test",
@@ -136,6 +78,42 @@ func TestGenerationResult(t *testing.T) {
 }
 
 
+func TestNewGenerator_OpenSCADPath(t *testing.T) {
+	tempDir := t.TempDir()
+	fakeOpenSCAD := filepath.Join(tempDir, "openscad")
+	if err := os.WriteFile(fakeOpenSCAD, []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{
+		CSVFile:      filepath.Join(tempDir, "test.csv"),
+		OutputDir:    filepath.Join(tempDir, "output"),
+		MaxWorkers:   2,
+		OpenSCADPath: fakeOpenSCAD,
+	}
+
+	gen, err := NewGenerator(cfg)
+	if err != nil {
+		t.Fatalf("NewGenerator() error = %v", err)
+	}
+
+	executor, ok := gen.executor.(*openscad.Executor)
+	if !ok {
+		t.Fatalf("unexpected executor type %T", gen.executor)
+	}
+	if executor.OpenSCADPath != fakeOpenSCAD {
+		t.Errorf("OpenSCADPath = %s, want %s", executor.OpenSCADPath, fakeOpenSCAD)
+	}
+	if executor.ScadFile != filepath.Join(tempDir, "FilamentSamples.scad") {
+		t.Errorf("ScadFile = %s, want template next to the CSV file", executor.ScadFile)
+	}
+
+	cfg.OpenSCADPath = filepath.Join(tempDir, "missing")
+	if _, err := NewGenerator(cfg); err == nil {
+		t.Error("NewGenerator() should reject a missing OpenSCAD path")
+	}
+}
+
 func TestWorkerConcurrency(t *testing.T) {
 	// Test that we can create multiple workers without issues
 	samples := []*models.FilamentSample{
@@ -1,17 +1,28 @@
 package generator
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"log"
 	"os"
 	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/guntharp/go-filamentsamples/internal/cache"
+	"github.com/guntharp/go-filamentsamples/internal/config"
+	"github.com/guntharp/go-filamentsamples/internal/fsutil"
+	"github.com/guntharp/go-filamentsamples/internal/materials"
+	"github.com/guntharp/go-filamentsamples/internal/openscad"
 	"github.com/guntharp/go-filamentsamples/pkg/models"
 )
 
@@ -30,7 +41,7 @@ func TestGenerator_Generate(t *testing.T) {
 	mockExecutor := &MockExecutor{
 		CheckAvailableFunc: func() error { return nil },
 		GetVersionFunc:     func() (string, error) { return "1.0.0", nil },
-		GenerateSTLFunc:    func(outputPath string, args []string) error { return nil },
+		GenerateSTLFunc:    func(ctx context.Context, outputPath string, args []string) error { return nil },
 	}
 
 	// Create mock parser
@@ -50,7 +61,7 @@ func TestGenerator_Generate(t *testing.T) {
 
 	// Create generator with mocked components
 	gen := &Generator{
-		config: &Config{
+		config: &config.Config{
 			CSVFile:    csvFile,
 			OutputDir:  outputDir,
 			MaxWorkers: 2,
@@ -63,7 +74,7 @@ func TestGenerator_Generate(t *testing.T) {
 	}
 
 	// Test successful generation
-	err := gen.Generate()
+	err := gen.Generate(context.Background())
 	if err != nil {
 		t.Errorf("Generate() error = %v, want nil", err)
 	}
@@ -89,7 +100,7 @@ func TestGenerator_Generate_DryRun(t *testing.T) {
 	mockExecutor := &MockExecutor{
 		CheckAvailableFunc: func() error { return nil },
 		GetVersionFunc:     func() (string, error) { return "1.0.0", nil },
-		GenerateSTLFunc: func(outputPath string, args []string) error {
+		GenerateSTLFunc: func(ctx context.Context, outputPath string, args []string) error {
 			return errors.New("should not be called in dry run")
 		},
 	}
@@ -111,27 +122,30 @@ func TestGenerator_Generate_DryRun(t *testing.T) {
 					Color:      "Blue",
 					TempHotend: "240-260",
 					TempBed:    "70-75",
+					Params:     map[string]string{"$fn": "100"},
 				},
 			}, nil
 		},
 	}
 
 	// Create generator with dry run enabled
+	var logs bytes.Buffer
 	gen := &Generator{
-		config: &Config{
+		config: &config.Config{
 			CSVFile:    csvFile,
 			OutputDir:  outputDir,
 			MaxWorkers: 2,
 			Verbose:    false,
 			DryRun:     true,
+			ScadParams: map[string]any{"$fn": 20.0},
 		},
 		executor: mockExecutor,
 		parser:   mockParser,
-		logger:   log.New(io.Discard, "", 0),
+		logger:   log.New(&logs, "", 0),
 	}
 
 	// Test dry run
-	err := gen.Generate()
+	err := gen.Generate(context.Background())
 	if err != nil {
 		t.Errorf("Generate() with dry run error = %v, want nil", err)
 	}
@@ -140,6 +154,12 @@ func TestGenerator_Generate_DryRun(t *testing.T) {
 	if mockExecutor.GetCallCount() > 0 {
 		t.Error("GenerateSTL should not be called in dry run mode")
 	}
+
+	for _, want := range []string{"Would generate: Test_PLA_Red_200-220_60-65.stl ($fn=20)", "Would generate: Test_PETG_Blue_240-260_70-75.stl ($fn=100)"} {
+		if !contains(logs.String(), want) {
+			t.Errorf("log does not contain %q:\n%s", want, logs.String())
+		}
+	}
 }
 
 func TestGenerator_Generate_Errors(t *testing.T) {
@@ -155,7 +175,7 @@ func TestGenerator_Generate_Errors(t *testing.T) {
 			name: "OpenSCAD not available",
 			setupFunc: func() *Generator {
 				return &Generator{
-					config: &Config{
+					config: &config.Config{
 						CSVFile:   csvFile,
 						OutputDir: tempDir,
 					},
@@ -176,7 +196,7 @@ func TestGenerator_Generate_Errors(t *testing.T) {
 			name: "CSV parse error",
 			setupFunc: func() *Generator {
 				return &Generator{
-					config: &Config{
+					config: &config.Config{
 						CSVFile:   csvFile,
 						OutputDir: tempDir,
 					},
@@ -196,10 +216,15 @@ func TestGenerator_Generate_Errors(t *testing.T) {
 		{
 			name: "output directory creation failure",
 			setupFunc: func() *Generator {
+				// A regular file in the path makes MkdirAll fail even as root.
+				blocker := filepath.Join(tempDir, "blocker")
+				if err := os.WriteFile(blocker, nil, 0644); err != nil {
+					t.Fatal(err)
+				}
 				return &Generator{
-					config: &Config{
+					config: &config.Config{
 						CSVFile:   csvFile,
-						OutputDir: "/invalid/path/that/cannot/be/created",
+						OutputDir: filepath.Join(blocker, "output"),
 					},
 					executor: &MockExecutor{
 						CheckAvailableFunc: func() error { return nil },
@@ -227,7 +252,7 @@ func TestGenerator_Generate_Errors(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			gen := tt.setupFunc()
-			err := gen.Generate()
+			err := gen.Generate(context.Background())
 			if err == nil {
 				t.Error("Expected error, got nil")
 			}
@@ -238,14 +263,14 @@ func TestGenerator_Generate_Errors(t *testing.T) {
 	}
 }
 
-func TestGenerator_processParallel(t *testing.T) {
+func TestGenerator_process(t *testing.T) {
 	// Create test samples
 	samples := createTestSamples(10)
 
 	// Track generation calls
 	var generatedCount int32
 	mockExecutor := &MockExecutor{
-		GenerateSTLFunc: func(outputPath string, args []string) error {
+		GenerateSTLFunc: func(ctx context.Context, outputPath string, args []string) error {
 			atomic.AddInt32(&generatedCount, 1)
 			time.Sleep(10 * time.Millisecond) // Simulate work
 			return nil
@@ -253,7 +278,7 @@ func TestGenerator_processParallel(t *testing.T) {
 	}
 
 	gen := &Generator{
-		config: &Config{
+		config: &config.Config{
 			OutputDir:  t.TempDir(),
 			MaxWorkers: 3,
 			Verbose:    false,
@@ -263,9 +288,9 @@ func TestGenerator_processParallel(t *testing.T) {
 	}
 
 	// Process samples
-	err := gen.processParallel(samples)
+	err := gen.process(context.Background(), feed(samples))
 	if err != nil {
-		t.Errorf("processParallel() error = %v", err)
+		t.Errorf("process() error = %v", err)
 	}
 
 	// Verify all samples were processed
@@ -274,14 +299,14 @@ func TestGenerator_processParallel(t *testing.T) {
 	}
 }
 
-func TestGenerator_processParallel_WithErrors(t *testing.T) {
+func TestGenerator_process_WithErrors(t *testing.T) {
 	// Create test samples
 	samples := createTestSamples(5)
 
 	// Mock executor that fails for some samples
 	var callCount int32
 	mockExecutor := &MockExecutor{
-		GenerateSTLFunc: func(outputPath string, args []string) error {
+		GenerateSTLFunc: func(ctx context.Context, outputPath string, args []string) error {
 			count := atomic.AddInt32(&callCount, 1)
 			if count == 2 || count == 4 {
 				return fmt.Errorf("generation failed for call %d", count)
@@ -291,7 +316,7 @@ func TestGenerator_processParallel_WithErrors(t *testing.T) {
 	}
 
 	gen := &Generator{
-		config: &Config{
+		config: &config.Config{
 			OutputDir:  t.TempDir(),
 			MaxWorkers: 2,
 			Verbose:    false,
@@ -301,7 +326,7 @@ func TestGenerator_processParallel_WithErrors(t *testing.T) {
 	}
 
 	// Process samples
-	err := gen.processParallel(samples)
+	err := gen.process(context.Background(), feed(samples))
 	if err == nil {
 		t.Error("Expected error when some samples fail")
 	}
@@ -324,11 +349,11 @@ func TestGenerator_worker(t *testing.T) {
 	// Create generator with mock
 	var processedCount int32
 	gen := &Generator{
-		config: &Config{
+		config: &config.Config{
 			OutputDir: t.TempDir(),
 		},
 		executor: &MockExecutor{
-			GenerateSTLFunc: func(outputPath string, args []string) error {
+			GenerateSTLFunc: func(ctx context.Context, outputPath string, args []string) error {
 				atomic.AddInt32(&processedCount, 1)
 				return nil
 			},
@@ -339,7 +364,7 @@ func TestGenerator_worker(t *testing.T) {
 	// Run worker
 	var wg sync.WaitGroup
 	wg.Add(1)
-	go gen.worker(jobs, results, &wg)
+	go gen.worker(context.Background(), jobs, results, &wg)
 	wg.Wait()
 	close(results)
 
@@ -398,19 +423,19 @@ func TestGenerator_generateSample(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			gen := &Generator{
-				config: &Config{
+				config: &config.Config{
 					OutputDir: t.TempDir(),
 					Verbose:   tt.verbose,
 				},
 				executor: &MockExecutor{
-					GenerateSTLFunc: func(outputPath string, args []string) error {
+					GenerateSTLFunc: func(ctx context.Context, outputPath string, args []string) error {
 						return tt.genErr
 					},
 				},
 				logger: log.New(io.Discard, "", 0),
 			}
 
-			err := gen.generateSample(sample)
+			_, _, err := gen.generateSample(context.Background(), sample)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("generateSample() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -421,4 +446,960 @@ func TestGenerator_generateSample(t *testing.T) {
 // Helper function
 func contains(s, substr string) bool {
 	return len(s) >= len(substr) && (s == substr || len(substr) == 0 || s[0:len(substr)] == substr || contains(s[1:], substr))
-}
\ No newline at end of file
+}
+func TestGenerator_process_Cancel(t *testing.T) {
+	samples := createTestSamples(10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var calls int32
+	mockExecutor := &MockExecutor{
+		GenerateSTLFunc: func(ctx context.Context, outputPath string, args []string) error {
+			if atomic.AddInt32(&calls, 1) == 2 {
+				cancel()
+			}
+			if atomic.LoadInt32(&calls) >= 2 {
+				<-ctx.Done()
+				return ctx.Err()
+			}
+			return nil
+		},
+	}
+
+	gen := &Generator{
+		config: &config.Config{
+			OutputDir:  t.TempDir(),
+			MaxWorkers: 1,
+		},
+		executor: mockExecutor,
+		logger:   log.New(io.Discard, "", 0),
+	}
+
+	err := gen.process(ctx, feed(samples))
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("process() error = %v, want context.Canceled", err)
+	}
+
+	var genErr *GenerationError
+	if errors.As(err, &genErr) {
+		t.Error("cancellation should not be reported as failed samples")
+	}
+
+	if got := mockExecutor.GetCallCount(); got != 2 {
+		t.Errorf("expected no samples to start after cancellation, got %d calls", got)
+	}
+}
+
+func TestGenerator_generateSample_Timeout(t *testing.T) {
+	sample := createTestSamples(1)[0]
+
+	gen := &Generator{
+		config: &config.Config{
+			OutputDir:     t.TempDir(),
+			SampleTimeout: config.Duration(20 * time.Millisecond),
+		},
+		executor: &MockExecutor{
+			GenerateSTLFunc: func(ctx context.Context, outputPath string, args []string) error {
+				<-ctx.Done()
+				return ctx.Err()
+			},
+		},
+		logger: log.New(io.Discard, "", 0),
+	}
+
+	_, _, err := gen.generateSample(context.Background(), sample)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("generateSample() error = %v, want deadline exceeded", err)
+	}
+	if !contains(err.Error(), "timed out after 20ms") {
+		t.Errorf("timeout error should name the limit, got %v", err)
+	}
+
+	// A timed-out sample is a failure of that sample, not an interruption
+	err = gen.process(context.Background(), feed([]*models.FilamentSample{sample}))
+	var genErr *GenerationError
+	if !errors.As(err, &genErr) || genErr.Failed != 1 {
+		t.Errorf("process() error = %v, want one failed sample", err)
+	}
+}
+
+func TestGenerator_WarningPolicy(t *testing.T) {
+	warning := openscad.Diagnostic{Severity: openscad.SeverityWarning, Message: "Object may not be a valid 2-manifold"}
+
+	tests := []struct {
+		name    string
+		policy  string
+		wantErr bool
+	}{
+		{name: "default reports", policy: "", wantErr: false},
+		{name: "report", policy: config.WarningPolicyReport, wantErr: false},
+		{name: "fail", policy: config.WarningPolicyFail, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outputDir := t.TempDir()
+			var logs bytes.Buffer
+			gen := &Generator{
+				config: &config.Config{
+					OutputDir:     outputDir,
+					MaxWorkers:    1,
+					WarningPolicy: tt.policy,
+				},
+				executor: &MockExecutor{
+					GenerateSTLFunc: func(ctx context.Context, outputPath string, args []string) error {
+						return os.WriteFile(outputPath, []byte(testSTL("card")), 0644)
+					},
+					DiagnosticsFunc: func(outputPath string, args []string) []openscad.Diagnostic {
+						return []openscad.Diagnostic{warning}
+					},
+				},
+				logger: log.New(&logs, "", 0),
+			}
+
+			sample := createTestSamples(1)[0]
+			_, diagnostics, err := gen.generateSample(context.Background(), sample)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("generateSample() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(diagnostics) != 1 {
+				t.Errorf("expected diagnostics to be returned, got %v", diagnostics)
+			}
+
+			_, statErr := os.Stat(filepath.Join(outputDir, sample.Filename()))
+			if tt.wantErr && !os.IsNotExist(statErr) {
+				t.Error("output of a sample failed by the warning policy should be removed")
+			}
+
+			gen.process(context.Background(), feed([]*models.FilamentSample{sample}))
+			if !contains(logs.String(), sample.Filename()+": WARNING: Object may not be a valid 2-manifold") {
+				t.Errorf("warning not logged with the sample name:\n%s", logs.String())
+			}
+		})
+	}
+}
+
+func TestGenerator_UndefinedReferences(t *testing.T) {
+	undefined := openscad.Diagnostic{
+		Severity: openscad.SeverityWarning,
+		Message:  "Ignoring unknown variable 'ABS_NOTCH_X' in file FilamentSamples.scad, line 186",
+		File:     "FilamentSamples.scad",
+		Line:     186,
+	}
+
+	tests := []struct {
+		policy  string
+		wantErr bool
+	}{
+		{policy: config.WarningPolicyReport, wantErr: true},
+		{policy: config.WarningPolicyFail, wantErr: true},
+		{policy: config.WarningPolicyIgnore, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			gen := &Generator{
+				config: &config.Config{
+					OutputDir:     t.TempDir(),
+					MaxWorkers:    1,
+					WarningPolicy: tt.policy,
+				},
+				executor: &MockExecutor{
+					GenerateSTLFunc: func(ctx context.Context, outputPath string, args []string) error {
+						return os.WriteFile(outputPath, []byte(testSTL("card")), 0644)
+					},
+					DiagnosticsFunc: func(outputPath string, args []string) []openscad.Diagnostic {
+						return []openscad.Diagnostic{undefined}
+					},
+				},
+				logger: log.New(io.Discard, "", 0),
+			}
+
+			_, _, err := gen.generateSample(context.Background(), createTestSamples(1)[0])
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("generateSample() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !contains(err.Error(), "variable ABS_NOTCH_X (line 186)") {
+				t.Errorf("error should name the undefined variable, got %v", err)
+			}
+		})
+	}
+}
+
+func TestGenerator_Generate_Incremental(t *testing.T) {
+	tempDir := t.TempDir()
+	scadFile := filepath.Join(tempDir, "test.scad")
+	outputDir := filepath.Join(tempDir, "output")
+	if err := os.WriteFile(scadFile, []byte("cube(1);"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	samples := createTestSamples(3)
+	var failName atomic.Value
+	failName.Store("")
+	mockExecutor := &MockExecutor{
+		GenerateSTLFunc: func(ctx context.Context, outputPath string, args []string) error {
+			name := failName.Load().(string)
+			if name != "" && contains(filepath.Base(outputPath), strings.TrimSuffix(name, ".stl")) {
+				return errors.New("render failed")
+			}
+			return os.WriteFile(outputPath, []byte(testSTL("card")), 0644)
+		},
+	}
+
+	var logs bytes.Buffer
+	gen := &Generator{
+		config: &config.Config{
+			CSVFile:    filepath.Join(tempDir, "test.csv"),
+			ScadFile:   scadFile,
+			OutputDir:  outputDir,
+			MaxWorkers: 2,
+		},
+		executor: mockExecutor,
+		parser: &MockParser{
+			ParseFileFunc: func(filename string) ([]*models.FilamentSample, error) {
+				return samples, nil
+			},
+		},
+		logger: log.New(&logs, "", 0),
+	}
+
+	run := func(wantCalls int, wantSummary string) {
+		t.Helper()
+		logs.Reset()
+		before := mockExecutor.GetCallCount()
+		gen.Generate(context.Background())
+		if calls := mockExecutor.GetCallCount() - before; calls != wantCalls {
+			t.Errorf("rendered %d samples, want %d", calls, wantCalls)
+		}
+		if !contains(logs.String(), wantSummary) {
+			t.Errorf("summary %q not logged:\n%s", wantSummary, logs.String())
+		}
+	}
+
+	run(3, "Built 3, skipped 0, failed 0 (of 3 samples)")
+	run(0, "Built 0, skipped 3, failed 0 (of 3 samples)")
+
+	// A changed row and a deleted output are rebuilt.
+	samples[0].Color = "Changed"
+	os.Remove(filepath.Join(outputDir, samples[1].Filename()))
+	run(2, "Built 2, skipped 1, failed 0 (of 3 samples)")
+
+	// A failed sample is not recorded and is retried next time.
+	samples[2].TempBed = "65"
+	failName.Store(samples[2].Filename())
+	run(1, "Built 0, skipped 2, failed 1 (of 3 samples)")
+	failName.Store("")
+	run(1, "Built 1, skipped 2, failed 0 (of 3 samples)")
+
+	// Changing the template rebuilds everything.
+	if err := os.WriteFile(scadFile, []byte("cube(2);"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	run(3, "Built 3, skipped 0, failed 0 (of 3 samples)")
+
+	gen.config.Force = true
+	run(3, "Built 3, skipped 0, failed 0 (of 3 samples)")
+}
+
+func TestGenerator_Generate_IncrementalWarnings(t *testing.T) {
+	tempDir := t.TempDir()
+	scadFile := filepath.Join(tempDir, "test.scad")
+	if err := os.WriteFile(scadFile, []byte("cube(1);"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	samples := createTestSamples(2)
+	mockExecutor := &MockExecutor{
+		GenerateSTLFunc: func(ctx context.Context, outputPath string, args []string) error {
+			return os.WriteFile(outputPath, []byte(testSTL("card")), 0644)
+		},
+		DiagnosticsFunc: func(outputPath string, args []string) []openscad.Diagnostic {
+			return []openscad.Diagnostic{{Severity: openscad.SeverityWarning, Message: "scale() with 0 factor"}}
+		},
+	}
+
+	var logs bytes.Buffer
+	gen := &Generator{
+		config: &config.Config{
+			CSVFile:       filepath.Join(tempDir, "test.csv"),
+			ScadFile:      scadFile,
+			OutputDir:     filepath.Join(tempDir, "output"),
+			MaxWorkers:    2,
+			WarningPolicy: config.WarningPolicyIgnore,
+		},
+		executor: mockExecutor,
+		parser: &MockParser{
+			ParseFileFunc: func(filename string) ([]*models.FilamentSample, error) {
+				return samples, nil
+			},
+		},
+		logger: log.New(&logs, "", 0),
+	}
+
+	run := func(wantCalls int, wantSummary string) {
+		t.Helper()
+		logs.Reset()
+		before := mockExecutor.GetCallCount()
+		gen.Generate(context.Background())
+		if calls := mockExecutor.GetCallCount() - before; calls != wantCalls {
+			t.Errorf("rendered %d samples, want %d", calls, wantCalls)
+		}
+		if !contains(logs.String(), wantSummary) {
+			t.Errorf("summary %q not logged:\n%s", wantSummary, logs.String())
+		}
+	}
+
+	run(2, "Built 2, skipped 0, failed 0 (of 2 samples)")
+	run(0, "Built 0, skipped 2, failed 0 (of 2 samples)")
+
+	// Outputs built with warnings are not up to date under the fail policy.
+	gen.config.WarningPolicy = config.WarningPolicyFail
+	run(2, "Built 0, skipped 0, failed 2 (of 2 samples)")
+}
+
+func TestGenerator_Generate_DuplicateOutputs(t *testing.T) {
+	tempDir := t.TempDir()
+	scadFile := filepath.Join(tempDir, "test.scad")
+	if err := os.WriteFile(scadFile, []byte("cube(1);"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The same row in two merged sample lists.
+	samples := createTestSamples(2)
+	samples = append(samples, &models.FilamentSample{
+		Brand: samples[0].Brand, Type: samples[0].Type, Color: samples[0].Color,
+		TempHotend: samples[0].TempHotend, TempBed: samples[0].TempBed,
+	})
+
+	var mu sync.Mutex
+	rendering := map[string]int{}
+	overlapped := false
+	executor := &MockExecutor{
+		GenerateSTLFunc: func(ctx context.Context, outputPath string, args []string) error {
+			key := strings.Join(args, " ")
+			mu.Lock()
+			rendering[key]++
+			overlapped = overlapped || rendering[key] > 1
+			mu.Unlock()
+			time.Sleep(20 * time.Millisecond)
+			mu.Lock()
+			rendering[key]--
+			mu.Unlock()
+			return nil
+		},
+	}
+
+	var logs bytes.Buffer
+	gen := &Generator{
+		config: &config.Config{
+			CSVFile:    filepath.Join(tempDir, "test.csv"),
+			ScadFile:   scadFile,
+			OutputDir:  filepath.Join(tempDir, "output"),
+			MaxWorkers: 4,
+		},
+		executor: executor,
+		parser: &MockParser{
+			ParseFileFunc: func(filename string) ([]*models.FilamentSample, error) {
+				return samples, nil
+			},
+		},
+		logger: log.New(&logs, "", 0),
+	}
+
+	if err := gen.Generate(context.Background()); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if overlapped {
+		t.Error("two samples with the same output file were rendered at the same time")
+	}
+	// The repeated row waits for the first and is then up to date.
+	if !contains(logs.String(), "Built 2, skipped 1, failed 0 (of 3 samples)") {
+		t.Errorf("unexpected summary:\n%s", logs.String())
+	}
+}
+
+func TestGenerator_Generate_RenderCache(t *testing.T) {
+	tempDir := t.TempDir()
+	scadFile := filepath.Join(tempDir, "test.scad")
+	if err := os.WriteFile(scadFile, []byte("cube(1);"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	renderCache := cache.New(filepath.Join(tempDir, "cache"))
+	mockExecutor := &MockExecutor{
+		GenerateSTLFunc: func(ctx context.Context, outputPath string, args []string) error {
+			return os.WriteFile(outputPath, []byte(testSTL(args[1])), 0644)
+		},
+	}
+	samples := createTestSamples(2)
+
+	// Two projects sharing the template and the cache.
+	newProject := func(name string) (*Generator, *bytes.Buffer) {
+		var logs bytes.Buffer
+		return &Generator{
+			config: &config.Config{
+				CSVFile:    filepath.Join(tempDir, name, "samples.csv"),
+				ScadFile:   scadFile,
+				OutputDir:  filepath.Join(tempDir, name, "stl"),
+				MaxWorkers: 2,
+				Cache:      true,
+			},
+			executor: mockExecutor,
+			parser: &MockParser{
+				ParseFileFunc: func(filename string) ([]*models.FilamentSample, error) {
+					return samples, nil
+				},
+			},
+			logger: log.New(&logs, "", 0),
+			cache:  renderCache,
+		}, &logs
+	}
+
+	first, _ := newProject("first")
+	if err := first.Generate(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if calls := mockExecutor.GetCallCount(); calls != 2 {
+		t.Fatalf("first project rendered %d samples, want 2", calls)
+	}
+
+	second, logs := newProject("second")
+	if err := second.Generate(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if calls := mockExecutor.GetCallCount(); calls != 2 {
+		t.Errorf("second project rendered %d samples, want all from cache", calls-2)
+	}
+	if !contains(logs.String(), "Built 2 (2 from cache), skipped 0, failed 0") {
+		t.Errorf("cache hits not reported:\n%s", logs.String())
+	}
+
+	for _, sample := range samples {
+		data, err := os.ReadFile(filepath.Join(tempDir, "second", "stl", sample.Filename()))
+		if err != nil || string(data) != testSTL(sample.OpenSCADArgs()[1]) {
+			t.Errorf("cached output of %s = %q, %v", sample.Filename(), data, err)
+		}
+	}
+
+	// Re-rendering an output that came from the cache must not change the
+	// cached copy.
+	second.config.Force = true
+	second.cache = nil
+	mockExecutor.GenerateSTLFunc = func(ctx context.Context, outputPath string, args []string) error {
+		return os.WriteFile(outputPath, []byte(testSTL("re-rendered")), 0644)
+	}
+	if err := second.Generate(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	stats, _ := renderCache.Stats()
+	if stats.Size != int64(len(testSTL(samples[0].OpenSCADArgs()[1]))+len(testSTL(samples[1].OpenSCADArgs()[1]))) {
+		t.Errorf("cache content changed by a later render, size %d", stats.Size)
+	}
+}
+
+func TestGenerator_Generate_RenderCacheChecks(t *testing.T) {
+	tempDir := t.TempDir()
+	scadFile := filepath.Join(tempDir, "test.scad")
+	if err := os.WriteFile(scadFile, []byte("cube(1);"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	renderCache := cache.New(filepath.Join(tempDir, "cache"))
+	mockExecutor := &MockExecutor{
+		GenerateSTLFunc: func(ctx context.Context, outputPath string, args []string) error {
+			return os.WriteFile(outputPath, []byte(testSTL("card")), 0644)
+		},
+		DiagnosticsFunc: func(outputPath string, args []string) []openscad.Diagnostic {
+			return []openscad.Diagnostic{{Severity: openscad.SeverityWarning, Message: "scale() with 0 factor"}}
+		},
+	}
+	samples := createTestSamples(1)
+
+	generate := func(name, policy string) (int, string) {
+		t.Helper()
+		var logs bytes.Buffer
+		gen := &Generator{
+			config: &config.Config{
+				CSVFile:       filepath.Join(tempDir, name, "samples.csv"),
+				ScadFile:      scadFile,
+				OutputDir:     filepath.Join(tempDir, name, "stl"),
+				MaxWorkers:    1,
+				Cache:         true,
+				WarningPolicy: policy,
+			},
+			executor: mockExecutor,
+			parser: &MockParser{
+				ParseFileFunc: func(filename string) ([]*models.FilamentSample, error) {
+					return samples, nil
+				},
+			},
+			logger: log.New(&logs, "", 0),
+			cache:  renderCache,
+		}
+		before := mockExecutor.GetCallCount()
+		gen.Generate(context.Background())
+		return mockExecutor.GetCallCount() - before, logs.String()
+	}
+
+	if calls, _ := generate("first", config.WarningPolicyIgnore); calls != 1 {
+		t.Fatalf("first project rendered %d samples, want 1", calls)
+	}
+
+	// The warnings of a cached render are checked against the policy.
+	calls, logs := generate("strict", config.WarningPolicyFail)
+	if calls != 0 || !contains(logs, "failed 1") || !contains(logs, "scale() with 0 factor") {
+		t.Errorf("cached warnings not replayed, %d renders:\n%s", calls, logs)
+	}
+
+	// A damaged cache entry is rendered again.
+	filepath.WalkDir(renderCache.Dir, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && filepath.Ext(path) == ".stl" {
+			os.WriteFile(path, []byte("solid truncated"), 0644)
+		}
+		return nil
+	})
+	calls, logs = generate("second", config.WarningPolicyIgnore)
+	if calls != 1 || !contains(logs, "Built 1, skipped 0, failed 0") {
+		t.Errorf("damaged cache entry reused, %d renders:\n%s", calls, logs)
+	}
+	data, err := os.ReadFile(filepath.Join(tempDir, "second", "stl", samples[0].Filename()))
+	if err != nil || string(data) != testSTL("card") {
+		t.Errorf("output = %q, %v", data, err)
+	}
+}
+
+func TestGenerator_generateSample_AtomicWrite(t *testing.T) {
+	tests := []struct {
+		name   string
+		render func(outputPath string) error
+		ok     bool
+	}{
+		{
+			name: "complete output",
+			render: func(outputPath string) error {
+				return os.WriteFile(outputPath, []byte(testSTL("card")), 0644)
+			},
+			ok: true,
+		},
+		{
+			name: "render fails after partial write",
+			render: func(outputPath string) error {
+				os.WriteFile(outputPath, []byte("solid card\n  facet normal"), 0644)
+				return errors.New("OpenSCAD crashed")
+			},
+		},
+		{
+			name: "truncated output",
+			render: func(outputPath string) error {
+				return os.WriteFile(outputPath, []byte("solid card\n  facet normal 0 0 1\n    outer loop\n"), 0644)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outputDir := t.TempDir()
+			sample := createTestSamples(1)[0]
+			finalPath := filepath.Join(outputDir, sample.Filename())
+			if err := os.WriteFile(finalPath, []byte("previous"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			gen := &Generator{
+				config: &config.Config{OutputDir: outputDir, MaxWorkers: 1},
+				executor: &MockExecutor{
+					GenerateSTLFunc: func(ctx context.Context, outputPath string, args []string) error {
+						if filepath.Dir(outputPath) != outputDir || outputPath == finalPath {
+							t.Errorf("OpenSCAD should render to a temporary file in the output directory, got %s", outputPath)
+						}
+						return tt.render(outputPath)
+					},
+				},
+				logger: log.New(io.Discard, "", 0),
+			}
+
+			_, _, err := gen.generateSample(context.Background(), sample)
+			if (err == nil) != tt.ok {
+				t.Fatalf("generateSample() error = %v, want success %v", err, tt.ok)
+			}
+
+			if tt.ok {
+				info, err := os.Stat(finalPath)
+				if err != nil {
+					t.Fatal(err)
+				}
+				// Not the 0600 of the temporary file.
+				if runtime.GOOS != "windows" && info.Mode().Perm() != fsutil.FileMode {
+					t.Errorf("output mode = %v, want %v", info.Mode().Perm(), fsutil.FileMode)
+				}
+			} else if data, _ := os.ReadFile(finalPath); string(data) != "previous" {
+				t.Errorf("existing output was replaced by a failed render: %q", data)
+			}
+			entries, _ := os.ReadDir(outputDir)
+			if len(entries) != 1 {
+				t.Errorf("temporary files left behind: %v", entries)
+			}
+		})
+	}
+}
+
+func TestGenerator_Generate_OnConflict(t *testing.T) {
+	tests := []struct {
+		policy    string
+		wantCalls int
+		wantFiles map[string]string
+	}{
+		{
+			policy:    config.OnConflictOverwrite,
+			wantCalls: 1,
+			wantFiles: map[string]string{"": testSTL("new")},
+		},
+		{
+			policy:    config.OnConflictSkip,
+			wantCalls: 0,
+			wantFiles: map[string]string{"": "existing"},
+		},
+		{
+			policy:    config.OnConflictKeepBoth,
+			wantCalls: 1,
+			wantFiles: map[string]string{"": testSTL("new"), "-2": "existing"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			tempDir := t.TempDir()
+			outputDir := filepath.Join(tempDir, "stl")
+			os.MkdirAll(outputDir, 0755)
+			scadFile := filepath.Join(tempDir, "test.scad")
+			os.WriteFile(scadFile, []byte("cube(1);"), 0644)
+
+			sample := createTestSamples(1)[0]
+			base := strings.TrimSuffix(sample.Filename(), ".stl")
+			if err := os.WriteFile(filepath.Join(outputDir, sample.Filename()), []byte("existing"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			mockExecutor := &MockExecutor{
+				GenerateSTLFunc: func(ctx context.Context, outputPath string, args []string) error {
+					return os.WriteFile(outputPath, []byte(testSTL("new")), 0644)
+				},
+			}
+			gen := &Generator{
+				config: &config.Config{
+					CSVFile:    filepath.Join(tempDir, "samples.csv"),
+					ScadFile:   scadFile,
+					OutputDir:  outputDir,
+					MaxWorkers: 1,
+					OnConflict: tt.policy,
+				},
+				executor: mockExecutor,
+				parser: &MockParser{
+					ParseFileFunc: func(filename string) ([]*models.FilamentSample, error) {
+						return []*models.FilamentSample{sample}, nil
+					},
+				},
+				logger: log.New(io.Discard, "", 0),
+			}
+
+			if err := gen.Generate(context.Background()); err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+			// A second run finds everything up to date or kept.
+			if err := gen.Generate(context.Background()); err != nil {
+				t.Fatalf("second Generate() error = %v", err)
+			}
+			if calls := mockExecutor.GetCallCount(); calls != tt.wantCalls {
+				t.Errorf("rendered %d times, want %d", calls, tt.wantCalls)
+			}
+
+			entries, _ := os.ReadDir(outputDir)
+			stls := 0
+			for _, e := range entries {
+				if strings.HasSuffix(e.Name(), ".stl") {
+					stls++
+				}
+			}
+			if stls != len(tt.wantFiles) {
+				t.Errorf("output directory holds %d STL files, want %d: %v", stls, len(tt.wantFiles), entries)
+			}
+			for suffix, want := range tt.wantFiles {
+				data, err := os.ReadFile(filepath.Join(outputDir, base+suffix+".stl"))
+				if err != nil || string(data) != want {
+					t.Errorf("%s%s.stl = %q, %v; want %q", base, suffix, data, err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerator_Generate_MaterialChecks(t *testing.T) {
+	tests := []struct {
+		name      string
+		hotend    string
+		overrides map[string]materials.Rule
+		wantErr   bool
+		wantLog   string
+	}{
+		{name: "plausible", hotend: "210"},
+		{name: "unusual", hotend: "250", wantLog: "line 3: warning: "},
+		{name: "implausible", hotend: "400", wantErr: true},
+		{
+			name:      "overridden",
+			hotend:    "400",
+			overrides: map[string]materials.Rule{"PLA": {Hotend: &materials.Limits{Min: 180, Max: 450}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sample := createTestSamples(1)[0]
+			sample.TempHotend = tt.hotend
+			sample.Line = 3
+
+			mockExecutor := &MockExecutor{}
+			var logs bytes.Buffer
+			gen := &Generator{
+				config: &config.Config{
+					CSVFile:    "samples.csv",
+					OutputDir:  t.TempDir(),
+					MaxWorkers: 1,
+					Materials:  tt.overrides,
+				},
+				executor: mockExecutor,
+				parser: &MockParser{
+					ParseFileFunc: func(filename string) ([]*models.FilamentSample, error) {
+						return []*models.FilamentSample{sample}, nil
+					},
+				},
+				logger: log.New(&logs, "", 0),
+			}
+
+			err := gen.Generate(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Generate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if !errors.Is(err, ErrParse) {
+					t.Errorf("error should wrap ErrParse, got %v", err)
+				}
+				if calls := mockExecutor.GetCallCount(); calls != 0 {
+					t.Errorf("nothing should be rendered, got %d calls", calls)
+				}
+			}
+			if !contains(logs.String(), tt.wantLog) {
+				t.Errorf("log does not contain %q:\n%s", tt.wantLog, logs.String())
+			}
+		})
+	}
+}
+
+func TestGenerator_Generate_Params(t *testing.T) {
+	tempDir := t.TempDir()
+	scadFile := filepath.Join(tempDir, "template.scad")
+	if err := os.WriteFile(scadFile, []byte("CARD_THICKNESS=2.2;\nFONT=\"Liberation Sans\";\ncube(CARD_THICKNESS);\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sets := `{"parameterSets": {"Thick": {"CARD_THICKNESS": "3", "FONT": "12"}, "Bad": {"CARD_THICKNESS": "thick"}}, "fileFormatVersion": "1"}`
+	if err := os.WriteFile(filepath.Join(tempDir, "template.json"), []byte(sets), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		params     map[string]string
+		scadParams map[string]any
+		set        string
+		runSet     string
+		wantErr    error
+		wantMsg    string
+		wantArgs   []string
+	}{
+		{name: "valid", params: map[string]string{"CARD_THICKNESS": "3", "$fn": "20"}},
+		{name: "unknown", params: map[string]string{"CARD_THICKNES": "3"}, wantErr: ErrParse,
+			wantMsg: "line 3: the template has no parameter CARD_THICKNES"},
+		{name: "mismatch", params: map[string]string{"FONT": "[1, 2]"}, wantErr: ErrParse,
+			wantMsg: "FONT must be a string"},
+		{name: "run-wide", scadParams: map[string]any{"CARD_THICKNESS": "thick"}, wantErr: ErrParams,
+			wantMsg: "scad_params: CARD_THICKNESS must be a number"},
+		{name: "parameter set", set: "Thick", params: map[string]string{"CARD_THICKNESS": "4"},
+			wantArgs: []string{"-D", "CARD_THICKNESS=4", "-D", `FONT="12"`}},
+		{name: "run-wide parameter set", runSet: "Thick", scadParams: map[string]any{"FONT": "Noto Sans"},
+			wantArgs: []string{"-D", "CARD_THICKNESS=3", "-D", `FONT="Noto Sans"`}},
+		{name: "unknown parameter set", set: "Nope", wantErr: ErrParse,
+			wantMsg: `line 3: unknown parameter set "Nope"`},
+		{name: "invalid parameter set", set: "Bad", wantErr: ErrParse,
+			wantMsg: `parameter set "Bad": CARD_THICKNESS must be a number`},
+		{name: "unknown run-wide parameter set", runSet: "Nope", wantErr: ErrParams,
+			wantMsg: `has no parameter set "Nope" (it has Bad, Thick)`},
+		{name: "invalid run-wide parameter set", runSet: "Bad", wantErr: ErrParams,
+			wantMsg: `parameter set "Bad": CARD_THICKNESS must be a number`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sample := createTestSamples(1)[0]
+			sample.Line = 3
+			sample.Params = tt.params
+			sample.ParameterSet = tt.set
+
+			var gotArgs []string
+			mockExecutor := &MockExecutor{
+				GenerateSTLFunc: func(ctx context.Context, outputPath string, args []string) error {
+					gotArgs = args
+					return nil
+				},
+			}
+			gen := &Generator{
+				config: &config.Config{
+					CSVFile:      "samples.csv",
+					OutputDir:    filepath.Join(t.TempDir(), "output"),
+					ScadFile:     scadFile,
+					MaxWorkers:   1,
+					ScadParams:   tt.scadParams,
+					ParameterSet: tt.runSet,
+				},
+				executor: mockExecutor,
+				parser: &MockParser{
+					ParseFileFunc: func(filename string) ([]*models.FilamentSample, error) {
+						return []*models.FilamentSample{sample}, nil
+					},
+				},
+				logger: log.New(io.Discard, "", 0),
+			}
+
+			err := gen.Generate(context.Background())
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("Generate() error = %v", err)
+				}
+				if tail := gotArgs[len(gotArgs)-len(tt.wantArgs):]; tt.wantArgs != nil && !reflect.DeepEqual(tail, tt.wantArgs) {
+					t.Errorf("OpenSCAD args end with %v, want %v", tail, tt.wantArgs)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) || !contains(err.Error(), tt.wantMsg) {
+				t.Errorf("Generate() error = %v, want %v with %q", err, tt.wantErr, tt.wantMsg)
+			}
+			if calls := mockExecutor.GetCallCount(); calls != 0 {
+				t.Errorf("nothing should be rendered, got %d calls", calls)
+			}
+		})
+	}
+}
+
+func TestGenerator_Generate_Streams(t *testing.T) {
+	samples := createTestSamples(10)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var once sync.Once
+
+	executor := &MockExecutor{
+		GenerateSTLFunc: func(ctx context.Context, outputPath string, args []string) error {
+			once.Do(func() { close(started) })
+			<-release
+			return nil
+		},
+	}
+
+	var emitted atomic.Int32
+	parser := &MockStreamParser{
+		StreamFileFunc: func(filename string, fn func(*models.FilamentSample) error) error {
+			for _, sample := range samples {
+				if err := fn(sample); err != nil {
+					return err
+				}
+				emitted.Add(1)
+			}
+			return nil
+		},
+	}
+
+	gen := &Generator{
+		config:   &config.Config{OutputDir: t.TempDir(), MaxWorkers: 1},
+		executor: executor,
+		parser:   parser,
+		logger:   log.New(io.Discard, "", 0),
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- gen.Generate(context.Background()) }()
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("rendering did not start while the list was being read")
+	}
+	time.Sleep(50 * time.Millisecond)
+	// One sample is rendering and one waits in the queue; reading blocks
+	// on the next instead of buffering the whole list.
+	if got := emitted.Load(); got > 2 {
+		t.Errorf("%d samples handed out while the only worker is busy, want at most 2", got)
+	}
+	close(release)
+
+	if err := <-done; err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if got := executor.GetCallCount(); got != len(samples) {
+		t.Errorf("rendered %d samples, want %d", got, len(samples))
+	}
+}
+
+func TestGenerator_Generate_StreamRejected(t *testing.T) {
+	tests := []struct {
+		name      string
+		hotends   []string
+		listErr   error
+		wantCalls int
+		wantErr   string
+	}{
+		{
+			name:      "invalid row after valid ones",
+			hotends:   []string{"200", "210"},
+			listErr:   errors.New("line 3: invalid temperature"),
+			wantCalls: 2,
+			wantErr:   "line 3: invalid temperature",
+		},
+		{
+			name:      "implausible temperature",
+			hotends:   []string{"200", "400", "210"},
+			wantCalls: 1,
+			wantErr:   "1 implausible temperature:",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			samples := createTestSamples(len(tt.hotends))
+			for i, hotend := range tt.hotends {
+				samples[i].TempHotend = hotend
+			}
+			executor := &MockExecutor{}
+			gen := &Generator{
+				config:   &config.Config{OutputDir: t.TempDir(), MaxWorkers: 1},
+				executor: executor,
+				parser: &MockStreamParser{
+					StreamFileFunc: func(filename string, fn func(*models.FilamentSample) error) error {
+						for _, sample := range samples {
+							if err := fn(sample); err != nil {
+								return err
+							}
+						}
+						return tt.listErr
+					},
+				},
+				logger: log.New(io.Discard, "", 0),
+			}
+
+			err := gen.Generate(context.Background())
+			if !errors.Is(err, ErrParse) || !contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Generate() error = %v, want ErrParse with %q", err, tt.wantErr)
+			}
+			if got := executor.GetCallCount(); got != tt.wantCalls {
+				t.Errorf("rendered %d samples, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
@@ -1,10 +1,15 @@
 package generator
 
-import "github.com/guntharp/go-filamentsamples/pkg/models"
+import (
+	"context"
+
+	"github.com/guntharp/go-filamentsamples/internal/openscad"
+	"github.com/guntharp/go-filamentsamples/pkg/models"
+)
 
 // Executor defines the interface for OpenSCAD operations
 type Executor interface {
-	GenerateSTL(outputPath string, args []string) error
+	GenerateSTL(ctx context.Context, outputPath string, args []string) ([]openscad.Diagnostic, error)
 	CheckAvailable() error
 	GetVersion() (string, error)
 }
@@ -12,4 +17,11 @@ type Executor interface {
 // Parser defines the interface for CSV parsing operations
 type Parser interface {
 	ParseFile(filename string) ([]*models.FilamentSample, error)
-}
\ No newline at end of file
+}
+
+// StreamParser is a Parser that can pass samples on as they are read, so
+// that rendering starts before the whole list has been parsed.
+type StreamParser interface {
+	Parser
+	StreamFile(filename string, fn func(*models.FilamentSample) error) error
+}
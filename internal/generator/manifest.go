@@ -0,0 +1,136 @@
+package generator
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/guntharp/go-filamentsamples/internal/fsutil"
+	"github.com/guntharp/go-filamentsamples/internal/openscad"
+)
+
+// ManifestFile is the name of the build manifest kept in the output directory.
+const ManifestFile = ".filament-samples-manifest.json"
+
+const manifestVersion = 2
+
+// Manifest records the input hash each STL file in the output directory was
+// built from, so unchanged samples can be skipped on the next run.
+type Manifest struct {
+	Version int `json:"version"`
+	// Entries maps an STL filename to the build it came from.
+	Entries map[string]ManifestEntry `json:"entries"`
+}
+
+// ManifestEntry describes the build of a single STL file.
+type ManifestEntry struct {
+	// Hash is the SampleHash the file was built from.
+	Hash string `json:"hash"`
+	// Warnings are the warnings OpenSCAD printed while building it, so a
+	// later run with a stricter warning policy can reject the file.
+	Warnings []openscad.Diagnostic `json:"warnings,omitempty"`
+}
+
+// LoadManifest reads the manifest in dir. A missing manifest, or one written
+// by a different manifest version, yields an empty manifest.
+func LoadManifest(dir string) (*Manifest, error) {
+	m := &Manifest{Version: manifestVersion, Entries: map[string]ManifestEntry{}}
+
+	data, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return m, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	// Check the version first: older manifests store their entries in a
+	// different shape.
+	var header struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return m, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	if header.Version != manifestVersion {
+		return m, nil
+	}
+
+	var loaded Manifest
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		return m, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	if loaded.Entries == nil {
+		return m, nil
+	}
+
+	return &loaded, nil
+}
+
+// Save writes the manifest to dir, replacing the previous one atomically.
+func (m *Manifest) Save(dir string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, ManifestFile+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	if err := tmp.Chmod(fsutil.FileMode); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), filepath.Join(dir, ManifestFile)); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
+
+// UpToDate reports whether filename in dir was built from hash and still
+// exists with content.
+func (m *Manifest) UpToDate(dir, filename, hash string) bool {
+	if m.Entries[filename].Hash != hash {
+		return false
+	}
+	info, err := os.Stat(filepath.Join(dir, filename))
+	return err == nil && info.Mode().IsRegular() && info.Size() > 0
+}
+
+// SampleHash identifies everything an STL file depends on: the OpenSCAD
+// arguments of the sample, the template content and the OpenSCAD version.
+func SampleHash(args []string, template []byte, openscadVersion string) string {
+	h := sha256.New()
+	write := func(b []byte) {
+		// Length-prefix each field so that no two inputs hash the same
+		// just because their concatenations do.
+		binary.Write(h, binary.LittleEndian, uint64(len(b)))
+		h.Write(b)
+	}
+
+	binary.Write(h, binary.LittleEndian, uint64(len(args)))
+	for _, arg := range args {
+		write([]byte(arg))
+	}
+	write(template)
+	write([]byte(openscadVersion))
+
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,129 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/guntharp/go-filamentsamples/internal/fsutil"
+	"github.com/guntharp/go-filamentsamples/internal/openscad"
+)
+
+func TestManifest_SaveLoad(t *testing.T) {
+	dir := t.TempDir()
+
+	m, err := LoadManifest(dir)
+	if err != nil {
+		t.Fatalf("LoadManifest() on empty dir error = %v", err)
+	}
+	if len(m.Entries) != 0 {
+		t.Fatalf("expected empty manifest, got %v", m.Entries)
+	}
+
+	warning := openscad.Diagnostic{Severity: openscad.SeverityWarning, Message: "scaled to zero", Line: 12}
+	m.Entries["a.stl"] = ManifestEntry{Hash: "hash-a", Warnings: []openscad.Diagnostic{warning}}
+	if err := m.Save(dir); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	loaded, err := LoadManifest(dir)
+	if err != nil {
+		t.Fatalf("LoadManifest() error = %v", err)
+	}
+	if got := loaded.Entries["a.stl"]; got.Hash != "hash-a" || len(got.Warnings) != 1 || got.Warnings[0] != warning {
+		t.Errorf("loaded entries = %v", loaded.Entries)
+	}
+
+	info, err := os.Stat(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != fsutil.FileMode {
+		t.Errorf("manifest mode = %v, want %v", info.Mode().Perm(), fsutil.FileMode)
+	}
+
+	matches, _ := filepath.Glob(filepath.Join(dir, ManifestFile+".*"))
+	if len(matches) != 0 {
+		t.Errorf("temporary files left behind: %v", matches)
+	}
+}
+
+func TestLoadManifest_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{name: "corrupt", content: "{not json", wantErr: true},
+		{name: "other version", content: `{"version": 99, "entries": {"a.stl": {"hash": "x"}}}`},
+		{name: "version 1", content: `{"version": 1, "entries": {"a.stl": "x"}}`},
+		{name: "no entries", content: `{"version": 2}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, ManifestFile), []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			m, err := LoadManifest(dir)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("LoadManifest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if m == nil || m.Entries == nil || len(m.Entries) != 0 {
+				t.Errorf("expected a usable empty manifest, got %+v", m)
+			}
+		})
+	}
+}
+
+func TestManifest_UpToDate(t *testing.T) {
+	dir := t.TempDir()
+	m := &Manifest{Version: manifestVersion, Entries: map[string]ManifestEntry{
+		"built.stl":   {Hash: "h1"},
+		"empty.stl":   {Hash: "h2"},
+		"missing.stl": {Hash: "h3"},
+	}}
+	os.WriteFile(filepath.Join(dir, "built.stl"), []byte("solid"), 0644)
+	os.WriteFile(filepath.Join(dir, "empty.stl"), nil, 0644)
+
+	tests := []struct {
+		filename string
+		hash     string
+		want     bool
+	}{
+		{"built.stl", "h1", true},
+		{"built.stl", "changed", false},
+		{"empty.stl", "h2", false},
+		{"missing.stl", "h3", false},
+		{"unknown.stl", "", false},
+	}
+
+	for _, tt := range tests {
+		if got := m.UpToDate(dir, tt.filename, tt.hash); got != tt.want {
+			t.Errorf("UpToDate(%s, %s) = %v, want %v", tt.filename, tt.hash, got, tt.want)
+		}
+	}
+}
+
+func TestSampleHash(t *testing.T) {
+	base := SampleHash([]string{"-D", "BRAND=\"A\""}, []byte("cube(1);"), "2021.01")
+
+	if SampleHash([]string{"-D", "BRAND=\"A\""}, []byte("cube(1);"), "2021.01") != base {
+		t.Error("SampleHash() is not deterministic")
+	}
+
+	variants := map[string]string{
+		"args":     SampleHash([]string{"-D", "BRAND=\"B\""}, []byte("cube(1);"), "2021.01"),
+		"template": SampleHash([]string{"-D", "BRAND=\"A\""}, []byte("cube(2);"), "2021.01"),
+		"version":  SampleHash([]string{"-D", "BRAND=\"A\""}, []byte("cube(1);"), "2021.02"),
+		"boundary": SampleHash([]string{"-D", "BRAND=\"A\"cube(1);"}, nil, "2021.01"),
+	}
+	for name, hash := range variants {
+		if hash == base {
+			t.Errorf("changing %s did not change the hash", name)
+		}
+	}
+}
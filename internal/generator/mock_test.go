@@ -1,30 +1,46 @@
 package generator
 
 import (
+	"context"
 	"fmt"
+	"os"
 	"sync"
 
+	"github.com/guntharp/go-filamentsamples/internal/openscad"
 	"github.com/guntharp/go-filamentsamples/pkg/models"
 )
 
 // MockExecutor is a mock implementation of the OpenSCAD executor
 type MockExecutor struct {
-	GenerateSTLFunc   func(outputPath string, args []string) error
+	GenerateSTLFunc   func(ctx context.Context, outputPath string, args []string) error
+	// DiagnosticsFunc optionally supplies the OpenSCAD diagnostics for a call
+	DiagnosticsFunc func(outputPath string, args []string) []openscad.Diagnostic
 	CheckAvailableFunc func() error
 	GetVersionFunc     func() (string, error)
 	callCount         int
 	mu                sync.Mutex
 }
 
-func (m *MockExecutor) GenerateSTL(outputPath string, args []string) error {
+func (m *MockExecutor) GenerateSTL(ctx context.Context, outputPath string, args []string) ([]openscad.Diagnostic, error) {
 	m.mu.Lock()
 	m.callCount++
 	m.mu.Unlock()
-	
+
+	var diagnostics []openscad.Diagnostic
+	if m.DiagnosticsFunc != nil {
+		diagnostics = m.DiagnosticsFunc(outputPath, args)
+	}
 	if m.GenerateSTLFunc != nil {
-		return m.GenerateSTLFunc(outputPath, args)
+		if err := m.GenerateSTLFunc(ctx, outputPath, args); err != nil {
+			return diagnostics, err
+		}
 	}
-	return nil
+
+	// Like OpenSCAD, write a model unless the function wrote one itself.
+	if info, err := os.Stat(outputPath); err != nil || info.Size() == 0 {
+		return diagnostics, os.WriteFile(outputPath, []byte(testSTL("mock")), 0644)
+	}
+	return diagnostics, nil
 }
 
 func (m *MockExecutor) CheckAvailable() error {
@@ -59,6 +75,16 @@ func (m *MockParser) ParseFile(filename string) ([]*models.FilamentSample, error
 	return []*models.FilamentSample{}, nil
 }
 
+// MockStreamParser is a mock parser that passes samples on as they are read
+type MockStreamParser struct {
+	MockParser
+	StreamFileFunc func(filename string, fn func(*models.FilamentSample) error) error
+}
+
+func (m *MockStreamParser) StreamFile(filename string, fn func(*models.FilamentSample) error) error {
+	return m.StreamFileFunc(filename, fn)
+}
+
 // Helper function to create test samples
 func createTestSamples(count int) []*models.FilamentSample {
 	samples := make([]*models.FilamentSample, count)
@@ -72,4 +98,25 @@ func createTestSamples(count int) []*models.FilamentSample {
 		}
 	}
 	return samples
-}
\ No newline at end of file
+}
+
+// testSTL returns a minimal valid ASCII STL file named name.
+func testSTL(name string) string {
+	return "solid " + name + "\n" +
+		"  facet normal 0 0 1\n    outer loop\n" +
+		"      vertex 0 0 0\n      vertex 1 0 0\n      vertex 0 1 0\n" +
+		"    endloop\n  endfacet\n" +
+		"endsolid " + name + "\n"
+}
+
+// feed returns a feed for Generator.process that emits samples in order.
+func feed(samples []*models.FilamentSample) func(emit func(*models.FilamentSample) error) error {
+	return func(emit func(*models.FilamentSample) error) error {
+		for _, sample := range samples {
+			if err := emit(sample); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,251 @@
+package input
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/guntharp/go-filamentsamples/internal/csv"
+	"github.com/guntharp/go-filamentsamples/pkg/models"
+	"github.com/guntharp/go-filamentsamples/pkg/scad"
+)
+
+// fieldKeys are the key names reported for fields a sample did not set.
+var fieldKeys = map[string]string{
+	models.FieldBrand:      "brand",
+	models.FieldType:       "type",
+	models.FieldColor:      "color",
+	models.FieldTempHotend: "hotend",
+	models.FieldTempBed:    "bed",
+	models.FieldBrandSize:  "brand_size",
+	models.FieldTypeSize:   "type_size",
+	models.FieldColorSize:  "color_size",
+	models.FieldQuantity:   "quantity",
+}
+
+// convert turns a decoded item into a validated sample. Keys name sample
+// fields with the same aliases as CSV headers, plus "params", "notes",
+// "quantity" and "parameter_set".
+func (p *Parser) convert(it item, index int) (*models.FilamentSample, []*csv.RowError) {
+	rowErr := func(key, value, reason string) *csv.RowError {
+		return &csv.RowError{Line: it.Line, Item: index, Column: key, Value: value, Reason: reason}
+	}
+
+	values, ok := it.Values.(map[string]any)
+	if !ok {
+		return nil, []*csv.RowError{rowErr("", "", fmt.Sprintf("expected a table of sample fields, got %s", describe(it.Values)))}
+	}
+
+	sample := &models.FilamentSample{Line: it.Line}
+	written := make(map[string]string)
+	var errs []*csv.RowError
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := values[key]
+
+		if field, ok := csv.FieldForName(key); ok {
+			s, err := scalar(value)
+			if err != nil {
+				errs = append(errs, rowErr(key, "", err.Error()))
+				continue
+			}
+			setField(sample, field, s)
+			written[field] = key
+			continue
+		}
+
+		switch strings.ToLower(key) {
+		case "params", "parameters":
+			params, err := paramsOf(value)
+			if err != nil {
+				errs = append(errs, rowErr(key, "", err.Error()))
+				continue
+			}
+			sample.Params = params
+		case "notes", "note":
+			s, ok := value.(string)
+			if !ok {
+				errs = append(errs, rowErr(key, "", fmt.Sprintf("must be text, got %s", describe(value))))
+				continue
+			}
+			sample.Notes = s
+		case "parameter_set", "parameterset", "preset":
+			set, err := scalar(value)
+			if err != nil {
+				errs = append(errs, rowErr(key, "", err.Error()))
+				continue
+			}
+			sample.ParameterSet = set
+		case "quantity", "qty":
+			n, err := integer(value)
+			if err != nil {
+				errs = append(errs, rowErr(key, "", err.Error()))
+				continue
+			}
+			sample.Quantity = n
+			written[models.FieldQuantity] = key
+		default:
+			errs = append(errs, rowErr(key, "", "unknown key"))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	p.Profiles.Fill(sample)
+
+	if err := sample.Validate(); err != nil {
+		for _, err := range models.Unjoin(err) {
+			var fieldErr *models.FieldError
+			if !errors.As(err, &fieldErr) {
+				errs = append(errs, rowErr("", "", err.Error()))
+				continue
+			}
+			key, ok := written[fieldErr.Field]
+			if !ok {
+				key = fieldKeys[fieldErr.Field]
+			}
+			errs = append(errs, rowErr(key, fieldErr.Value, fieldErr.Err.Error()))
+		}
+		return nil, errs
+	}
+	sample.NormalizeTemperatures()
+
+	return sample, nil
+}
+
+func setField(sample *models.FilamentSample, field, value string) {
+	switch field {
+	case models.FieldBrand:
+		sample.Brand = value
+	case models.FieldType:
+		sample.Type = value
+	case models.FieldColor:
+		sample.Color = value
+	case models.FieldTempHotend:
+		sample.TempHotend = value
+	case models.FieldTempBed:
+		sample.TempBed = value
+	case models.FieldBrandSize:
+		sample.BrandSize = value
+	case models.FieldTypeSize:
+		sample.TypeSize = value
+	case models.FieldColorSize:
+		sample.ColorSize = value
+	}
+}
+
+// scalar returns a single value as the text a CSV cell would hold.
+func scalar(v any) (string, error) {
+	switch x := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return strings.TrimSpace(x), nil
+	case json.Number:
+		return x.String(), nil
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64), nil
+	case int:
+		return strconv.Itoa(x), nil
+	case int64:
+		return strconv.FormatInt(x, 10), nil
+	case bool:
+		return strconv.FormatBool(x), nil
+	}
+	return "", fmt.Errorf("must be a single value, got %s", describe(v))
+}
+
+// paramsOf converts a params table. Values keep the type they were decoded
+// with: each becomes the OpenSCAD literal of the value, so "1984" stays a
+// string and lists become vectors such as [1, 2, 3].
+func paramsOf(v any) (map[string]string, error) {
+	table, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("must be a table of parameter names and values, got %s", describe(v))
+	}
+
+	params := make(map[string]string, len(table))
+	for name, value := range table {
+		if !scad.ValidName(name) {
+			return nil, fmt.Errorf("invalid parameter name %q", name)
+		}
+		literal, err := paramLiteral(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s %w", name, err)
+		}
+		params[name] = literal
+	}
+	return params, nil
+}
+
+func paramLiteral(v any) (string, error) {
+	switch x := v.(type) {
+	case json.Number:
+		if f, err := scad.ParseNumber(x.String()); err == nil {
+			return scad.Number(f)
+		}
+	case []any:
+		elems := make([]string, len(x))
+		for i, e := range x {
+			literal, err := paramLiteral(e)
+			if err != nil {
+				return "", err
+			}
+			elems[i] = literal
+		}
+		return "[" + strings.Join(elems, ", ") + "]", nil
+	case map[string]any:
+	default:
+		if literal, err := scad.Literal(v); err == nil {
+			return literal, nil
+		}
+	}
+	return "", fmt.Errorf("must be a single value or a list, got %s", describe(v))
+}
+
+func integer(v any) (int, error) {
+	switch x := v.(type) {
+	case json.Number:
+		if n, err := strconv.Atoi(x.String()); err == nil {
+			return n, nil
+		}
+	case float64:
+		if x == math.Trunc(x) && math.Abs(x) < math.MaxInt32 {
+			return int(x), nil
+		}
+	case int:
+		return x, nil
+	case int64:
+		return int(x), nil
+	case string:
+		if n, err := strconv.Atoi(strings.TrimSpace(x)); err == nil {
+			return n, nil
+		}
+	}
+	return 0, fmt.Errorf("must be a whole number, got %s", describe(v))
+}
+
+func describe(v any) string {
+	switch x := v.(type) {
+	case nil:
+		return "nothing"
+	case map[string]any:
+		return "a table"
+	case []any:
+		return "a list"
+	case string:
+		return strconv.Quote(x)
+	}
+	return fmt.Sprint(v)
+}
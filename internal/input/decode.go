@@ -0,0 +1,217 @@
+package input
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// samplesKey names the list of samples in a JSON object, YAML mapping or
+// TOML document.
+const samplesKey = "samples"
+
+// item is one decoded sample before conversion, with the line it starts on
+// or 0 when that is not known.
+type item struct {
+	Line   int
+	Values any
+}
+
+func decode(data []byte, format string) ([]item, error) {
+	var items []item
+	var err error
+	switch format {
+	case FormatJSON:
+		items, err = decodeJSON(data)
+	case FormatYAML:
+		items, err = decodeYAML(data)
+	case FormatTOML:
+		items, err = decodeTOML(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", format, err)
+	}
+	return items, nil
+}
+
+// decodeJSON accepts a list of samples or an object holding one under
+// "samples". It walks the tokens so that the line of every sample is known.
+func decodeJSON(data []byte) ([]item, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	fail := func(err error) ([]item, error) {
+		var syntaxErr *json.SyntaxError
+		var typeErr *json.UnmarshalTypeError
+		switch {
+		case errors.As(err, &syntaxErr):
+			return nil, fmt.Errorf("line %d: %w", lineAt(data, int(syntaxErr.Offset)), err)
+		case errors.As(err, &typeErr):
+			return nil, fmt.Errorf("line %d: %w", lineAt(data, int(typeErr.Offset)), err)
+		}
+		return nil, err
+	}
+
+	tok, err := dec.Token()
+	if err != nil {
+		return fail(err)
+	}
+	inObject := false
+	switch tok {
+	case json.Delim('['):
+	case json.Delim('{'):
+		inObject = true
+		if err := findJSONKey(dec, data); err != nil {
+			return fail(err)
+		}
+	default:
+		return nil, fmt.Errorf("expected a list of samples or an object with a %q list", samplesKey)
+	}
+
+	var items []item
+	for dec.More() {
+		start := skipSeparators(data, int(dec.InputOffset()))
+		var values any
+		if err := dec.Decode(&values); err != nil {
+			return fail(err)
+		}
+		items = append(items, item{Line: lineAt(data, start), Values: values})
+	}
+	if _, err := dec.Token(); err != nil {
+		return fail(err)
+	}
+
+	if inObject && dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return fail(err)
+		}
+		return nil, fmt.Errorf("line %d: unknown key %q, only %q is allowed at the top level",
+			lineAt(data, int(dec.InputOffset())), key, samplesKey)
+	}
+	return items, nil
+}
+
+// findJSONKey advances dec to the start of the samples list.
+func findJSONKey(dec *json.Decoder, data []byte) error {
+	if !dec.More() {
+		return fmt.Errorf("no %q list", samplesKey)
+	}
+	key, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if key != samplesKey {
+		return fmt.Errorf("line %d: unknown key %q, only %q is allowed at the top level",
+			lineAt(data, int(dec.InputOffset())), key, samplesKey)
+	}
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok != json.Delim('[') {
+		return fmt.Errorf("line %d: %q must be a list", lineAt(data, int(dec.InputOffset())), samplesKey)
+	}
+	return nil
+}
+
+func lineAt(data []byte, offset int) int {
+	offset = min(offset, len(data))
+	return 1 + bytes.Count(data[:offset], []byte("\n"))
+}
+
+func skipSeparators(data []byte, offset int) int {
+	for offset < len(data) {
+		switch data[offset] {
+		case ' ', '\t', '\r', '\n', ',':
+			offset++
+		default:
+			return offset
+		}
+	}
+	return offset
+}
+
+// decodeYAML accepts a sequence of samples or a mapping holding one under
+// "samples".
+func decodeYAML(data []byte) ([]item, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+
+	list := doc.Content[0]
+	if list.Kind == yaml.MappingNode {
+		root := list
+		list = nil
+		for i := 0; i+1 < len(root.Content); i += 2 {
+			key := root.Content[i]
+			if key.Value != samplesKey {
+				return nil, fmt.Errorf("line %d: unknown key %q, only %q is allowed at the top level", key.Line, key.Value, samplesKey)
+			}
+			list = root.Content[i+1]
+		}
+		if list == nil {
+			return nil, fmt.Errorf("no %q list", samplesKey)
+		}
+	}
+	if list.Kind != yaml.SequenceNode {
+		return nil, fmt.Errorf("line %d: expected a list of samples", list.Line)
+	}
+
+	items := make([]item, 0, len(list.Content))
+	for _, node := range list.Content {
+		var values any
+		if err := node.Decode(&values); err != nil {
+			return nil, err
+		}
+		items = append(items, item{Line: node.Line, Values: values})
+	}
+	return items, nil
+}
+
+var tomlSampleTable = regexp.MustCompile(`(?m)^[ \t]*\[\[[ \t]*"?samples"?[ \t]*\]\]`)
+
+// decodeTOML accepts a document with a "samples" array of tables. Lines are
+// known when every sample is written as a [[samples]] table.
+func decodeTOML(data []byte) ([]item, error) {
+	var doc map[string]any
+	if err := toml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	var list []any
+	for key, value := range doc {
+		if key != samplesKey {
+			return nil, fmt.Errorf("unknown key %q, only %q is allowed at the top level", key, samplesKey)
+		}
+		switch v := value.(type) {
+		case []map[string]any:
+			for _, table := range v {
+				list = append(list, table)
+			}
+		case []any:
+			list = v
+		default:
+			return nil, fmt.Errorf("%q must be an array of tables", samplesKey)
+		}
+	}
+
+	tables := tomlSampleTable.FindAllIndex(data, -1)
+	items := make([]item, len(list))
+	for i, values := range list {
+		items[i] = item{Values: values}
+		if len(tables) == len(list) {
+			items[i].Line = lineAt(data, tables[i][0])
+		}
+	}
+	return items, nil
+}
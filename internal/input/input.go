@@ -0,0 +1,271 @@
+// Package input reads sample lists from CSV, JSON, YAML and TOML files,
+// choosing the format by file extension unless one is set. Every format
+// fills in temperatures from material profiles, validates samples and
+// reports invalid ones the same way.
+package input
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/guntharp/go-filamentsamples/internal/csv"
+	"github.com/guntharp/go-filamentsamples/internal/profiles"
+	"github.com/guntharp/go-filamentsamples/pkg/models"
+	"github.com/guntharp/go-filamentsamples/pkg/scad"
+)
+
+// Input formats accepted in Parser.Format.
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+	FormatTOML = "toml"
+)
+
+// ValidFormat reports whether format is a known input format or "", which
+// chooses by file extension.
+func ValidFormat(format string) bool {
+	switch format {
+	case "", FormatCSV, FormatJSON, FormatYAML, FormatTOML:
+		return true
+	}
+	return false
+}
+
+// extensionFormats maps file extensions to the format they stand for.
+var extensionFormats = map[string]string{
+	".csv":  FormatCSV,
+	".json": FormatJSON,
+	".yaml": FormatYAML,
+	".yml":  FormatYAML,
+	".toml": FormatTOML,
+}
+
+// DetectFormat returns the format of a file from its extension. Files with
+// an unknown extension are CSV.
+func DetectFormat(path string) string {
+	if format, ok := extensionFormats[strings.ToLower(filepath.Ext(path))]; ok {
+		return format
+	}
+	return FormatCSV
+}
+
+// Parser reads sample lists in any supported format.
+type Parser struct {
+	// Format forces the input format; "" chooses by file extension, and
+	// reads standard input as CSV.
+	Format string
+	// Profiles fills in temperatures left empty.
+	Profiles *profiles.Library
+	// Lenient skips invalid samples instead of rejecting the input. Each
+	// problem of a skipped sample is passed to Skipped when it is set.
+	Lenient bool
+	Skipped func(*csv.RowError)
+	// Detected is called with the name and detected format of each CSV
+	// source.
+	Detected func(source string, f csv.Format)
+	// Stdin is read for the source "-".
+	Stdin io.Reader
+	// Schema, when set, is the template the samples are rendered with. CSV
+	// columns it does not define are ignored and passed to Ignored with
+	// the name of their source.
+	Schema  *scad.Schema
+	Ignored func(source, column string)
+}
+
+// NewParser returns a parser that chooses the format by file extension and
+// uses the built-in material profiles.
+func NewParser() *Parser {
+	return &Parser{Profiles: profiles.New(nil), Stdin: os.Stdin}
+}
+
+// ParseFile reads the samples of the sources named by spec, as accepted by
+// Resolve: a file, a directory, "-" for stdin, or a comma separated list of
+// them.
+func (p *Parser) ParseFile(spec string) ([]*models.FilamentSample, error) {
+	return collect(func(fn func(*models.FilamentSample) error) error {
+		return p.StreamFile(spec, fn)
+	})
+}
+
+// StreamFile is like ParseFile but passes samples to fn as they are read,
+// as StreamSources does.
+func (p *Parser) StreamFile(spec string, fn func(*models.FilamentSample) error) error {
+	sources, err := Resolve(spec, p.Format, p.Stdin)
+	if err != nil {
+		return err
+	}
+	return p.StreamSources(sources, fn)
+}
+
+// ParseSources reads and merges the samples of sources in order. Every
+// sample records the source it came from. In strict mode the invalid
+// samples of all sources are reported together in one *csv.ParseError.
+func (p *Parser) ParseSources(sources []Source) ([]*models.FilamentSample, error) {
+	return collect(func(fn func(*models.FilamentSample) error) error {
+		return p.StreamSources(sources, fn)
+	})
+}
+
+// StreamSources reads sources in order and passes each valid sample to fn
+// as soon as it is read. CSV files are read row by row; the other formats
+// are decoded one file at a time. In strict mode no sample is passed on
+// after the first invalid one, but every source is still read so that all
+// invalid samples are reported together in one *csv.ParseError. An error
+// returned by fn stops reading and is returned.
+func (p *Parser) StreamSources(sources []Source, fn func(*models.FilamentSample) error) error {
+	var invalid []*csv.RowError
+	emit := func(sample *models.FilamentSample) error {
+		if len(invalid) > 0 {
+			return nil
+		}
+		return fn(sample)
+	}
+
+	for _, src := range sources {
+		err := p.streamSource(src, emit)
+		var parseErr *csv.ParseError
+		switch {
+		case errors.As(err, &parseErr):
+			invalid = append(invalid, parseErr.Errors...)
+		case err != nil:
+			return err
+		}
+	}
+
+	if len(invalid) > 0 {
+		return &csv.ParseError{Errors: invalid}
+	}
+	return nil
+}
+
+func collect(stream func(func(*models.FilamentSample) error) error) ([]*models.FilamentSample, error) {
+	var samples []*models.FilamentSample
+	err := stream(func(sample *models.FilamentSample) error {
+		samples = append(samples, sample)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return samples, nil
+}
+
+func (p *Parser) streamSource(src Source, fn func(*models.FilamentSample) error) error {
+	name := src.Name()
+	r, err := src.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", name, err)
+	}
+	defer r.Close()
+
+	format := p.Format
+	if format == "" {
+		format = DetectFormat(name)
+	}
+
+	var fnErr error
+	err = p.stream(r, name, format, func(sample *models.FilamentSample) error {
+		fnErr = fn(sample)
+		return fnErr
+	})
+	var parseErr *csv.ParseError
+	if err == nil || err == fnErr || errors.As(err, &parseErr) {
+		return err
+	}
+	return fmt.Errorf("%s: %w", name, err)
+}
+
+// Parse reads samples in format from r.
+func (p *Parser) Parse(r io.Reader, format string) ([]*models.FilamentSample, error) {
+	return collect(func(fn func(*models.FilamentSample) error) error {
+		return p.stream(r, "", format, fn)
+	})
+}
+
+// stream reads samples in format from r, recording source as the origin of
+// every sample and row error.
+func (p *Parser) stream(r io.Reader, source, format string, fn func(*models.FilamentSample) error) error {
+	skipped := func(err *csv.RowError) {
+		err.File = source
+		if p.Skipped != nil {
+			p.Skipped(err)
+		}
+	}
+	emit := func(sample *models.FilamentSample) error {
+		sample.Source = source
+		return fn(sample)
+	}
+
+	var err error
+	switch format {
+	case FormatCSV:
+		parser := csv.NewParser()
+		parser.Profiles = p.Profiles
+		parser.Lenient = p.Lenient
+		parser.Skipped = skipped
+		parser.Detected = func(f csv.Format) {
+			if p.Detected != nil {
+				p.Detected(source, f)
+			}
+		}
+		parser.Schema = p.Schema
+		parser.Ignored = func(column string) {
+			if p.Ignored != nil {
+				p.Ignored(source, column)
+			}
+		}
+		err = parser.Stream(r, emit)
+	case FormatJSON, FormatYAML, FormatTOML:
+		var data []byte
+		if data, err = io.ReadAll(r); err != nil {
+			return err
+		}
+		var items []item
+		if items, err = decode(data, format); err != nil {
+			return err
+		}
+		err = p.build(items, skipped, emit)
+	default:
+		return fmt.Errorf("unknown input format %q", format)
+	}
+
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		for _, rowErr := range parseErr.Errors {
+			rowErr.File = source
+		}
+	}
+	return err
+}
+
+// build converts decoded items into validated samples and passes them to
+// fn, handling invalid items like the CSV parser handles invalid rows.
+func (p *Parser) build(items []item, skipped func(*csv.RowError), fn func(*models.FilamentSample) error) error {
+	var invalid []*csv.RowError
+
+	for i, it := range items {
+		sample, errs := p.convert(it, i+1)
+		switch {
+		case len(errs) > 0 && p.Lenient:
+			for _, err := range errs {
+				skipped(err)
+			}
+		case len(errs) > 0:
+			invalid = append(invalid, errs...)
+		case len(invalid) == 0:
+			if err := fn(sample); err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(invalid) > 0 {
+		return &csv.ParseError{Errors: invalid}
+	}
+	return nil
+}
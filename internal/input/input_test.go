@@ -0,0 +1,312 @@
+package input
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/guntharp/go-filamentsamples/internal/csv"
+	"github.com/guntharp/go-filamentsamples/pkg/models"
+)
+
+func TestDetectFormat(t *testing.T) {
+	tests := map[string]string{
+		"samples.csv":  FormatCSV,
+		"samples.txt":  FormatCSV,
+		"samples":      FormatCSV,
+		"samples.json": FormatJSON,
+		"samples.YAML": FormatYAML,
+		"samples.yml":  FormatYAML,
+		"samples.toml": FormatTOML,
+	}
+	for path, want := range tests {
+		if got := DetectFormat(path); got != want {
+			t.Errorf("DetectFormat(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestParser_Parse(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		input  string
+	}{
+		{
+			name:   "json list",
+			format: FormatJSON,
+			input: `[
+  {"brand": "Bambu Labs", "type": "PLA", "color": "Red", "hotend": "200-220", "bed": 60},
+  {"Brand": "Polymaker", "Material": "PETG", "Colour": "Blue",
+   "params": {"height": 2.5, "corners": [1, 2]}, "notes": "spare", "qty": 3, "parameter_set": "Wide"}
+]`,
+		},
+		{
+			name:   "json object",
+			format: FormatJSON,
+			input: `{"samples": [
+  {"brand": "Bambu Labs", "type": "PLA", "color": "Red", "hotend": "200-220", "bed": 60},
+  {"Brand": "Polymaker", "Material": "PETG", "Colour": "Blue",
+   "params": {"height": 2.5, "corners": [1, 2]}, "notes": "spare", "qty": 3, "Preset": "Wide"}
+]}`,
+		},
+		{
+			name:   "yaml",
+			format: FormatYAML,
+			input: `samples:
+  - brand: Bambu Labs
+    type: PLA
+    color: Red
+    hotend: 200-220
+    bed: 60
+  - brand: Polymaker
+    material: PETG
+    colour: Blue
+    params:
+      height: 2.5
+      corners: [1, 2]
+    notes: spare
+    quantity: 3
+    parameter_set: Wide
+`,
+		},
+		{
+			name:   "toml",
+			format: FormatTOML,
+			input: `[[samples]]
+brand = "Bambu Labs"
+type = "PLA"
+color = "Red"
+hotend = "200-220"
+bed = 60
+
+[[samples]]
+brand = "Polymaker"
+material = "PETG"
+colour = "Blue"
+notes = "spare"
+quantity = 3
+parameter_set = "Wide"
+params = { height = 2.5, corners = [1, 2] }
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			samples, err := NewParser().Parse(strings.NewReader(tt.input), tt.format)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if len(samples) != 2 {
+				t.Fatalf("got %d samples, want 2", len(samples))
+			}
+
+			first := samples[0]
+			if first.Brand != "Bambu Labs" || first.TempHotend != "200-220" || first.TempBed != "60" {
+				t.Errorf("first sample = %+v", first)
+			}
+
+			second := samples[1]
+			if second.Type != "PETG" || second.Color != "Blue" {
+				t.Errorf("second sample = %+v", second)
+			}
+			if second.TempHotend == "" || len(second.Inferred) != 2 {
+				t.Errorf("second sample temperatures were not filled in: %+v", second)
+			}
+			wantParams := map[string]string{"height": "2.5", "corners": "[1, 2]"}
+			if !reflect.DeepEqual(second.Params, wantParams) {
+				t.Errorf("Params = %v, want %v", second.Params, wantParams)
+			}
+			if second.Notes != "spare" || second.Quantity != 3 || second.ParameterSet != "Wide" {
+				t.Errorf("Notes, Quantity, ParameterSet = %q, %d, %q; want spare, 3, Wide", second.Notes, second.Quantity, second.ParameterSet)
+			}
+			if second.Line <= first.Line {
+				t.Errorf("lines = %d, %d; want increasing", first.Line, second.Line)
+			}
+		})
+	}
+}
+
+func TestParser_Parse_TypedParams(t *testing.T) {
+	tests := map[string]string{
+		FormatJSON: `[{"brand": "A", "type": "PLA", "color": "Red",
+  "params": {"FONT": "1984", "SHOW": "true", "LABELS": ["a, b", "c"], "R": 2, "ON": true}}]`,
+		FormatYAML: "- brand: A\n  type: PLA\n  color: Red\n  params:\n" +
+			"    FONT: \"1984\"\n    SHOW: \"true\"\n    LABELS: [\"a, b\", c]\n    R: 2\n    \"ON\": true\n",
+		FormatTOML: "[[samples]]\nbrand = \"A\"\ntype = \"PLA\"\ncolor = \"Red\"\n" +
+			"params = { FONT = \"1984\", SHOW = \"true\", LABELS = [\"a, b\", \"c\"], R = 2, ON = true }\n",
+	}
+	want := map[string]string{
+		"FONT": `"1984"`, "SHOW": `"true"`, "LABELS": `["a, b", "c"]`, "R": "2", "ON": "true",
+	}
+
+	for format, input := range tests {
+		t.Run(format, func(t *testing.T) {
+			samples, err := NewParser().Parse(strings.NewReader(input), format)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if got := samples[0].Params; !reflect.DeepEqual(got, want) {
+				t.Errorf("Params = %v, want %v", got, want)
+			}
+			// The literals are passed to OpenSCAD as they are.
+			if got := samples[0].ParamLiterals(models.ArgOptions{}); !reflect.DeepEqual(got, want) {
+				t.Errorf("ParamLiterals() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestParser_Parse_Lines(t *testing.T) {
+	tests := []struct {
+		format string
+		input  string
+		want   []int
+	}{
+		{FormatJSON, "[\n  {\"brand\": \"A\", \"type\": \"PLA\", \"color\": \"Red\"},\n\n  {\"brand\": \"B\", \"type\": \"PLA\", \"color\": \"Blue\"}\n]", []int{2, 4}},
+		{FormatYAML, "# samples\n- brand: A\n  type: PLA\n  color: Red\n- brand: B\n  type: PLA\n  color: Blue\n", []int{2, 5}},
+		{FormatTOML, "# samples\n[[samples]]\nbrand = \"A\"\ntype = \"PLA\"\ncolor = \"Red\"\n\n[[samples]]\nbrand = \"B\"\ntype = \"PLA\"\ncolor = \"Blue\"\n", []int{2, 7}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			samples, err := NewParser().Parse(strings.NewReader(tt.input), tt.format)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			var got []int
+			for _, s := range samples {
+				got = append(got, s.Line)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("lines = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParser_Parse_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		input   string
+		wantErr []string
+	}{
+		{
+			name:    "invalid json",
+			format:  FormatJSON,
+			input:   "[\n  {\"brand\": \"A\",}\n]",
+			wantErr: []string{"invalid json: line 2"},
+		},
+		{
+			name:    "unknown top level key",
+			format:  FormatYAML,
+			input:   "sample:\n  - brand: A\n",
+			wantErr: []string{`line 1: unknown key "sample"`},
+		},
+		{
+			name:    "not a list",
+			format:  FormatJSON,
+			input:   `"PLA"`,
+			wantErr: []string{"expected a list of samples"},
+		},
+		{
+			name:   "invalid samples",
+			format: FormatJSON,
+			input: `[
+  {"brand": "A", "type": "PLA", "color": "Red", "hotend": "hot"},
+  {"brand": "B", "type": "PLA", "color": ["Red"], "spool": 1},
+  "PLA"
+]`,
+			wantErr: []string{
+				`line 2, column hotend: invalid temperature "hot"`,
+				`line 3, column color: must be a single value, got a list`,
+				`line 3, column spool: unknown key`,
+				`line 4: expected a table of sample fields, got "PLA"`,
+			},
+		},
+		{
+			name:    "invalid params",
+			format:  FormatYAML,
+			input:   "- brand: A\n  type: PLA\n  color: Red\n  params:\n    2nd: 1\n",
+			wantErr: []string{`line 1, column params: invalid parameter name "2nd"`},
+		},
+		{
+			name:    "negative quantity",
+			format:  FormatTOML,
+			input:   "[[samples]]\nbrand = \"A\"\ntype = \"PLA\"\ncolor = \"Red\"\nqty = -1\n",
+			wantErr: []string{"line 1, column qty: quantity must not be negative"},
+		},
+		{
+			name:    "unknown format",
+			format:  "xml",
+			input:   "<samples/>",
+			wantErr: []string{`unknown input format "xml"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewParser().Parse(strings.NewReader(tt.input), tt.format)
+			if err == nil {
+				t.Fatal("Parse() expected an error")
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q does not contain %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParser_Parse_Lenient(t *testing.T) {
+	input := `[
+  {"brand": "A", "type": "PLA", "color": "Red"},
+  {"brand": "B", "type": "PLA", "color": "Blue", "hotend": "hot"}
+]`
+	parser := NewParser()
+	parser.Lenient = true
+	var skipped []*csv.RowError
+	parser.Skipped = func(err *csv.RowError) { skipped = append(skipped, err) }
+
+	samples, err := parser.Parse(strings.NewReader(input), FormatJSON)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(samples) != 1 || samples[0].Brand != "A" {
+		t.Errorf("samples = %+v, want only brand A", samples)
+	}
+	if len(skipped) != 1 || skipped[0].Line != 3 || skipped[0].Column != "hotend" {
+		t.Errorf("skipped = %+v, want line 3 column hotend", skipped)
+	}
+}
+
+func TestParser_ParseFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "samples.yml")
+	if err := os.WriteFile(path, []byte("- brand: A\n  type: PLA\n  color: Red\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	samples, err := NewParser().ParseFile(path)
+	if err != nil || len(samples) != 1 {
+		t.Fatalf("ParseFile() = %v, %v; want one sample", samples, err)
+	}
+
+	parser := NewParser()
+	parser.Format = FormatCSV
+	if _, err := parser.ParseFile(path); err == nil {
+		t.Error("ParseFile() with a forced CSV format should fail on YAML")
+	}
+
+	var parseErr *csv.ParseError
+	_, err = NewParser().Parse(strings.NewReader(`[{"brand": "A"}]`), FormatJSON)
+	if !errors.As(err, &parseErr) {
+		t.Errorf("error %v is not a *csv.ParseError", err)
+	}
+}
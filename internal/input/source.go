@@ -0,0 +1,130 @@
+package input
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// StdinName is the name given to samples read from standard input.
+const StdinName = "<stdin>"
+
+// Source is a stream of samples with a name used in messages and reports,
+// such as the path of a file.
+type Source interface {
+	Name() string
+	Open() (io.ReadCloser, error)
+}
+
+type fileSource string
+
+// File returns a source reading the file at path.
+func File(path string) Source { return fileSource(path) }
+
+func (f fileSource) Name() string { return string(f) }
+
+func (f fileSource) Open() (io.ReadCloser, error) { return os.Open(string(f)) }
+
+type readerSource struct {
+	name string
+	r    io.Reader
+}
+
+// Reader returns a source reading r once under name.
+func Reader(name string, r io.Reader) Source { return readerSource{name: name, r: r} }
+
+func (s readerSource) Name() string { return s.name }
+
+func (s readerSource) Open() (io.ReadCloser, error) { return io.NopCloser(s.r), nil }
+
+// Resolve returns the sources named by spec, a comma separated list of
+// files, directories and "-" for stdin. A directory stands for the files of
+// format directly inside it, in name order: its .csv files when format is
+// "" or csv, and for example its .yaml and .yml files when it is yaml.
+// Hidden files are left out, and so are the structured files of a
+// directory read as CSV, such as the config and the Customizer's parameter
+// file kept next to the sample lists.
+func Resolve(spec, format string, stdin io.Reader) ([]Source, error) {
+	var sources []Source
+	usedStdin := false
+
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.TrimSpace(name)
+		switch {
+		case name == "":
+			continue
+		case name == "-":
+			if usedStdin {
+				return nil, errors.New("standard input can only be read once")
+			}
+			usedStdin = true
+			sources = append(sources, Reader(StdinName, stdin))
+			continue
+		}
+
+		info, err := os.Stat(name)
+		if err != nil || !info.IsDir() {
+			// Missing files are reported when they are opened.
+			sources = append(sources, File(name))
+			continue
+		}
+		files, err := sampleFiles(name, format)
+		if err != nil {
+			return nil, err
+		}
+		if len(files) == 0 {
+			return nil, fmt.Errorf("no sample files in directory %s", name)
+		}
+		for _, file := range files {
+			sources = append(sources, File(file))
+		}
+	}
+
+	if len(sources) == 0 {
+		return nil, errors.New("no sample files given")
+	}
+	return sources, nil
+}
+
+func sampleFiles(dir, format string) ([]string, error) {
+	if format == "" {
+		format = FormatCSV
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") {
+			continue
+		}
+		if extensionFormats[strings.ToLower(filepath.Ext(name))] == format {
+			files = append(files, filepath.Join(dir, name))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// BaseDir returns the directory the first source in spec lives in: the
+// directory itself when it names one, and "." for stdin. Default paths such
+// as the template and output directory are relative to it.
+func BaseDir(spec string) string {
+	first, _, _ := strings.Cut(spec, ",")
+	first = strings.TrimSpace(first)
+	if first == "" || first == "-" {
+		return "."
+	}
+	if info, err := os.Stat(first); err == nil && info.IsDir() {
+		return filepath.Clean(first)
+	}
+	return filepath.Dir(first)
+}
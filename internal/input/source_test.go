@@ -0,0 +1,201 @@
+package input
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/guntharp/go-filamentsamples/internal/csv"
+	"github.com/guntharp/go-filamentsamples/pkg/models"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func names(sources []Source) []string {
+	var out []string
+	for _, src := range sources {
+		out = append(out, src.Name())
+	}
+	return out
+}
+
+func TestResolve(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"polymaker.csv":         "",
+		"bambu.yaml":            "",
+		"esun.yml":              "",
+		"notes.txt":             "",
+		".hidden.csv":           "",
+		"elegoo.CSV":            "",
+		"prusament.toml":        "",
+		"filament-samples.json": "",
+		"FilamentSamples.json":  "",
+	})
+	if err := os.Mkdir(filepath.Join(dir, "old.csv"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	empty := t.TempDir()
+
+	tests := []struct {
+		name    string
+		spec    string
+		format  string
+		want    []string
+		wantErr string
+	}{
+		{name: "file", spec: "samples.csv", want: []string{"samples.csv"}},
+		{name: "stdin", spec: "-", want: []string{StdinName}},
+		{
+			// The config and the Customizer's parameter file next to the
+			// sample lists are not read as samples.
+			name: "directory",
+			spec: dir,
+			want: []string{filepath.Join(dir, "elegoo.CSV"), filepath.Join(dir, "polymaker.csv")},
+		},
+		{
+			name:   "directory with format",
+			spec:   dir,
+			format: FormatYAML,
+			want:   []string{filepath.Join(dir, "bambu.yaml"), filepath.Join(dir, "esun.yml")},
+		},
+		{name: "list", spec: "a.csv, - ,b.json,", want: []string{"a.csv", StdinName, "b.json"}},
+		{name: "stdin twice", spec: "-,-", wantErr: "only be read once"},
+		{name: "empty directory", spec: empty, wantErr: "no sample files in directory"},
+		{name: "nothing", spec: " , ", wantErr: "no sample files given"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sources, err := Resolve(tt.spec, tt.format, strings.NewReader(""))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Resolve(%q) error = %v, want %q", tt.spec, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Resolve(%q) error = %v", tt.spec, err)
+			}
+			if got := names(sources); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Resolve(%q) = %v, want %v", tt.spec, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBaseDir(t *testing.T) {
+	dir := t.TempDir()
+	tests := map[string]string{
+		"samples.csv":                  ".",
+		"lists/samples.csv,b.csv":      "lists",
+		"-":                            ".",
+		dir:                            dir,
+		dir + string(os.PathSeparator): dir,
+	}
+	for spec, want := range tests {
+		if got := BaseDir(spec); got != want {
+			t.Errorf("BaseDir(%q) = %q, want %q", spec, got, want)
+		}
+	}
+}
+
+func TestParser_ParseFile_Sources(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"a.csv":  "Brand,Type,Color\nA,PLA,Red\nA,PETG,Blue\n",
+		"b.yaml": "- brand: B\n  type: PLA\n  color: Green\n",
+	})
+
+	parser := NewParser()
+	parser.Stdin = strings.NewReader("C,PLA,White,200,60\n")
+	samples, err := parser.ParseFile(dir + "," + filepath.Join(dir, "b.yaml") + ",-")
+	if err != nil {
+		t.Fatalf("ParseFile() error = %v", err)
+	}
+
+	var got []string
+	for _, s := range samples {
+		got = append(got, filepath.Base(s.Source)+":"+s.Brand+":"+s.Color)
+	}
+	want := []string{"a.csv:A:Red", "a.csv:A:Blue", "b.yaml:B:Green", StdinName + ":C:White"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("samples = %v, want %v", got, want)
+	}
+	if samples[1].Line != 3 || samples[2].Line != 1 {
+		t.Errorf("lines = %d, %d; want 3, 1", samples[1].Line, samples[2].Line)
+	}
+}
+
+func TestParser_ParseSources_Errors(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"a.csv":  "A,PLA,Red,hot,60\nA,PLA,Blue,200,60\n",
+		"b.json": `[{"brand": "B", "type": "PLA"}]`,
+	})
+	sources := []Source{File(filepath.Join(dir, "a.csv")), File(filepath.Join(dir, "b.json"))}
+
+	_, err := NewParser().ParseSources(sources)
+	var parseErr *csv.ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("ParseSources() error = %v, want a *csv.ParseError", err)
+	}
+	if len(parseErr.Errors) != 2 {
+		t.Fatalf("got %d errors, want one per file: %v", len(parseErr.Errors), err)
+	}
+	for i, file := range []string{"a.csv", "b.json"} {
+		if got := parseErr.Errors[i].File; got != filepath.Join(dir, file) {
+			t.Errorf("error %d file = %q, want %s", i, got, file)
+		}
+	}
+	if !strings.Contains(err.Error(), filepath.Join(dir, "a.csv")+": line 1, column TempHotend") {
+		t.Errorf("error does not name the file and line: %v", err)
+	}
+
+	parser := NewParser()
+	parser.Lenient = true
+	var skipped []string
+	parser.Skipped = func(err *csv.RowError) { skipped = append(skipped, filepath.Base(err.File)) }
+	samples, err := parser.ParseSources(sources)
+	if err != nil || len(samples) != 1 {
+		t.Fatalf("lenient ParseSources() = %d samples, %v; want 1", len(samples), err)
+	}
+	if !reflect.DeepEqual(skipped, []string{"a.csv", "b.json"}) {
+		t.Errorf("skipped files = %v", skipped)
+	}
+
+	_, err = NewParser().ParseSources([]Source{File(filepath.Join(dir, "missing.csv"))})
+	if err == nil || !strings.Contains(err.Error(), "failed to open") {
+		t.Errorf("missing file error = %v", err)
+	}
+}
+
+func TestParser_StreamSources_Strict(t *testing.T) {
+	sources := []Source{
+		Reader("a.csv", strings.NewReader("A,PLA,Red,200,60\nA,PLA,Blue,hot,60\nA,PLA,Green,200,60\n")),
+		Reader("b.csv", strings.NewReader("B,PLA,Red,200,60\nB,PLA,Blue,200,bed\n")),
+	}
+
+	var streamed []string
+	err := NewParser().StreamSources(sources, func(sample *models.FilamentSample) error {
+		streamed = append(streamed, sample.Source+":"+sample.Color)
+		return nil
+	})
+	var parseErr *csv.ParseError
+	if !errors.As(err, &parseErr) || len(parseErr.Errors) != 2 {
+		t.Fatalf("StreamSources() error = %v, want the invalid rows of both sources", err)
+	}
+	if !reflect.DeepEqual(streamed, []string{"a.csv:Red"}) {
+		t.Errorf("streamed %v, want only the sample before the first invalid one", streamed)
+	}
+}
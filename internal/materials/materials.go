@@ -0,0 +1,256 @@
+// Package materials checks sample temperatures against per-material limits,
+// catching typos such as a PLA hotend of 400 and rows whose hotend and bed
+// columns were swapped.
+package materials
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/guntharp/go-filamentsamples/pkg/models"
+)
+
+// Limits bounds one temperature of a material in Celsius. Values outside
+// Min and Max are errors; values outside the typical range are warnings. A
+// zero TypicalMax disables the typical range.
+type Limits struct {
+	Min        int `json:"min"`
+	Max        int `json:"max"`
+	TypicalMin int `json:"typical_min,omitempty"`
+	TypicalMax int `json:"typical_max,omitempty"`
+}
+
+// Rule holds the limits of a material. A nil field is not checked, and in
+// an override keeps the built-in limits.
+type Rule struct {
+	Hotend *Limits `json:"hotend,omitempty"`
+	Bed    *Limits `json:"bed,omitempty"`
+}
+
+// Rules maps a normalized material name, such as PLA or PA-CF, to its rule.
+type Rules map[string]Rule
+
+// Generic applies to materials that are not in the table. It only catches
+// values no printer can use.
+var Generic = Rule{
+	Hotend: &Limits{Min: 150, Max: 350},
+	Bed:    &Limits{Min: 0, Max: 160},
+}
+
+var builtin = Rules{
+	"PLA":     {Hotend: &Limits{160, 270, 180, 240}, Bed: &Limits{0, 100, 20, 70}},
+	"PLA-CF":  {Hotend: &Limits{180, 280, 200, 250}, Bed: &Limits{0, 100, 20, 70}},
+	"PETG":    {Hotend: &Limits{200, 300, 220, 270}, Bed: &Limits{0, 120, 60, 90}},
+	"PETG-CF": {Hotend: &Limits{220, 310, 240, 290}, Bed: &Limits{40, 120, 60, 100}},
+	"ABS":     {Hotend: &Limits{200, 300, 220, 270}, Bed: &Limits{50, 130, 80, 110}},
+	"ASA":     {Hotend: &Limits{220, 300, 235, 270}, Bed: &Limits{50, 130, 80, 110}},
+	"PC":      {Hotend: &Limits{230, 320, 250, 300}, Bed: &Limits{60, 150, 90, 120}},
+	"TPU":     {Hotend: &Limits{180, 260, 200, 240}, Bed: &Limits{0, 90, 25, 60}},
+	"PA":      {Hotend: &Limits{220, 320, 240, 280}, Bed: &Limits{30, 130, 60, 100}},
+	"PA-CF":   {Hotend: &Limits{240, 330, 260, 300}, Bed: &Limits{30, 130, 80, 110}},
+	"HIPS":    {Hotend: &Limits{200, 280, 220, 250}, Bed: &Limits{60, 130, 90, 110}},
+	"PVA":     {Hotend: &Limits{160, 240, 185, 220}, Bed: &Limits{0, 80, 45, 65}},
+	"PP":      {Hotend: &Limits{190, 270, 210, 250}, Bed: &Limits{40, 120, 80, 100}},
+}
+
+// aliases maps other common names to a material in the table.
+var aliases = map[string]string{
+	"NYLON":   "PA",
+	"PA6":     "PA",
+	"PA12":    "PA",
+	"PAHT":    "PA",
+	"PA6-CF":  "PA-CF",
+	"PA12-CF": "PA-CF",
+	"PAHT-CF": "PA-CF",
+	"PET-CF":  "PETG-CF",
+	"TPE":     "TPU",
+	"FLEX":    "TPU",
+}
+
+// Default returns a copy of the built-in rules.
+func Default() Rules {
+	rules := make(Rules, len(builtin))
+	for name, rule := range builtin {
+		rules[name] = rule
+	}
+	return rules
+}
+
+// Merge returns r with overrides applied. Material names are normalized,
+// and each limit given in an override replaces the built-in one.
+func (r Rules) Merge(overrides map[string]Rule) Rules {
+	merged := make(Rules, len(r)+len(overrides))
+	for name, rule := range r {
+		merged[name] = rule
+	}
+	for name, override := range overrides {
+		name = normalize(name)
+		rule := merged[name]
+		if override.Hotend != nil {
+			rule.Hotend = override.Hotend
+		}
+		if override.Bed != nil {
+			rule.Bed = override.Bed
+		}
+		merged[name] = rule
+	}
+	return merged
+}
+
+// Validate reports limits that contradict themselves.
+func (r Rules) Validate() error {
+	names := make([]string, 0, len(r))
+	for name := range r {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		for field, l := range map[string]*Limits{"hotend": r[name].Hotend, "bed": r[name].Bed} {
+			if l == nil {
+				continue
+			}
+			if l.Min > l.Max {
+				return fmt.Errorf("%s %s: min %d is above max %d", name, field, l.Min, l.Max)
+			}
+			if l.TypicalMax != 0 && (l.TypicalMin > l.TypicalMax || l.TypicalMin < l.Min || l.TypicalMax > l.Max) {
+				return fmt.Errorf("%s %s: typical range %d-%d must lie within %d-%d",
+					name, field, l.TypicalMin, l.TypicalMax, l.Min, l.Max)
+			}
+		}
+	}
+	return nil
+}
+
+var separators = regexp.MustCompile(`[\s_/]+`)
+
+func normalize(material string) string {
+	s := strings.ToUpper(strings.TrimSpace(material))
+	s = strings.ReplaceAll(s, "+", "")
+	return strings.Trim(separators.ReplaceAllString(s, "-"), "-")
+}
+
+// Lookup finds the rule for a sample type such as "PLA Matte", "PLA+" or
+// "PA6-CF". It tries the full normalized name, then shorter prefixes, then
+// each word on its own, so "PLA Silk" and "Silk PLA" both use PLA. The
+// returned name is the material the rule belongs to.
+func (r Rules) Lookup(material string) (string, Rule, bool) {
+	segments := strings.Split(normalize(material), "-")
+
+	var candidates []string
+	for n := len(segments); n > 0; n-- {
+		candidates = append(candidates, strings.Join(segments[:n], "-"))
+	}
+	candidates = append(candidates, segments[1:]...)
+
+	for _, c := range candidates {
+		if alias, ok := aliases[c]; ok {
+			c = alias
+		}
+		if rule, ok := r[c]; ok {
+			return c, rule, true
+		}
+	}
+	return "", Rule{}, false
+}
+
+// Severity tells whether an issue stops generation.
+type Severity string
+
+const (
+	Warning Severity = "warning"
+	Error   Severity = "error"
+)
+
+// Issue is an implausible temperature in a sample.
+type Issue struct {
+	Line     int
+	Severity Severity
+	Sample   *models.FilamentSample
+	Message  string
+}
+
+func (i Issue) String() string {
+	prefix := ""
+	if i.Sample.Source != "" {
+		prefix = i.Sample.Source + ": "
+	}
+	if i.Line > 0 {
+		prefix += fmt.Sprintf("line %d: ", i.Line)
+	}
+	return fmt.Sprintf("%s%s: %s %s %s: %s", prefix, i.Severity,
+		i.Sample.Brand, i.Sample.Type, i.Sample.Color, i.Message)
+}
+
+// Check returns the plausibility issues of every sample. Samples whose
+// temperatures do not parse are left to FilamentSample.Validate.
+func (r Rules) Check(samples []*models.FilamentSample) []Issue {
+	var issues []Issue
+	for _, sample := range samples {
+		issues = append(issues, r.CheckSample(sample)...)
+	}
+	return issues
+}
+
+// CheckSample returns the plausibility issues of one sample.
+func (r Rules) CheckSample(sample *models.FilamentSample) []Issue {
+	hotend, err := sample.HotendRange()
+	if err != nil {
+		return nil
+	}
+	bed, err := sample.BedRange()
+	if err != nil {
+		return nil
+	}
+	hotend, bed = hotend.Celsius(), bed.Celsius()
+
+	issue := func(severity Severity, format string, args ...any) Issue {
+		return Issue{Line: sample.Line, Severity: severity, Sample: sample, Message: fmt.Sprintf(format, args...)}
+	}
+
+	if hotend.Max <= bed.Min {
+		return []Issue{issue(Error, "hotend %s°C is not above bed %s°C, the columns look swapped", hotend, bed)}
+	}
+
+	name, rule, ok := r.Lookup(sample.Type)
+	if !ok {
+		name, rule = "any material", Generic
+	}
+
+	var issues []Issue
+	for _, c := range []struct {
+		field  string
+		value  models.TemperatureRange
+		limits *Limits
+	}{
+		{"hotend", hotend, rule.Hotend},
+		{"bed", bed, rule.Bed},
+	} {
+		l := c.limits
+		if l == nil {
+			continue
+		}
+		switch {
+		case c.value.Min < l.Min || c.value.Max > l.Max:
+			issues = append(issues, issue(Error, "%s %s°C is outside the %d-%d°C possible for %s",
+				c.field, c.value, l.Min, l.Max, name))
+		case l.TypicalMax != 0 && (c.value.Min < l.TypicalMin || c.value.Max > l.TypicalMax):
+			issues = append(issues, issue(Warning, "%s %s°C is outside the typical %d-%d°C for %s",
+				c.field, c.value, l.TypicalMin, l.TypicalMax, name))
+		}
+	}
+	return issues
+}
+
+// Errors returns the issues with error severity.
+func Errors(issues []Issue) []Issue {
+	var errs []Issue
+	for _, i := range issues {
+		if i.Severity == Error {
+			errs = append(errs, i)
+		}
+	}
+	return errs
+}
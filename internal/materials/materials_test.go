@@ -0,0 +1,144 @@
+package materials
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/guntharp/go-filamentsamples/pkg/models"
+)
+
+func TestRules_Lookup(t *testing.T) {
+	rules := Default()
+
+	tests := []struct {
+		material string
+		want     string
+		wantOK   bool
+	}{
+		{"PLA", "PLA", true},
+		{"pla", "PLA", true},
+		{"PLA Matte", "PLA", true},
+		{"PLA+", "PLA", true},
+		{"Silk PLA", "PLA", true},
+		{"PolyTerra PLA", "PLA", true},
+		{"PLA-CF", "PLA-CF", true},
+		{"PETG Pro", "PETG", true},
+		{"PETG-CF", "PETG-CF", true},
+		{"PET-CF", "PETG-CF", true},
+		{"PA6-CF", "PA-CF", true},
+		{"PAHT-CF", "PA-CF", true},
+		{"Nylon", "PA", true},
+		{"TPU 95A", "TPU", true},
+		{"TPU Silk", "TPU", true},
+		{"ABS", "ABS", true},
+		{"Wood", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.material, func(t *testing.T) {
+			got, _, ok := rules.Lookup(tt.material)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("Lookup(%q) = %q, %v, want %q, %v", tt.material, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestRules_CheckSample(t *testing.T) {
+	tests := []struct {
+		name         string
+		material     string
+		hotend, bed  string
+		wantSeverity Severity
+		wantMessage  string
+	}{
+		{name: "typical PLA", material: "PLA Basic", hotend: "190-230", bed: "35-45"},
+		{name: "typical PETG", material: "PETG", hotend: "240-270", bed: "65-75"},
+		{name: "PLA hotend typo", material: "PLA", hotend: "400", bed: "60", wantSeverity: Error, wantMessage: "hotend 400°C is outside the 160-270°C possible for PLA"},
+		{name: "PLA hotend unusual", material: "PLA", hotend: "250", bed: "60", wantSeverity: Warning, wantMessage: "outside the typical 180-240°C"},
+		{name: "ABS cold bed", material: "ABS", hotend: "250", bed: "20", wantSeverity: Error, wantMessage: "bed 20°C"},
+		{name: "swapped columns", material: "PLA", hotend: "60", bed: "210", wantSeverity: Error, wantMessage: "columns look swapped"},
+		{name: "Fahrenheit", material: "PLA", hotend: "410°F", bed: "140°F"},
+		{name: "unknown material", material: "Wood", hotend: "200", bed: "60"},
+		{name: "unknown material impossible", material: "Wood", hotend: "500", bed: "60", wantSeverity: Error, wantMessage: "any material"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sample := &models.FilamentSample{
+				Brand: "Test", Type: tt.material, Color: "Red",
+				TempHotend: tt.hotend, TempBed: tt.bed, Line: 7,
+			}
+
+			issues := Default().CheckSample(sample)
+			if tt.wantSeverity == "" {
+				if len(issues) != 0 {
+					t.Fatalf("expected no issues, got %v", issues)
+				}
+				return
+			}
+			if len(issues) != 1 {
+				t.Fatalf("expected one issue, got %v", issues)
+			}
+			if issues[0].Severity != tt.wantSeverity {
+				t.Errorf("severity = %s, want %s", issues[0].Severity, tt.wantSeverity)
+			}
+			if !strings.Contains(issues[0].Message, tt.wantMessage) {
+				t.Errorf("message %q does not contain %q", issues[0].Message, tt.wantMessage)
+			}
+			if !strings.HasPrefix(issues[0].String(), "line 7: "+string(tt.wantSeverity)+": Test "+tt.material+" Red: ") {
+				t.Errorf("unexpected issue text %q", issues[0].String())
+			}
+
+			sample.Source = "bambu.csv"
+			if got := Default().CheckSample(sample)[0].String(); !strings.HasPrefix(got, "bambu.csv: line 7: ") {
+				t.Errorf("issue text %q does not name the source", got)
+			}
+		})
+	}
+}
+
+func TestRules_Merge(t *testing.T) {
+	rules := Default().Merge(map[string]Rule{
+		"pla":  {Hotend: &Limits{Min: 150, Max: 300}},
+		"Wood": {Hotend: &Limits{Min: 180, Max: 230, TypicalMin: 190, TypicalMax: 220}},
+	})
+
+	sample := &models.FilamentSample{Brand: "Test", Type: "PLA", Color: "Red", TempHotend: "280", TempBed: "60"}
+	if issues := rules.CheckSample(sample); len(issues) != 0 {
+		t.Errorf("override should allow PLA at 280, got %v", issues)
+	}
+	if rules["PLA"].Bed == nil {
+		t.Error("override of the hotend limits should keep the built-in bed limits")
+	}
+	if Default()["PLA"].Hotend.Max != 270 {
+		t.Error("Merge must not modify the built-in rules")
+	}
+
+	wood := &models.FilamentSample{Brand: "Test", Type: "Wood PLA", Color: "Oak", TempHotend: "240", TempBed: "60"}
+	issues := rules.CheckSample(wood)
+	if len(Errors(issues)) != 1 {
+		t.Errorf("added Wood rule should reject 240, got %v", issues)
+	}
+}
+
+func TestRules_Validate(t *testing.T) {
+	if err := Default().Validate(); err != nil {
+		t.Fatalf("built-in rules are invalid: %v", err)
+	}
+
+	tests := map[string]Limits{
+		"min above max":          {Min: 300, Max: 200},
+		"typical outside limits": {Min: 180, Max: 250, TypicalMin: 170, TypicalMax: 240},
+		"typical reversed":       {Min: 180, Max: 250, TypicalMin: 240, TypicalMax: 200},
+	}
+	for name, limits := range tests {
+		t.Run(name, func(t *testing.T) {
+			limits := limits
+			rules := Default().Merge(map[string]Rule{"PLA": {Hotend: &limits}})
+			if err := rules.Validate(); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package openscad
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Severity classifies a line of OpenSCAD console output.
+type Severity string
+
+const (
+	SeverityEcho    Severity = "ECHO"
+	SeverityTrace   Severity = "TRACE"
+	SeverityWarning Severity = "WARNING"
+	SeverityError   Severity = "ERROR"
+)
+
+// Diagnostic is a single message printed by OpenSCAD while rendering.
+type Diagnostic struct {
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+	File     string   `json:"file,omitempty"`
+	Line     int      `json:"line,omitempty"`
+}
+
+func (d Diagnostic) String() string {
+	return string(d.Severity) + ": " + d.Message
+}
+
+// severityPrefixes maps the prefixes OpenSCAD uses to a severity. DEPRECATED
+// messages are reported as warnings since they do not stop rendering.
+var severityPrefixes = []struct {
+	prefix   string
+	severity Severity
+}{
+	{"ECHO:", SeverityEcho},
+	{"TRACE:", SeverityTrace},
+	{"WARNING:", SeverityWarning},
+	{"DEPRECATED:", SeverityWarning},
+	{"ERROR:", SeverityError},
+}
+
+var locationPattern = regexp.MustCompile(`in file "?([^",]+)"?, line (\d+)`)
+
+// ParseDiagnostics extracts the ECHO, TRACE, WARNING and ERROR lines from
+// OpenSCAD's console output. Progress and statistics lines are dropped.
+// Lines may be of any length: an ECHO of a large list easily exceeds the
+// limit of a bufio.Scanner.
+func ParseDiagnostics(output []byte) []Diagnostic {
+	var diagnostics []Diagnostic
+
+	for _, raw := range bytes.Split(output, []byte("\n")) {
+		line := strings.TrimSpace(string(raw))
+		for _, p := range severityPrefixes {
+			message, ok := strings.CutPrefix(line, p.prefix)
+			if !ok {
+				continue
+			}
+
+			d := Diagnostic{Severity: p.severity, Message: strings.TrimSpace(message)}
+			if p.prefix == "DEPRECATED:" {
+				d.Message = line
+			}
+			if m := locationPattern.FindStringSubmatch(line); m != nil {
+				d.File = m[1]
+				d.Line, _ = strconv.Atoi(m[2])
+			}
+			diagnostics = append(diagnostics, d)
+			break
+		}
+	}
+
+	return diagnostics
+}
+
+var undefinedPattern = regexp.MustCompile(`unknown (variable|module|function) ['"]?([^'"\s,]+)['"]?`)
+
+// UndefinedReference reports whether d is OpenSCAD's warning about a
+// reference to an undefined variable, module or function, and if so which
+// kind of name it was and the name itself.
+func (d Diagnostic) UndefinedReference() (kind, name string, ok bool) {
+	if d.Severity != SeverityWarning {
+		return "", "", false
+	}
+	m := undefinedPattern.FindStringSubmatch(d.Message)
+	if m == nil {
+		return "", "", false
+	}
+	return m[1], m[2], true
+}
+
+// Filter returns the diagnostics with the given severity.
+func Filter(diagnostics []Diagnostic, severity Severity) []Diagnostic {
+	var matched []Diagnostic
+	for _, d := range diagnostics {
+		if d.Severity == severity {
+			matched = append(matched, d)
+		}
+	}
+	return matched
+}
@@ -0,0 +1,89 @@
+package openscad
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseDiagnostics(t *testing.T) {
+	output := `Geometries in cache: 12
+ECHO: "rendering", "ABS"
+WARNING: Ignoring unknown variable 'ABS_NOTCH_X' in file FilamentSamples.scad, line 193
+WARNING: Ignoring unknown variable "ABS_NOTCH_X" in file /cards/FilamentSamples.scad, line 193
+TRACE: called by 'Card' in file FilamentSamples.scad, line 225
+DEPRECATED: The assign() module will be removed in future releases. Use a regular assignment instead.
+ERROR: Parser error in file "/cards/broken.scad", line 3: syntax error
+Rendering Polygon Mesh using CGAL...
+`
+
+	want := []Diagnostic{
+		{Severity: SeverityEcho, Message: `"rendering", "ABS"`},
+		{Severity: SeverityWarning, Message: "Ignoring unknown variable 'ABS_NOTCH_X' in file FilamentSamples.scad, line 193", File: "FilamentSamples.scad", Line: 193},
+		{Severity: SeverityWarning, Message: `Ignoring unknown variable "ABS_NOTCH_X" in file /cards/FilamentSamples.scad, line 193`, File: "/cards/FilamentSamples.scad", Line: 193},
+		{Severity: SeverityTrace, Message: "called by 'Card' in file FilamentSamples.scad, line 225", File: "FilamentSamples.scad", Line: 225},
+		{Severity: SeverityWarning, Message: "DEPRECATED: The assign() module will be removed in future releases. Use a regular assignment instead."},
+		{Severity: SeverityError, Message: `Parser error in file "/cards/broken.scad", line 3: syntax error`, File: "/cards/broken.scad", Line: 3},
+	}
+
+	got := ParseDiagnostics([]byte(output))
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseDiagnostics() =\n%+v\nwant\n%+v", got, want)
+	}
+
+	if warnings := Filter(got, SeverityWarning); len(warnings) != 3 {
+		t.Errorf("Filter(WARNING) returned %d diagnostics, want 3", len(warnings))
+	}
+
+	if s := got[1].String(); s != "WARNING: Ignoring unknown variable 'ABS_NOTCH_X' in file FilamentSamples.scad, line 193" {
+		t.Errorf("String() = %q", s)
+	}
+}
+
+func TestParseDiagnostics_Empty(t *testing.T) {
+	if got := ParseDiagnostics(nil); got != nil {
+		t.Errorf("ParseDiagnostics(nil) = %v, want nil", got)
+	}
+}
+
+func TestParseDiagnostics_LongLine(t *testing.T) {
+	// An ECHO of a large list, longer than a bufio.Scanner line.
+	echo := "ECHO: [" + strings.Repeat("0.2, ", 100000) + "0.2]"
+	output := echo + "\nWARNING: Ignoring unknown variable 'ABS_NOTCH_X' in file FilamentSamples.scad, line 193\n"
+
+	got := ParseDiagnostics([]byte(output))
+	if len(got) != 2 || got[0].Severity != SeverityEcho || got[1].Severity != SeverityWarning {
+		t.Fatalf("ParseDiagnostics() returned %d diagnostics, want the ECHO and the WARNING", len(got))
+	}
+	if len(got[0].Message) != len(echo)-len("ECHO: ") {
+		t.Errorf("ECHO message truncated to %d bytes", len(got[0].Message))
+	}
+}
+
+func TestDiagnostic_UndefinedReference(t *testing.T) {
+	tests := []struct {
+		message  string
+		severity Severity
+		wantKind string
+		wantName string
+		wantOK   bool
+	}{
+		{"Ignoring unknown variable 'ABS_NOTCH_X' in file FilamentSamples.scad, line 186", SeverityWarning, "variable", "ABS_NOTCH_X", true},
+		{`Ignoring unknown variable "ABS_NOTCH_X" in file FilamentSamples.scad, line 186`, SeverityWarning, "variable", "ABS_NOTCH_X", true},
+		{"Ignoring unknown module 'Notchh' in file FilamentSamples.scad, line 186", SeverityWarning, "module", "Notchh", true},
+		{"Ignoring unknown function 'lenght' in file FilamentSamples.scad, line 12", SeverityWarning, "function", "lenght", true},
+		{"Normalized tree is growing past 200000 elements", SeverityWarning, "", "", false},
+		{`"unknown variable 'X'"`, SeverityEcho, "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.message, func(t *testing.T) {
+			d := Diagnostic{Severity: tt.severity, Message: tt.message}
+			kind, name, ok := d.UndefinedReference()
+			if kind != tt.wantKind || name != tt.wantName || ok != tt.wantOK {
+				t.Errorf("UndefinedReference() = (%q, %q, %v), want (%q, %q, %v)",
+					kind, name, ok, tt.wantKind, tt.wantName, tt.wantOK)
+			}
+		})
+	}
+}
@@ -1,21 +1,45 @@
 package openscad
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"runtime"
+	"strings"
+	"time"
 )
 
+// ErrNotFound is returned when no OpenSCAD binary can be located.
+var ErrNotFound = errors.New("OpenSCAD not found")
+
 type Executor struct {
 	OpenSCADPath string
 	ScadFile     string
 }
 
+// EnvOpenSCAD names an environment variable that overrides the platform
+// specific search for the OpenSCAD binary.
+const EnvOpenSCAD = "OPENSCAD"
+
 func NewExecutor(scadFile string) (*Executor, error) {
-	path, err := findOpenSCADPath()
-	if err != nil {
-		return nil, err
+	return NewExecutorWithPath(scadFile, "")
+}
+
+// NewExecutorWithPath creates an executor for an explicit OpenSCAD binary.
+// When openscadPath is empty the binary is located through $OPENSCAD or the
+// platform's standard install locations.
+func NewExecutorWithPath(scadFile, openscadPath string) (*Executor, error) {
+	path := openscadPath
+	if path == "" {
+		var err error
+		path, err = findOpenSCADPath()
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	return &Executor{
@@ -24,35 +48,64 @@ func NewExecutor(scadFile string) (*Executor, error) {
 	}, nil
 }
 
-func (e *Executor) GenerateSTL(outputPath string, args []string) error {
+// waitDelay bounds how long GenerateSTL waits for output pipes to close
+// after OpenSCAD has been killed.
+const waitDelay = 5 * time.Second
+
+// GenerateSTL renders the template to outputPath and returns the
+// diagnostics OpenSCAD printed. Output is captured per invocation so that
+// concurrent renders do not interleave on the terminal. If ctx is canceled or
+// times out, the OpenSCAD process group is killed and any partially written
+// output is removed.
+func (e *Executor) GenerateSTL(ctx context.Context, outputPath string, args []string) ([]Diagnostic, error) {
 	cmdArgs := []string{"-o", outputPath}
 	cmdArgs = append(cmdArgs, args...)
 	cmdArgs = append(cmdArgs, e.ScadFile)
 
-	cmd := exec.Command(e.OpenSCADPath, cmdArgs...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	var output bytes.Buffer
+	cmd := exec.CommandContext(ctx, e.OpenSCADPath, cmdArgs...)
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	cmd.WaitDelay = waitDelay
+	configureCommand(cmd)
+
+	err := cmd.Run()
+	diagnostics := ParseDiagnostics(output.Bytes())
 
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("OpenSCAD execution failed: %w", err)
+	if err != nil {
+		os.Remove(outputPath)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return diagnostics, fmt.Errorf("OpenSCAD execution stopped: %w", ctxErr)
+		}
+		if errs := Filter(diagnostics, SeverityError); len(errs) > 0 {
+			return diagnostics, fmt.Errorf("OpenSCAD execution failed: %w: %s", err, errs[0])
+		}
+		return diagnostics, fmt.Errorf("OpenSCAD execution failed: %w", err)
 	}
 
-	return nil
+	return diagnostics, nil
 }
 
 func findOpenSCADPath() (string, error) {
+	if path := os.Getenv(EnvOpenSCAD); path != "" {
+		if _, err := os.Stat(path); err != nil {
+			return "", fmt.Errorf("%w at $%s=%s", ErrNotFound, EnvOpenSCAD, path)
+		}
+		return path, nil
+	}
+
 	switch runtime.GOOS {
 	case "windows":
 		path := `C:\Program Files\OpenSCAD\openscad.exe`
 		if _, err := os.Stat(path); err == nil {
 			return path, nil
 		}
-		return "", fmt.Errorf("OpenSCAD not found at %s", path)
+		return "", fmt.Errorf("%w at %s", ErrNotFound, path)
 
 	case "linux":
 		path, err := exec.LookPath("openscad")
 		if err != nil {
-			return "", fmt.Errorf("OpenSCAD not found in PATH")
+			return "", fmt.Errorf("%w in PATH", ErrNotFound)
 		}
 		return path, nil
 
@@ -70,7 +123,7 @@ func findOpenSCADPath() (string, error) {
 
 		path, err := exec.LookPath("openscad")
 		if err != nil {
-			return "", fmt.Errorf("OpenSCAD not found in standard locations or PATH")
+			return "", fmt.Errorf("%w in standard locations or PATH", ErrNotFound)
 		}
 		return path, nil
 
@@ -87,10 +140,28 @@ func (e *Executor) CheckAvailable() error {
 }
 
 func (e *Executor) GetVersion() (string, error) {
+	// OpenSCAD prints its version to stderr.
 	cmd := exec.Command(e.OpenSCADPath, "--version")
-	output, err := cmd.Output()
+	output, err := cmd.CombinedOutput()
 	if err != nil {
 		return "", fmt.Errorf("failed to get OpenSCAD version: %w", err)
 	}
 	return string(output), nil
-}
\ No newline at end of file
+}
+
+// CheckSyntax parses the .scad file without rendering it by exporting its
+// abstract syntax tree to a temporary file.
+func (e *Executor) CheckSyntax() error {
+	tempDir, err := os.MkdirTemp("", "filament-samples-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tempDir)
+
+	cmd := exec.Command(e.OpenSCADPath, "-o", filepath.Join(tempDir, "template.ast"), e.ScadFile)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w: %s", e.ScadFile, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
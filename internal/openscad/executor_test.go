@@ -1,9 +1,12 @@
 package openscad
 
 import (
+	"context"
+	"errors"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
 )
 
@@ -19,7 +22,7 @@ func TestNewExecutor(t *testing.T) {
 	executor, err := NewExecutor(scadFile)
 	if err != nil {
 		// Check if it's an OpenSCAD availability issue
-		if runtime.GOOS == "darwin" && err.Error() == "OpenSCAD not found in standard locations or PATH" {
+		if errors.Is(err, ErrNotFound) {
 			t.Skip("OpenSCAD not available for testing")
 		}
 		t.Fatalf("NewExecutor() error = %v", err)
@@ -38,6 +41,42 @@ func TestNewExecutor(t *testing.T) {
 	}
 }
 
+func TestNewExecutorWithPath(t *testing.T) {
+	tempDir := t.TempDir()
+	scadFile := filepath.Join(tempDir, "test.scad")
+	explicit := filepath.Join(tempDir, "explicit-openscad")
+	fromEnv := filepath.Join(tempDir, "env-openscad")
+	for _, path := range []string{explicit, fromEnv} {
+		if err := os.WriteFile(path, []byte("fake"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Setenv(EnvOpenSCAD, fromEnv)
+
+	executor, err := NewExecutorWithPath(scadFile, explicit)
+	if err != nil {
+		t.Fatalf("NewExecutorWithPath() error = %v", err)
+	}
+	if executor.OpenSCADPath != explicit {
+		t.Errorf("explicit path should win, got %s", executor.OpenSCADPath)
+	}
+
+	executor, err = NewExecutorWithPath(scadFile, "")
+	if err != nil {
+		t.Fatalf("NewExecutorWithPath() error = %v", err)
+	}
+	if executor.OpenSCADPath != fromEnv {
+		t.Errorf("$%s should override detection, got %s", EnvOpenSCAD, executor.OpenSCADPath)
+	}
+
+	t.Setenv(EnvOpenSCAD, filepath.Join(tempDir, "missing"))
+	_, err = NewExecutorWithPath(scadFile, "")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a missing $%s, got %v", EnvOpenSCAD, err)
+	}
+}
+
 func TestFindOpenSCADPath(t *testing.T) {
 	path, err := findOpenSCADPath()
 	
@@ -129,7 +168,7 @@ func TestExecutor_GenerateSTL(t *testing.T) {
 	}
 
 	args := []string{"-D", "TEST=1"}
-	err := executor.GenerateSTL(outputFile, args)
+	_, err := executor.GenerateSTL(context.Background(), outputFile, args)
 	if err != nil {
 		t.Errorf("GenerateSTL() error = %v", err)
 	}
@@ -319,8 +358,47 @@ func TestExecutor_GenerateSTL_Error(t *testing.T) {
 	}
 
 	args := []string{"-D", "TEST=1"}
-	err := executor.GenerateSTL(outputFile, args)
+	_, err := executor.GenerateSTL(context.Background(), outputFile, args)
 	if err == nil {
 		t.Error("GenerateSTL() should return error when OpenSCAD fails")
 	}
-}
\ No newline at end of file
+}
+func TestExecutor_CheckSyntax(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake OpenSCAD script requires a POSIX shell")
+	}
+
+	tempDir := t.TempDir()
+	scadFile := filepath.Join(tempDir, "test.scad")
+	if err := os.WriteFile(scadFile, []byte("cube([10,10,10]);"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Fake OpenSCAD that fails when the template contains "syntax error"
+	fakeOpenSCAD := filepath.Join(tempDir, "fake_openscad")
+	script := "#!/bin/sh\nif grep -q 'syntax error' \"$3\"; then echo 'ERROR: Parser error in file test.scad, line 1: syntax error' >&2; exit 1; fi\ntouch \"$2\"\n"
+	if err := os.WriteFile(fakeOpenSCAD, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	executor := &Executor{
+		OpenSCADPath: fakeOpenSCAD,
+		ScadFile:     scadFile,
+	}
+
+	if err := executor.CheckSyntax(); err != nil {
+		t.Errorf("CheckSyntax() error = %v", err)
+	}
+
+	if err := os.WriteFile(scadFile, []byte("syntax error"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := executor.CheckSyntax()
+	if err == nil {
+		t.Fatal("CheckSyntax() should fail for an invalid template")
+	}
+	if !strings.Contains(err.Error(), "Parser error") {
+		t.Errorf("CheckSyntax() error should include OpenSCAD output, got %v", err)
+	}
+}
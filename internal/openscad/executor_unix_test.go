@@ -0,0 +1,129 @@
+//go:build !windows
+
+package openscad
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestExecutor_GenerateSTL_Cancel(t *testing.T) {
+	tempDir := t.TempDir()
+	scadFile := filepath.Join(tempDir, "test.scad")
+	outputFile := filepath.Join(tempDir, "output.stl")
+	pidFile := filepath.Join(tempDir, "child.pid")
+
+	if err := os.WriteFile(scadFile, []byte("cube([10,10,10]);"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Fake OpenSCAD that writes a partial file, starts a child and hangs
+	fakeOpenSCAD := filepath.Join(tempDir, "fake_openscad")
+	script := "#!/bin/sh\necho 'solid partial' > \"$2\"\nsleep 30 &\necho $! > " + pidFile + "\nwait\n"
+	if err := os.WriteFile(fakeOpenSCAD, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	executor := &Executor{
+		OpenSCADPath: fakeOpenSCAD,
+		ScadFile:     scadFile,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := executor.GenerateSTL(ctx, outputFile, nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("GenerateSTL() error = %v, want deadline exceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("GenerateSTL() took %s after cancellation", elapsed)
+	}
+
+	if _, err := os.Stat(outputFile); !os.IsNotExist(err) {
+		t.Error("partial output should be removed after cancellation")
+	}
+
+	pid, err := os.ReadFile(pidFile)
+	if err != nil {
+		t.Fatalf("child pid not recorded: %v", err)
+	}
+	child, err := strconv.Atoi(strings.TrimSpace(string(pid)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The child may take a moment to be reaped after SIGKILL.
+	deadline := time.Now().Add(2 * time.Second)
+	for processAlive(child) {
+		if time.Now().After(deadline) {
+			syscall.Kill(child, syscall.SIGKILL)
+			t.Fatal("child process survived cancellation")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// processAlive reports whether pid is running. Killed children of the fake
+// OpenSCAD are reparented and may linger as zombies until reaped, which
+// counts as dead here.
+func processAlive(pid int) bool {
+	if syscall.Kill(pid, 0) != nil {
+		return false
+	}
+	stat, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return true
+	}
+	fields := strings.Fields(string(stat))
+	return len(fields) < 3 || fields[2] != "Z"
+}
+
+func TestExecutor_GenerateSTL_Diagnostics(t *testing.T) {
+	tempDir := t.TempDir()
+	scadFile := filepath.Join(tempDir, "test.scad")
+	outputFile := filepath.Join(tempDir, "output.stl")
+
+	if err := os.WriteFile(scadFile, []byte("cube([10,10,10]);"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fakeOpenSCAD := filepath.Join(tempDir, "fake_openscad")
+	script := "#!/bin/sh\necho 'ECHO: 1'\necho \"WARNING: Ignoring unknown variable 'X' in file test.scad, line 2\" >&2\n" +
+		"if [ \"$4\" = 'FAIL=1' ]; then echo 'ERROR: Assertion failed in file test.scad, line 5' >&2; exit 1; fi\ntouch \"$2\"\n"
+	if err := os.WriteFile(fakeOpenSCAD, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	executor := &Executor{
+		OpenSCADPath: fakeOpenSCAD,
+		ScadFile:     scadFile,
+	}
+
+	diagnostics, err := executor.GenerateSTL(context.Background(), outputFile, []string{"-D", "OK=1"})
+	if err != nil {
+		t.Fatalf("GenerateSTL() error = %v", err)
+	}
+	if len(diagnostics) != 2 || diagnostics[1].Severity != SeverityWarning || diagnostics[1].Line != 2 {
+		t.Errorf("unexpected diagnostics: %+v", diagnostics)
+	}
+
+	diagnostics, err = executor.GenerateSTL(context.Background(), outputFile, []string{"-D", "FAIL=1"})
+	if err == nil {
+		t.Fatal("GenerateSTL() should fail when OpenSCAD exits non-zero")
+	}
+	if !strings.Contains(err.Error(), "ERROR: Assertion failed") {
+		t.Errorf("error should include the OpenSCAD error, got %v", err)
+	}
+	if len(Filter(diagnostics, SeverityError)) != 1 {
+		t.Errorf("diagnostics should be returned on failure, got %+v", diagnostics)
+	}
+}
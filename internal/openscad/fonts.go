@@ -0,0 +1,134 @@
+package openscad
+
+import (
+	"bufio"
+	"bytes"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+var fontAssignment = regexp.MustCompile(`^\s*FONT\s*=\s*"([^"]*)"\s*;`)
+
+// TemplateFont returns the font spec assigned to FONT in a .scad file.
+func TemplateFont(scadFile string) (string, error) {
+	file, err := os.Open(scadFile)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	font := ""
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if m := fontAssignment.FindStringSubmatch(scanner.Text()); m != nil {
+			font = m[1]
+		}
+	}
+	return font, scanner.Err()
+}
+
+// SplitFontSpec splits an OpenSCAD font spec like "Liberation Sans:style=Bold"
+// into its family and style.
+func SplitFontSpec(spec string) (family, style string) {
+	parts := strings.Split(spec, ":")
+	family = strings.TrimSpace(parts[0])
+	for _, part := range parts[1:] {
+		key, value, ok := strings.Cut(part, "=")
+		if ok && strings.TrimSpace(key) == "style" {
+			style = strings.TrimSpace(value)
+		}
+	}
+	return family, style
+}
+
+// FontInstalled reports whether a font matching spec is available to
+// OpenSCAD. It asks fontconfig when fc-list is installed, which is what
+// OpenSCAD itself uses, and otherwise looks for a matching font file in the
+// platform's font directories.
+func FontInstalled(spec string) (bool, error) {
+	if fcList, err := exec.LookPath("fc-list"); err == nil {
+		out, err := exec.Command(fcList, spec, "family").Output()
+		if err != nil {
+			return false, err
+		}
+		return len(bytes.TrimSpace(out)) > 0, nil
+	}
+
+	family, style := SplitFontSpec(spec)
+	return findFontFile(fontDirs(), family, style), nil
+}
+
+func fontDirs() []string {
+	home, _ := os.UserHomeDir()
+
+	switch runtime.GOOS {
+	case "windows":
+		return []string{
+			filepath.Join(os.Getenv("WINDIR"), "Fonts"),
+			filepath.Join(os.Getenv("LOCALAPPDATA"), "Microsoft", "Windows", "Fonts"),
+		}
+	case "darwin":
+		return []string{
+			"/System/Library/Fonts",
+			"/Library/Fonts",
+			filepath.Join(home, "Library", "Fonts"),
+		}
+	default:
+		return []string{
+			"/usr/share/fonts",
+			"/usr/local/share/fonts",
+			filepath.Join(home, ".fonts"),
+			filepath.Join(home, ".local", "share", "fonts"),
+		}
+	}
+}
+
+// findFontFile matches font file names such as LiberationSans-Bold.ttf
+// against a family and style.
+func findFontFile(dirs []string, family, style string) bool {
+	wantFamily := normalizeFontName(family)
+	wantStyle := normalizeFontName(style)
+	if wantStyle == "regular" {
+		wantStyle = ""
+	}
+
+	found := false
+	for _, dir := range dirs {
+		_ = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return nil
+			}
+
+			ext := strings.ToLower(filepath.Ext(path))
+			if ext != ".ttf" && ext != ".otf" && ext != ".ttc" {
+				return nil
+			}
+
+			name := normalizeFontName(strings.TrimSuffix(d.Name(), filepath.Ext(path)))
+			if strings.HasPrefix(name, wantFamily) && strings.Contains(name[len(wantFamily):], wantStyle) {
+				found = true
+				return fs.SkipAll
+			}
+			return nil
+		})
+		if found {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeFontName(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
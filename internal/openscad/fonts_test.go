@@ -0,0 +1,81 @@
+package openscad
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTemplateFont(t *testing.T) {
+	scadFile := filepath.Join(t.TempDir(), "test.scad")
+	content := `// FONT = "Arial Rounded MT Bold:style=Regular";
+FONT = "Liberation Sans:style=Bold";
+TEXT_X=4.0;
+`
+	if err := os.WriteFile(scadFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	font, err := TemplateFont(scadFile)
+	if err != nil {
+		t.Fatalf("TemplateFont() error = %v", err)
+	}
+	if font != "Liberation Sans:style=Bold" {
+		t.Errorf("TemplateFont() = %q, want %q", font, "Liberation Sans:style=Bold")
+	}
+
+	if _, err := TemplateFont(filepath.Join(t.TempDir(), "missing.scad")); err == nil {
+		t.Error("TemplateFont() should fail for a missing file")
+	}
+}
+
+func TestSplitFontSpec(t *testing.T) {
+	tests := []struct {
+		spec       string
+		wantFamily string
+		wantStyle  string
+	}{
+		{spec: "Liberation Sans:style=Bold", wantFamily: "Liberation Sans", wantStyle: "Bold"},
+		{spec: "Liberation Sans", wantFamily: "Liberation Sans"},
+		{spec: "Arial Rounded MT Bold: style = Regular", wantFamily: "Arial Rounded MT Bold", wantStyle: "Regular"},
+	}
+
+	for _, tt := range tests {
+		family, style := SplitFontSpec(tt.spec)
+		if family != tt.wantFamily || style != tt.wantStyle {
+			t.Errorf("SplitFontSpec(%q) = %q, %q, want %q, %q", tt.spec, family, style, tt.wantFamily, tt.wantStyle)
+		}
+	}
+}
+
+func TestFindFontFile(t *testing.T) {
+	fontDir := t.TempDir()
+	nested := filepath.Join(fontDir, "truetype", "liberation")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"LiberationSans-Bold.ttf", "DejaVuSans.ttf", "README"} {
+		if err := os.WriteFile(filepath.Join(nested, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dirs := []string{filepath.Join(fontDir, "missing"), fontDir}
+
+	tests := []struct {
+		family string
+		style  string
+		want   bool
+	}{
+		{family: "Liberation Sans", style: "Bold", want: true},
+		{family: "Liberation Sans", style: "Italic", want: false},
+		{family: "DejaVu Sans", style: "Regular", want: true},
+		{family: "Arial", style: "", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := findFontFile(dirs, tt.family, tt.style); got != tt.want {
+			t.Errorf("findFontFile(%q, %q) = %v, want %v", tt.family, tt.style, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,18 @@
+//go:build !windows
+
+package openscad
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// configureCommand starts OpenSCAD in its own process group so that
+// cancellation kills any children it spawned, and so that a Ctrl-C in the
+// terminal is handled by us rather than delivered to OpenSCAD directly.
+func configureCommand(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
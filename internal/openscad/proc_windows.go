@@ -0,0 +1,9 @@
+//go:build windows
+
+package openscad
+
+import "os/exec"
+
+// configureCommand relies on the default cancellation, which kills the
+// OpenSCAD process. OpenSCAD does not spawn children on Windows.
+func configureCommand(cmd *exec.Cmd) {}
@@ -0,0 +1,87 @@
+package openscad
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ErrInvalidSTL is returned by ValidateSTL for files that are empty,
+// truncated or not STL at all.
+var ErrInvalidSTL = errors.New("invalid STL file")
+
+// ValidateSTL checks that path holds a complete ASCII or binary STL file
+// with at least one facet, as OpenSCAD writes them.
+func ValidateSTL(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return fmt.Errorf("%w: file is empty", ErrInvalidSTL)
+	}
+
+	// Binary STL headers may start with "solid" too, so a file whose size
+	// matches its binary facet count is taken as binary first.
+	if facets, ok := binaryFacets(data); ok {
+		if facets == 0 {
+			return fmt.Errorf("%w: no facets", ErrInvalidSTL)
+		}
+		return nil
+	}
+
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("solid")) {
+		return fmt.Errorf("%w: neither ASCII nor a complete binary STL", ErrInvalidSTL)
+	}
+	return validateASCII(data)
+}
+
+// binaryFacets returns the facet count of a binary STL whose size matches
+// the count in its header.
+func binaryFacets(data []byte) (uint32, bool) {
+	const headerSize, facetSize = 84, 50
+	if len(data) < headerSize {
+		return 0, false
+	}
+	count := binary.LittleEndian.Uint32(data[80:headerSize])
+	return count, int64(len(data)) == headerSize+facetSize*int64(count)
+}
+
+func validateASCII(data []byte) error {
+	var facets, endFacets, vertices int
+	last := ""
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		last = line
+		switch keyword, _, _ := strings.Cut(line, " "); keyword {
+		case "facet":
+			facets++
+		case "endfacet":
+			endFacets++
+		case "vertex":
+			vertices++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSTL, err)
+	}
+
+	switch {
+	case !strings.HasPrefix(last, "endsolid"):
+		return fmt.Errorf("%w: missing endsolid, the file is truncated", ErrInvalidSTL)
+	case facets == 0:
+		return fmt.Errorf("%w: no facets", ErrInvalidSTL)
+	case facets != endFacets || vertices != 3*facets:
+		return fmt.Errorf("%w: %d facets with %d endfacets and %d vertices", ErrInvalidSTL, facets, endFacets, vertices)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package openscad
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const asciiFacet = `  facet normal 0 0 1
+    outer loop
+      vertex 0 0 0
+      vertex 1 0 0
+      vertex 0 1 0
+    endloop
+  endfacet
+`
+
+func binarySTL(facets uint32, extra int) []byte {
+	data := make([]byte, 84+50*int(facets)+extra)
+	copy(data, "solid binary header")
+	binary.LittleEndian.PutUint32(data[80:], facets)
+	return data
+}
+
+func TestValidateSTL(t *testing.T) {
+	tests := []struct {
+		name    string
+		content []byte
+		wantErr bool
+	}{
+		{name: "ascii", content: []byte("solid OpenSCAD_Model\n" + asciiFacet + asciiFacet + "endsolid OpenSCAD_Model\n")},
+		{name: "binary", content: binarySTL(2, 0)},
+		{name: "empty", content: nil, wantErr: true},
+		{name: "truncated ascii", content: []byte("solid OpenSCAD_Model\n" + asciiFacet + "  facet normal 0 0 1\n    outer loop\n      vertex 0 0"), wantErr: true},
+		{name: "ascii without facets", content: []byte("solid x\nendsolid x\n"), wantErr: true},
+		{name: "ascii missing vertex", content: []byte("solid x\n" + strings.Replace(asciiFacet, "      vertex 0 1 0\n", "", 1) + "endsolid x\n"), wantErr: true},
+		{name: "truncated binary", content: binarySTL(2, -10), wantErr: true},
+		{name: "binary without facets", content: binarySTL(0, 0), wantErr: true},
+		{name: "not stl", content: []byte("ERROR: something went wrong"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "out.stl")
+			if err := os.WriteFile(path, tt.content, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			err := ValidateSTL(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateSTL() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidSTL) {
+				t.Errorf("error should wrap ErrInvalidSTL, got %v", err)
+			}
+		})
+	}
+
+	if err := ValidateSTL(filepath.Join(t.TempDir(), "missing.stl")); err == nil {
+		t.Error("ValidateSTL() on a missing file should fail")
+	}
+}
@@ -0,0 +1,43 @@
+package openscad
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/guntharp/go-filamentsamples/pkg/scad"
+)
+
+// TemplateSchema reads the parameters a .scad file defines, which are the
+// variables a -D option can override.
+func TemplateSchema(scadFile string) (*scad.Schema, error) {
+	src, err := os.ReadFile(scadFile)
+	if err != nil {
+		return nil, err
+	}
+	schema, err := scad.ParseTemplate(src)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", scadFile, err)
+	}
+	return schema, nil
+}
+
+// ParameterFilePath returns the Customizer parameter file OpenSCAD keeps
+// for scadFile: the .json file of the same name next to it.
+func ParameterFilePath(scadFile string) string {
+	return strings.TrimSuffix(scadFile, filepath.Ext(scadFile)) + ".json"
+}
+
+// LoadParameterFile reads the Customizer parameter sets saved in path.
+func LoadParameterFile(path string) (*scad.ParameterFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := scad.ParseParameterFile(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return f, nil
+}
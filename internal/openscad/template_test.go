@@ -0,0 +1,60 @@
+package openscad
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTemplateSchema(t *testing.T) {
+	scadFile := filepath.Join(t.TempDir(), "test.scad")
+	content := "CARD_THICKNESS=2.2;\nmodule Card() { INNER=1; cube(INNER); }\n"
+	if err := os.WriteFile(scadFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	schema, err := TemplateSchema(scadFile)
+	if err != nil {
+		t.Fatalf("TemplateSchema() error = %v", err)
+	}
+	if len(schema.Params) != 1 || schema.Params[0].Name != "CARD_THICKNESS" {
+		t.Errorf("Params = %+v, want only CARD_THICKNESS", schema.Params)
+	}
+
+	if err := os.WriteFile(scadFile, []byte("FONT=\"Liberation;\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := TemplateSchema(scadFile); err == nil {
+		t.Error("TemplateSchema() should fail for an unterminated string")
+	}
+
+	if _, err := TemplateSchema(filepath.Join(t.TempDir(), "missing.scad")); err == nil {
+		t.Error("TemplateSchema() should fail for a missing file")
+	}
+}
+
+func TestLoadParameterFile(t *testing.T) {
+	scadFile := filepath.Join(t.TempDir(), "FilamentSamples.scad")
+	path := ParameterFilePath(scadFile)
+	if want := filepath.Join(filepath.Dir(scadFile), "FilamentSamples.json"); path != want {
+		t.Errorf("ParameterFilePath() = %s, want %s", path, want)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"parameterSets": {"Default": {"$fn": "50"}}, "fileFormatVersion": "1"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := LoadParameterFile(path)
+	if err != nil {
+		t.Fatalf("LoadParameterFile() error = %v", err)
+	}
+	if f.ParameterSets["Default"]["$fn"] != "50" {
+		t.Errorf("ParameterSets = %v", f.ParameterSets)
+	}
+
+	if err := os.WriteFile(path, []byte(`{}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadParameterFile(path); err == nil {
+		t.Error("LoadParameterFile() should fail without parameterSets")
+	}
+}
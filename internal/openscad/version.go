@@ -0,0 +1,53 @@
+package openscad
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// MinimumVersion is the oldest OpenSCAD release known to render the template.
+var MinimumVersion = Version{Year: 2019, Month: 5}
+
+// Version is an OpenSCAD release such as 2021.01 or a nightly like 2024.12.06.
+type Version struct {
+	Year  int
+	Month int
+	Day   int
+}
+
+var versionPattern = regexp.MustCompile(`(\d{4})\.(\d{1,2})(?:\.(\d{1,2}))?`)
+
+// ParseVersion extracts the version from the output of "openscad --version".
+func ParseVersion(output string) (Version, error) {
+	m := versionPattern.FindStringSubmatch(output)
+	if m == nil {
+		return Version{}, fmt.Errorf("no OpenSCAD version found in %q", output)
+	}
+
+	v := Version{}
+	v.Year, _ = strconv.Atoi(m[1])
+	v.Month, _ = strconv.Atoi(m[2])
+	if m[3] != "" {
+		v.Day, _ = strconv.Atoi(m[3])
+	}
+	return v, nil
+}
+
+// Less reports whether v is an older release than other.
+func (v Version) Less(other Version) bool {
+	if v.Year != other.Year {
+		return v.Year < other.Year
+	}
+	if v.Month != other.Month {
+		return v.Month < other.Month
+	}
+	return v.Day < other.Day
+}
+
+func (v Version) String() string {
+	if v.Day != 0 {
+		return fmt.Sprintf("%d.%02d.%02d", v.Year, v.Month, v.Day)
+	}
+	return fmt.Sprintf("%d.%02d", v.Year, v.Month)
+}
@@ -0,0 +1,48 @@
+package openscad
+
+import "testing"
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  string
+		want    Version
+		wantErr bool
+	}{
+		{name: "release", output: "OpenSCAD version 2021.01\n", want: Version{Year: 2021, Month: 1}},
+		{name: "nightly", output: "OpenSCAD version 2024.12.06\n", want: Version{Year: 2024, Month: 12, Day: 6}},
+		{name: "short form", output: "OpenSCAD 2019.05", want: Version{Year: 2019, Month: 5}},
+		{name: "garbage", output: "command not found", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseVersion(tt.output)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseVersion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseVersion() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVersion_Less(t *testing.T) {
+	older := Version{Year: 2019, Month: 5}
+	newer := Version{Year: 2021, Month: 1}
+	nightly := Version{Year: 2021, Month: 1, Day: 3}
+
+	if !older.Less(newer) || newer.Less(older) {
+		t.Error("2019.05 should be older than 2021.01")
+	}
+	if !newer.Less(nightly) {
+		t.Error("2021.01 should be older than 2021.01.03")
+	}
+	if newer.Less(newer) {
+		t.Error("a version should not be older than itself")
+	}
+	if nightly.String() != "2021.01.03" || older.String() != "2019.05" {
+		t.Errorf("unexpected String() output: %s, %s", nightly, older)
+	}
+}
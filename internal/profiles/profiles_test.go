@@ -0,0 +1,128 @@
+package profiles
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/guntharp/go-filamentsamples/pkg/models"
+)
+
+func TestLibrary_Lookup(t *testing.T) {
+	lib := New([]Profile{
+		{Brand: "Prusament", Type: "PETG", Hotend: "250", Bed: "85"},
+		{Type: "PLA", Hotend: "200-215", Bed: "55"},
+	})
+
+	tests := []struct {
+		name       string
+		brand, typ string
+		wantHotend string
+		wantOK     bool
+	}{
+		{name: "brand and type", brand: "Bambu Labs", typ: "PLA Matte", wantHotend: "190-230", wantOK: true},
+		{name: "case and spacing", brand: "bambu  labs", typ: "pla matte", wantHotend: "190-230", wantOK: true},
+		{name: "user brand profile", brand: "Prusament", typ: "PETG", wantHotend: "250", wantOK: true},
+		{name: "type fallback", brand: "Polymaker", typ: "PETG", wantHotend: "230-250", wantOK: true},
+		{name: "user overrides built-in type", brand: "Polymaker", typ: "PLA", wantHotend: "200-215", wantOK: true},
+		{name: "shorter type", brand: "Elegoo", typ: "PLA Silk", wantHotend: "200-215", wantOK: true},
+		{name: "brand type before shorter type", brand: "Bambu Labs", typ: "PLA Silk Dual", wantHotend: "210-240", wantOK: true},
+		{name: "unknown", brand: "Test", typ: "Wood", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, ok := lib.Lookup(tt.brand, tt.typ)
+			if ok != tt.wantOK || p.Hotend != tt.wantHotend {
+				t.Errorf("Lookup(%q, %q) = %+v, %v; want hotend %q, %v", tt.brand, tt.typ, p, ok, tt.wantHotend, tt.wantOK)
+			}
+		})
+	}
+
+	var empty *Library
+	if _, ok := empty.Lookup("Bambu Labs", "PLA"); ok {
+		t.Error("a nil library should find nothing")
+	}
+}
+
+func TestLibrary_Fill(t *testing.T) {
+	lib := New(nil)
+
+	tests := []struct {
+		name         string
+		sample       models.FilamentSample
+		wantOK       bool
+		wantHotend   string
+		wantBed      string
+		wantInferred []string
+	}{
+		{
+			name:         "both empty",
+			sample:       models.FilamentSample{Brand: "Bambu Labs", Type: "PETG", Color: "Red"},
+			wantOK:       true,
+			wantHotend:   "240-270",
+			wantBed:      "65-75",
+			wantInferred: []string{models.FieldTempHotend, models.FieldTempBed},
+		},
+		{
+			name:         "bed given",
+			sample:       models.FilamentSample{Brand: "Bambu Labs", Type: "PETG", Color: "Red", TempBed: "80"},
+			wantOK:       true,
+			wantHotend:   "240-270",
+			wantBed:      "80",
+			wantInferred: []string{models.FieldTempHotend},
+		},
+		{
+			name:       "both given",
+			sample:     models.FilamentSample{Brand: "Test", Type: "Wood", Color: "Oak", TempHotend: "200", TempBed: "60"},
+			wantOK:     true,
+			wantHotend: "200",
+			wantBed:    "60",
+		},
+		{
+			name:   "no profile",
+			sample: models.FilamentSample{Brand: "Test", Type: "Wood", Color: "Oak"},
+			wantOK: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sample := tt.sample
+			if ok := lib.Fill(&sample); ok != tt.wantOK {
+				t.Fatalf("Fill() = %v, want %v", ok, tt.wantOK)
+			}
+			if sample.TempHotend != tt.wantHotend || sample.TempBed != tt.wantBed {
+				t.Errorf("temperatures = %q, %q; want %q, %q", sample.TempHotend, sample.TempBed, tt.wantHotend, tt.wantBed)
+			}
+			if !reflect.DeepEqual(sample.Inferred, tt.wantInferred) {
+				t.Errorf("Inferred = %v, want %v", sample.Inferred, tt.wantInferred)
+			}
+		})
+	}
+}
+
+func TestProfile_Validate(t *testing.T) {
+	tests := []struct {
+		profile Profile
+		wantErr bool
+	}{
+		{Profile{Type: "PLA", Hotend: "200", Bed: "60"}, false},
+		{Profile{Brand: "Test", Type: "PLA", Hotend: "200"}, false},
+		{Profile{Hotend: "200", Bed: "60"}, true},
+		{Profile{Type: "PLA", Hotend: "hot"}, true},
+	}
+
+	for _, tt := range tests {
+		if err := tt.profile.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate(%+v) error = %v, wantErr %v", tt.profile, err, tt.wantErr)
+		}
+	}
+}
+
+func TestBuiltinProfiles(t *testing.T) {
+	for _, p := range builtin {
+		if err := p.Validate(); err != nil {
+			t.Errorf("built-in profile %s: %v", p.name(), err)
+		}
+	}
+}
@@ -0,0 +1,12 @@
+// Package textutil formats text for messages.
+package textutil
+
+import "fmt"
+
+// Plural returns n followed by noun, with an s unless n is 1.
+func Plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
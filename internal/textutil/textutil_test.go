@@ -0,0 +1,20 @@
+package textutil
+
+import "testing"
+
+func TestPlural(t *testing.T) {
+	tests := []struct {
+		n    int
+		want string
+	}{
+		{0, "0 samples"},
+		{1, "1 sample"},
+		{2, "2 samples"},
+	}
+
+	for _, tt := range tests {
+		if got := Plural(tt.n, "sample"); got != tt.want {
+			t.Errorf("Plural(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
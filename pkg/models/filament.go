@@ -2,8 +2,12 @@ package models
 
 import (
 	"errors"
+	"fmt"
+	"sort"
 	"strconv"
 	"strings"
+
+	"github.com/guntharp/go-filamentsamples/pkg/scad"
 )
 
 type FilamentSample struct {
@@ -15,88 +19,442 @@ type FilamentSample struct {
 	BrandSize   string
 	TypeSize    string
 	ColorSize   string
+
+	// Source names the input the sample was read from, such as a file
+	// path, and Line is its line there, or 0.
+	Source string
+	Line   int
+	// Params holds extra template parameters, from input columns that
+	// name no field above or from a params table, keyed by name.
+	Params map[string]string
+	// ParameterSet names the Customizer parameter set the sample starts
+	// from, or is empty for the run's set.
+	ParameterSet string
+	// Notes is free text about the sample; it is not printed.
+	Notes string
+	// Quantity records how many cards of the sample are wanted, 0 when
+	// not given. It is informational and does not change the output.
+	Quantity int
+	// Inferred lists the fields that were empty in the input and filled in
+	// from a material profile, such as FieldTempHotend.
+	Inferred []string
+}
+
+// Field names used in FieldError and FilamentSample.Inferred.
+const (
+	FieldBrand      = "Brand"
+	FieldType       = "Type"
+	FieldColor      = "Color"
+	FieldTempHotend = "TempHotend"
+	FieldTempBed    = "TempBed"
+	FieldBrandSize  = "BrandSize"
+	FieldTypeSize   = "TypeSize"
+	FieldColorSize  = "ColorSize"
+	FieldQuantity   = "Quantity"
+	FieldParams     = "Params"
+	// FieldParameterSet is the Customizer parameter set a sample names.
+	FieldParameterSet = "ParameterSet"
+)
+
+// IsInferred reports whether field was filled in from a material profile.
+func (f *FilamentSample) IsInferred(field string) bool {
+	for _, name := range f.Inferred {
+		if name == field {
+			return true
+		}
+	}
+	return false
+}
+
+// FieldError is an invalid value of one sample field.
+type FieldError struct {
+	// Field is the name of the field, such as FieldTempHotend.
+	Field string
+	Value string
+	Err   error
+}
+
+func (e *FieldError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
 }
 
+// Unjoin splits an error joined with errors.Join, such as one returned by
+// Validate, into its parts. Any other error is returned on its own.
+func Unjoin(err error) []error {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return joined.Unwrap()
+	}
+	return []error{err}
+}
+
+// Location returns the "source: line n: " prefix of messages about the
+// sample, leaving out what is unknown.
+func (f *FilamentSample) Location() string {
+	prefix := ""
+	if f.Source != "" {
+		prefix = f.Source + ": "
+	}
+	if f.Line > 0 {
+		prefix += fmt.Sprintf("line %d: ", f.Line)
+	}
+	return prefix
+}
+
+// Validate checks a sample after empty temperatures have been filled in from
+// material profiles, so a missing temperature means no profile matched.
+// Every invalid field is reported as a *FieldError, joined with errors.Join.
 func (f *FilamentSample) Validate() error {
+	var errs []error
+	fail := func(field, value string, err error) {
+		errs = append(errs, &FieldError{Field: field, Value: value, Err: err})
+	}
+
 	if f.Brand == "" {
-		return errors.New("brand is required")
+		fail(FieldBrand, f.Brand, errors.New("brand is required"))
 	}
 	if f.Type == "" {
-		return errors.New("type is required")
+		fail(FieldType, f.Type, errors.New("type is required"))
 	}
 	if f.Color == "" {
-		return errors.New("color is required")
+		fail(FieldColor, f.Color, errors.New("color is required"))
+	}
+	// Brand, type and color make up the output filename.
+	for _, name := range []struct{ field, label, value string }{
+		{FieldBrand, "brand", f.Brand},
+		{FieldType, "type", f.Type},
+		{FieldColor, "color", f.Color},
+	} {
+		if strings.ContainsAny(name.value, `/\`) {
+			fail(name.field, name.value, fmt.Errorf("%s must not contain / or \\, got %q", name.label, name.value))
+		}
 	}
+
 	if f.TempHotend == "" {
-		return errors.New("hotend temperature is required")
+		fail(FieldTempHotend, f.TempHotend, errors.New("hotend temperature is required: no material profile matches the brand and type"))
+	} else if err := f.validateTemperature(f.TempHotend); err != nil {
+		fail(FieldTempHotend, f.TempHotend, err)
 	}
 	if f.TempBed == "" {
-		return errors.New("bed temperature is required")
+		fail(FieldTempBed, f.TempBed, errors.New("bed temperature is required: no material profile matches the brand and type"))
+	} else if err := f.validateTemperature(f.TempBed); err != nil {
+		fail(FieldTempBed, f.TempBed, err)
 	}
-	
-	if err := f.validateTemperature(f.TempHotend); err != nil {
-		return err
+
+	if f.Quantity < 0 {
+		fail(FieldQuantity, strconv.Itoa(f.Quantity), fmt.Errorf("quantity must not be negative, got %d", f.Quantity))
 	}
-	if err := f.validateTemperature(f.TempBed); err != nil {
-		return err
+
+	for _, size := range f.sizes() {
+		if size.value == "" {
+			continue
+		}
+		if _, err := scad.ParseNumber(size.value); err != nil {
+			fail(size.field, size.value, fmt.Errorf("%s must be a number, got %q", size.label, size.value))
+		}
 	}
-	
-	return nil
+
+	for _, name := range f.ParamNames() {
+		switch {
+		case !scad.ValidName(name):
+			fail(FieldParams, name, fmt.Errorf("invalid parameter name %q", name))
+		case f.reserved(name):
+			fail(FieldParams, name, fmt.Errorf("parameter %s is set from the sample's own columns", name))
+		}
+	}
+
+	return errors.Join(errs...)
 }
 
 func (f *FilamentSample) validateTemperature(temp string) error {
-	if strings.Contains(temp, "-") {
-		parts := strings.Split(temp, "-")
-		if len(parts) != 2 {
-			return errors.New("invalid temperature range format")
+	_, err := ParseTemperature(temp)
+	return err
+}
+
+// HotendRange returns the parsed hotend temperature.
+func (f *FilamentSample) HotendRange() (TemperatureRange, error) {
+	return ParseTemperature(f.TempHotend)
+}
+
+// BedRange returns the parsed bed temperature.
+func (f *FilamentSample) BedRange() (TemperatureRange, error) {
+	return ParseTemperature(f.TempBed)
+}
+
+// NormalizeTemperatures rewrites TempHotend and TempBed in the canonical
+// Celsius syntax, so 210–240, 410-464°F and 225±15 all become 210-240.
+// Temperatures that do not parse are left as they are.
+func (f *FilamentSample) NormalizeTemperatures() {
+	if r, err := f.HotendRange(); err == nil {
+		f.TempHotend = r.Celsius().String()
+	}
+	if r, err := f.BedRange(); err == nil {
+		f.TempBed = r.Celsius().String()
+	}
+}
+
+// canonicalTemperature returns temp in the canonical Celsius syntax, or
+// unchanged if it does not parse.
+func canonicalTemperature(temp string) string {
+	r, err := ParseTemperature(temp)
+	if err != nil {
+		return temp
+	}
+	return r.Celsius().String()
+}
+
+func (f *FilamentSample) Filename() string {
+	parts := []string{f.Brand, f.Type, f.Color, canonicalTemperature(f.TempHotend), canonicalTemperature(f.TempBed)}
+	return strings.Join(parts, "_") + ".stl"
+}
+
+type sizeField struct {
+	name  string
+	field string
+	label string
+	value string
+}
+
+func (f *FilamentSample) sizes() []sizeField {
+	return []sizeField{
+		{"BRAND_SIZE", FieldBrandSize, "brand size", f.BrandSize},
+		{"TYPE_SIZE", FieldTypeSize, "type size", f.TypeSize},
+		{"COLOR_SIZE", FieldColorSize, "color size", f.ColorSize},
+	}
+}
+
+// reserved reports whether the template variable name is set from a field
+// of the sample rather than from Params.
+func (f *FilamentSample) reserved(name string) bool {
+	if IsFieldVariable(name) {
+		return true
+	}
+	for _, size := range f.sizes() {
+		if name == size.name {
+			return true
 		}
-		
-		min, err := strconv.Atoi(strings.TrimSpace(parts[0]))
-		if err != nil {
-			return errors.New("invalid minimum temperature")
+	}
+	return false
+}
+
+// ParamNames returns the names in Params in sorted order.
+func (f *FilamentSample) ParamNames() []string {
+	names := make([]string, 0, len(f.Params))
+	for name := range f.Params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CheckParams checks Params against the parameters a template defines.
+// Every name the template lacks and every value of the wrong type is
+// reported as a *FieldError, joined with errors.Join. Names Validate
+// rejects are left to it.
+func (f *FilamentSample) CheckParams(schema *scad.Schema) error {
+	var errs []error
+	for _, name := range f.ParamNames() {
+		if !scad.ValidName(name) || f.reserved(name) {
+			continue
 		}
-		
-		max, err := strconv.Atoi(strings.TrimSpace(parts[1]))
-		if err != nil {
-			return errors.New("invalid maximum temperature")
+		value := f.Params[name]
+		if err := schema.Check(name, scad.Infer(value)); err != nil {
+			errs = append(errs, &FieldError{Field: FieldParams, Value: value, Err: err})
 		}
-		
-		if min >= max {
-			return errors.New("minimum temperature must be less than maximum")
+	}
+	return errors.Join(errs...)
+}
+
+// ArgOptions controls how a sample is passed to the template.
+type ArgOptions struct {
+	// TemperatureFormat is used for the temperatures printed on the card.
+	TemperatureFormat TemperatureFormat
+	// Params are template parameters for every sample, as OpenSCAD
+	// literals keyed by name. The sample's own Params and fields take
+	// precedence.
+	Params map[string]string
+	// ParameterSets are Customizer parameter sets as OpenSCAD literals,
+	// keyed by set name and then by parameter name. ParameterSet names the
+	// set for samples that do not choose one. A set is overridden by
+	// Params.
+	ParameterSets map[string]map[string]string
+	ParameterSet  string
+}
+
+// IsFieldVariable reports whether name is a template variable every sample
+// sets from its own fields, such as BRAND, so a run-wide parameter of that
+// name would never apply.
+func IsFieldVariable(name string) bool {
+	switch name {
+	case "BRAND", "TYPE", "COLOR", "TEMP_HOTEND", "TEMP_BED":
+		return true
+	}
+	return false
+}
+
+// SetVariable sets the field the template variable name is passed from,
+// such as Brand for BRAND or BrandSize for BRAND_SIZE, to value. It reports
+// false, changing nothing, when no field passes name.
+func (f *FilamentSample) SetVariable(name, value string) bool {
+	switch name {
+	case "BRAND":
+		f.Brand = value
+	case "TYPE":
+		f.Type = value
+	case "COLOR":
+		f.Color = value
+	case "TEMP_HOTEND":
+		f.TempHotend = value
+	case "TEMP_BED":
+		f.TempBed = value
+	case "BRAND_SIZE":
+		f.BrandSize = value
+	case "TYPE_SIZE":
+		f.TypeSize = value
+	case "COLOR_SIZE":
+		f.ColorSize = value
+	default:
+		return false
+	}
+	return true
+}
+
+// ParamLiterals returns the template parameters passed for the sample as
+// OpenSCAD literals keyed by name: the parameter set the sample or opts
+// chooses, overridden by opts.Params and then by Params typed by
+// scad.Infer. Names the sample sets from its own fields, such as a
+// BRAND_SIZE given in its BrandSize, and names Validate rejects are left
+// out.
+func (f *FilamentSample) ParamLiterals(opts ArgOptions) map[string]string {
+	set := opts.ParameterSets[f.parameterSet(opts)]
+	literals := make(map[string]string, len(set)+len(opts.Params)+len(f.Params))
+	for _, params := range []map[string]string{set, opts.Params} {
+		for name, literal := range params {
+			if !f.sets(name) {
+				literals[name] = literal
+			}
 		}
-	} else {
-		_, err := strconv.Atoi(strings.TrimSpace(temp))
-		if err != nil {
-			return errors.New("invalid temperature value")
+	}
+	for name, value := range f.Params {
+		if scad.ValidName(name) && !f.reserved(name) {
+			literals[name] = scad.Infer(value)
 		}
 	}
-	
+	return literals
+}
+
+// parameterSet returns the name of the parameter set the sample starts
+// from, or "" for none.
+func (f *FilamentSample) parameterSet(opts ArgOptions) string {
+	if f.ParameterSet != "" {
+		return f.ParameterSet
+	}
+	return opts.ParameterSet
+}
+
+// CheckParameterSet checks that the parameter set the sample names is one
+// of opts.ParameterSets and that its parameters fit the template, which
+// may be nil. Problems are reported as a *FieldError.
+func (f *FilamentSample) CheckParameterSet(opts ArgOptions, schema *scad.Schema) error {
+	if f.ParameterSet == "" {
+		return nil
+	}
+	set, ok := opts.ParameterSets[f.ParameterSet]
+	if !ok {
+		return &FieldError{Field: FieldParameterSet, Value: f.ParameterSet, Err: fmt.Errorf("unknown parameter set %q", f.ParameterSet)}
+	}
+	if schema == nil {
+		return nil
+	}
+	if err := schema.CheckAll(set); err != nil {
+		return &FieldError{Field: FieldParameterSet, Value: f.ParameterSet, Err: fmt.Errorf("parameter set %q: %w", f.ParameterSet, err)}
+	}
 	return nil
 }
 
-func (f *FilamentSample) Filename() string {
-	parts := []string{f.Brand, f.Type, f.Color, f.TempHotend, f.TempBed}
-	return strings.Join(parts, "_") + ".stl"
+// sets reports whether the sample passes the template variable name from
+// one of its fields.
+func (f *FilamentSample) sets(name string) bool {
+	if IsFieldVariable(name) {
+		return true
+	}
+	for _, size := range f.sizes() {
+		if name == size.name {
+			return size.value != ""
+		}
+	}
+	return false
 }
 
+// OpenSCADArgs returns the -D options that pass the sample to the template
+// with the default options.
 func (f *FilamentSample) OpenSCADArgs() []string {
+	return f.OpenSCADArgsWith(ArgOptions{})
+}
+
+// OpenSCADArgsWith returns the -D options that pass the sample to the
+// template. Every value is encoded as an OpenSCAD literal, so no cell can end
+// the define early and inject code. Sizes that are not numbers, which
+// Validate rejects, are left out. The parameters of ParamLiterals follow in
+// name order.
+func (f *FilamentSample) OpenSCADArgsWith(opts ArgOptions) []string {
 	args := []string{
-		"-D", `BRAND="` + f.Brand + `"`,
-		"-D", `TYPE="` + f.Type + `"`,
-		"-D", `COLOR="` + f.Color + `"`,
-		"-D", `TEMP_HOTEND="` + f.TempHotend + `"`,
-		"-D", `TEMP_BED="` + f.TempBed + `"`,
+		"-D", "BRAND=" + scad.String(f.Brand),
+		"-D", "TYPE=" + scad.String(f.Type),
+		"-D", "COLOR=" + scad.String(f.Color),
+		"-D", "TEMP_HOTEND=" + scad.String(formatTemperature(f.TempHotend, opts.TemperatureFormat)),
+		"-D", "TEMP_BED=" + scad.String(formatTemperature(f.TempBed, opts.TemperatureFormat)),
 	}
-	
-	if f.BrandSize != "" {
-		args = append(args, "-D", "BRAND_SIZE="+f.BrandSize)
+
+	for _, size := range f.sizes() {
+		if size.value == "" {
+			continue
+		}
+		n, err := scad.ParseNumber(size.value)
+		if err != nil {
+			continue
+		}
+		literal, err := scad.Number(n)
+		if err != nil {
+			continue
+		}
+		args = append(args, "-D", size.name+"="+literal)
 	}
-	if f.TypeSize != "" {
-		args = append(args, "-D", "TYPE_SIZE="+f.TypeSize)
+
+	literals := f.ParamLiterals(opts)
+	names := make([]string, 0, len(literals))
+	for name := range literals {
+		names = append(names, name)
 	}
-	if f.ColorSize != "" {
-		args = append(args, "-D", "COLOR_SIZE="+f.ColorSize)
+	sort.Strings(names)
+	for _, name := range names {
+		args = append(args, "-D", name+"="+literals[name])
 	}
-	
+
 	return args
-}
\ No newline at end of file
+}
+
+// Defines returns every template variable OpenSCADArgsWith assigns, as
+// OpenSCAD literals keyed by name.
+func (f *FilamentSample) Defines(opts ArgOptions) map[string]string {
+	args := f.OpenSCADArgsWith(opts)
+	defines := make(map[string]string, len(args)/2)
+	for i := 1; i < len(args); i += 2 {
+		name, literal, _ := strings.Cut(args[i], "=")
+		defines[name] = literal
+	}
+	return defines
+}
+
+// formatTemperature formats temp for the card, or returns it unchanged if it
+// does not parse.
+func formatTemperature(temp string, format TemperatureFormat) string {
+	r, err := ParseTemperature(temp)
+	if err != nil {
+		return temp
+	}
+	return format.Format(r)
+}
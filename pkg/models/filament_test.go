@@ -1,7 +1,12 @@
 package models
 
 import (
+	"errors"
+	"reflect"
+	"regexp"
 	"testing"
+
+	"github.com/guntharp/go-filamentsamples/pkg/scad"
 )
 
 func TestFilamentSample_Validate(t *testing.T) {
@@ -62,6 +67,28 @@ func TestFilamentSample_Validate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "path separator in brand",
+			sample: FilamentSample{
+				Brand:      "A/B",
+				Type:       "PLA",
+				Color:      "Red",
+				TempHotend: "200-220",
+				TempBed:    "60",
+			},
+			wantErr: true,
+		},
+		{
+			name: "backslash in color",
+			sample: FilamentSample{
+				Brand:      "Test Brand",
+				Type:       "PLA",
+				Color:      `Red\Blue`,
+				TempHotend: "200-220",
+				TempBed:    "60",
+			},
+			wantErr: true,
+		},
 		{
 			name: "missing hotend temp",
 			sample: FilamentSample{
@@ -148,6 +175,68 @@ func TestFilamentSample_Validate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "decimal sizes",
+			sample: FilamentSample{
+				Brand:      "Test Brand",
+				Type:       "PLA",
+				Color:      "Red",
+				TempHotend: "200-220",
+				TempBed:    "60",
+				BrandSize:  "12",
+				TypeSize:   "7.5",
+				ColorSize:  ".8e1",
+			},
+			wantErr: false,
+		},
+		{
+			name: "size injecting code",
+			sample: FilamentSample{
+				Brand:      "Test Brand",
+				Type:       "PLA",
+				Color:      "Red",
+				TempHotend: "200-220",
+				TempBed:    "60",
+				BrandSize:  "4;r_hole=0",
+			},
+			wantErr: true,
+		},
+		{
+			name: "non-numeric color size",
+			sample: FilamentSample{
+				Brand:      "Test Brand",
+				Type:       "PLA",
+				Color:      "Red",
+				TempHotend: "200-220",
+				TempBed:    "60",
+				ColorSize:  "large",
+			},
+			wantErr: true,
+		},
+		{
+			name: "params",
+			sample: FilamentSample{
+				Brand: "Test Brand", Type: "PLA", Color: "Red", TempHotend: "200-220", TempBed: "60",
+				Params: map[string]string{"CARD_THICKNESS": "3", "$fn": "64"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid param name",
+			sample: FilamentSample{
+				Brand: "Test Brand", Type: "PLA", Color: "Red", TempHotend: "200-220", TempBed: "60",
+				Params: map[string]string{"Spool weight": "1000"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "param set by a column",
+			sample: FilamentSample{
+				Brand: "Test Brand", Type: "PLA", Color: "Red", TempHotend: "200-220", TempBed: "60",
+				Params: map[string]string{"BRAND_SIZE": "10"},
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -217,6 +306,34 @@ func TestFilamentSample_OpenSCADArgs(t *testing.T) {
 				"-D", "BRAND_SIZE=12",
 			},
 		},
+		{
+			name: "with params",
+			sample: FilamentSample{
+				Brand:      "Test Brand",
+				Type:       "PLA",
+				Color:      "Red",
+				TempHotend: "200-220",
+				TempBed:    "60",
+				Params: map[string]string{
+					"INVERT_CARD":    "true",
+					"CARD_THICKNESS": "3",
+					"FONT":           "Liberation Sans",
+					"INSET_DEPTHS":   "[0.2, 0.4]",
+					"BRAND":          "ignored",
+				},
+			},
+			expectedArgs: []string{
+				"-D", `BRAND="Test Brand"`,
+				"-D", `TYPE="PLA"`,
+				"-D", `COLOR="Red"`,
+				"-D", `TEMP_HOTEND="200-220"`,
+				"-D", `TEMP_BED="60"`,
+				"-D", "CARD_THICKNESS=3",
+				"-D", `FONT="Liberation Sans"`,
+				"-D", "INSET_DEPTHS=[0.2, 0.4]",
+				"-D", "INVERT_CARD=true",
+			},
+		},
 		{
 			name: "with type size",
 			sample: FilamentSample{
@@ -296,4 +413,201 @@ func TestFilamentSample_OpenSCADArgs(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+
+func TestFilamentSample_OpenSCADArgs_Escaping(t *testing.T) {
+	sample := FilamentSample{
+		Brand:      `Brand\Co`,
+		Type:       "PLA",
+		Color:      `12" Dark`,
+		TempHotend: "200-220",
+		TempBed:    "60",
+		BrandSize:  "4;r_hole=0",
+		TypeSize:   "8.50",
+	}
+
+	expected := []string{
+		"-D", `BRAND="Brand\\Co"`,
+		"-D", `TYPE="PLA"`,
+		"-D", `COLOR="12\" Dark"`,
+		"-D", `TEMP_HOTEND="200-220"`,
+		"-D", `TEMP_BED="60"`,
+		"-D", "TYPE_SIZE=8.5",
+	}
+
+	if got := sample.OpenSCADArgs(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("OpenSCADArgs() = %q, want %q", got, expected)
+	}
+}
+
+func FuzzFilamentSample_OpenSCADArgs(f *testing.F) {
+	f.Add("Brand", `12" Dark`, "12")
+	f.Add(`a\b`, "Grün", "4;r_hole=0")
+	f.Add(`"; cube(100); x="`, "\x00", "1e3")
+
+	define := regexp.MustCompile(`^(BRAND|TYPE|COLOR|TEMP_HOTEND|TEMP_BED)="([^"\\]|\\.)*"$|^(BRAND|TYPE|COLOR)_SIZE=[-+0-9.e]+$`)
+
+	f.Fuzz(func(t *testing.T, brand, color, size string) {
+		sample := FilamentSample{
+			Brand:      brand,
+			Type:       "PLA",
+			Color:      color,
+			TempHotend: "200",
+			TempBed:    "60",
+			BrandSize:  size,
+		}
+
+		args := sample.OpenSCADArgs()
+		for i := 1; i < len(args); i += 2 {
+			if !define.MatchString(args[i]) {
+				t.Fatalf("unsafe define %q for brand %q, color %q, size %q", args[i], brand, color, size)
+			}
+		}
+
+		if brand == "" || color == "" {
+			return
+		}
+		hasSize := len(args) == 12
+		if err := sample.Validate(); (err == nil) != (hasSize || size == "") {
+			t.Fatalf("Validate() = %v but BRAND_SIZE passed = %v for size %q", err, hasSize, size)
+		}
+	})
+}
+
+func TestFilamentSample_ParamLiterals(t *testing.T) {
+	sample := FilamentSample{
+		Brand: "Test", Type: "PLA", Color: "Red", TempHotend: "200", TempBed: "60", BrandSize: "5",
+		Params: map[string]string{"FONT": "Noto Sans", "BRAND_SIZE": "9"},
+	}
+	opts := ArgOptions{Params: map[string]string{
+		"FONT": `"Liberation Sans"`, "$fn": "20", "BRAND_SIZE": "4", "TYPE_SIZE": "4",
+	}}
+
+	got := sample.ParamLiterals(opts)
+	want := map[string]string{"FONT": `"Noto Sans"`, "$fn": "20", "TYPE_SIZE": "4"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParamLiterals() = %v, want %v", got, want)
+	}
+
+	args := sample.OpenSCADArgsWith(opts)
+	wantTail := []string{"-D", "$fn=20", "-D", `FONT="Noto Sans"`, "-D", "TYPE_SIZE=4"}
+	if tail := args[len(args)-len(wantTail):]; !reflect.DeepEqual(tail, wantTail) {
+		t.Errorf("OpenSCADArgsWith() ends with %v, want %v", tail, wantTail)
+	}
+}
+
+func TestFilamentSample_ParamLiterals_ParameterSet(t *testing.T) {
+	sample := FilamentSample{
+		Brand: "Test", Type: "PLA", Color: "Red", TempHotend: "200", TempBed: "60", BrandSize: "5",
+		Params: map[string]string{"CARD_THICKNESS": "4"},
+	}
+	opts := ArgOptions{
+		ParameterSets: map[string]map[string]string{
+			"Default": {"BRAND": `"extrudr"`, "BRAND_SIZE": "4.2", "CARD_THICKNESS": "2.2", "FONT": `"Liberation Sans"`, "PLA_NOTCH_X": "17"},
+			"Wide":    {"CARD_LENGTH": "100"},
+		},
+		ParameterSet: "Default",
+		Params:       map[string]string{"FONT": `"Noto Sans"`},
+	}
+
+	got := sample.ParamLiterals(opts)
+	want := map[string]string{"CARD_THICKNESS": "4", "FONT": `"Noto Sans"`, "PLA_NOTCH_X": "17"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParamLiterals() = %v, want %v", got, want)
+	}
+
+	sample.ParameterSet = "Wide"
+	got = sample.ParamLiterals(opts)
+	want = map[string]string{"CARD_LENGTH": "100", "CARD_THICKNESS": "4", "FONT": `"Noto Sans"`}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParamLiterals() with the sample's set = %v, want %v", got, want)
+	}
+}
+
+func TestFilamentSample_CheckParameterSet(t *testing.T) {
+	schema, err := scad.ParseTemplate([]byte("CARD_THICKNESS=2.2;\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := ArgOptions{ParameterSets: map[string]map[string]string{
+		"Thick": {"CARD_THICKNESS": "3"},
+		"Bad":   {"CARD_THICKNESS": `"thick"`},
+	}}
+
+	tests := []struct {
+		set     string
+		wantErr string
+	}{
+		{set: ""},
+		{set: "Thick"},
+		{set: "Nope", wantErr: `unknown parameter set "Nope"`},
+		{set: "Bad", wantErr: `parameter set "Bad": CARD_THICKNESS must be a number like 2.2, got string "thick"`},
+	}
+	for _, tt := range tests {
+		sample := FilamentSample{ParameterSet: tt.set}
+		err := sample.CheckParameterSet(opts, schema)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("CheckParameterSet(%q) error = %v", tt.set, err)
+			}
+			continue
+		}
+		var fieldErr *FieldError
+		if !errors.As(err, &fieldErr) || fieldErr.Field != FieldParameterSet || err.Error() != tt.wantErr {
+			t.Errorf("CheckParameterSet(%q) error = %v, want FieldError %q", tt.set, err, tt.wantErr)
+		}
+	}
+}
+
+func TestFilamentSample_CheckParams(t *testing.T) {
+	schema, err := scad.ParseTemplate([]byte("CARD_THICKNESS=2.2;\nFONT=\"Liberation Sans\";\nBRAND=\"x\";\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sample := FilamentSample{Params: map[string]string{"CARD_THICKNESS": "3", "FONT": "Noto Sans", "$fn": "20"}}
+	if err := sample.CheckParams(schema); err != nil {
+		t.Errorf("CheckParams() error = %v", err)
+	}
+
+	sample.Params = map[string]string{"CARD_THICKNESS": "thick", "TEXT_X": "4", "BRAND": "ignored"}
+	err = sample.CheckParams(schema)
+	var fieldErr *FieldError
+	if !errors.As(err, &fieldErr) || fieldErr.Field != FieldParams || fieldErr.Value != "thick" {
+		t.Fatalf("CheckParams() error = %v, want a FieldError for CARD_THICKNESS", err)
+	}
+	want := "CARD_THICKNESS must be a number like 2.2, got string \"thick\"\nthe template has no parameter TEXT_X"
+	if err.Error() != want {
+		t.Errorf("CheckParams() error = %q, want %q", err, want)
+	}
+}
+
+func TestFilamentSample_Location(t *testing.T) {
+	tests := []struct {
+		sample FilamentSample
+		want   string
+	}{
+		{FilamentSample{Source: "samples.csv", Line: 4}, "samples.csv: line 4: "},
+		{FilamentSample{Source: "samples.csv"}, "samples.csv: "},
+		{FilamentSample{Line: 4}, "line 4: "},
+		{FilamentSample{}, ""},
+	}
+
+	for _, tt := range tests {
+		if got := tt.sample.Location(); got != tt.want {
+			t.Errorf("Location() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestUnjoin(t *testing.T) {
+	err := (&FilamentSample{}).Validate()
+	if errs := Unjoin(err); len(errs) != 5 {
+		t.Errorf("Unjoin() returned %d errors, want 5: %v", len(errs), errs)
+	}
+
+	single := errors.New("single")
+	if errs := Unjoin(single); len(errs) != 1 || errs[0] != single {
+		t.Errorf("Unjoin(single) = %v", errs)
+	}
+}
@@ -0,0 +1,150 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// TemperatureUnit is the scale a temperature is written in.
+type TemperatureUnit string
+
+const (
+	Celsius    TemperatureUnit = "C"
+	Fahrenheit TemperatureUnit = "F"
+)
+
+// TemperatureRange is a printing temperature, either a single value with
+// Min equal to Max or a range.
+type TemperatureRange struct {
+	Min  int             `json:"min"`
+	Max  int             `json:"max"`
+	Unit TemperatureUnit `json:"unit"`
+}
+
+// temperaturePattern accepts 210, 210-240 with a hyphen, en or em dash, or
+// "to", 210±10 (or 210+/-10), each optionally followed by a unit such as °C,
+// °F, C or F.
+var temperaturePattern = regexp.MustCompile(
+	`^(\d+)\s*(?:(?:-|–|—|to)\s*(\d+)|(?:±|\+/-|\+-)\s*(\d+))?\s*(?:[°º]\s*)?([CcFf])?$`)
+
+// ParseTemperature parses a temperature as written in the CSV file. Values
+// without a unit are Celsius.
+func ParseTemperature(s string) (TemperatureRange, error) {
+	m := temperaturePattern.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return TemperatureRange{}, fmt.Errorf("invalid temperature %q: expected a value such as 210, 210-240 or 210±10", s)
+	}
+
+	value, err := strconv.Atoi(m[1])
+	if err != nil {
+		return TemperatureRange{}, fmt.Errorf("invalid temperature %q", s)
+	}
+	r := TemperatureRange{Min: value, Max: value, Unit: Celsius}
+
+	switch {
+	case m[2] != "":
+		if r.Max, err = strconv.Atoi(m[2]); err != nil {
+			return TemperatureRange{}, fmt.Errorf("invalid temperature %q", s)
+		}
+		if r.Min >= r.Max {
+			return TemperatureRange{}, fmt.Errorf("invalid temperature %q: minimum temperature must be less than maximum", s)
+		}
+	case m[3] != "":
+		tolerance, err := strconv.Atoi(m[3])
+		if err != nil || tolerance == 0 || tolerance > value {
+			return TemperatureRange{}, fmt.Errorf("invalid temperature %q: tolerance must be between 1 and %d", s, value)
+		}
+		r.Min, r.Max = value-tolerance, value+tolerance
+	}
+
+	if strings.EqualFold(m[4], "F") {
+		r.Unit = Fahrenheit
+	}
+
+	return r, nil
+}
+
+// IsRange reports whether r spans more than a single temperature.
+func (r TemperatureRange) IsRange() bool {
+	return r.Min != r.Max
+}
+
+// Celsius returns r converted to Celsius, rounded to whole degrees.
+func (r TemperatureRange) Celsius() TemperatureRange {
+	if r.Unit != Fahrenheit {
+		return TemperatureRange{Min: r.Min, Max: r.Max, Unit: Celsius}
+	}
+	return TemperatureRange{Min: toCelsius(r.Min), Max: toCelsius(r.Max), Unit: Celsius}
+}
+
+// Fahrenheit returns r converted to Fahrenheit, rounded to whole degrees.
+func (r TemperatureRange) Fahrenheit() TemperatureRange {
+	if r.Unit == Fahrenheit {
+		return r
+	}
+	return TemperatureRange{Min: toFahrenheit(r.Min), Max: toFahrenheit(r.Max), Unit: Fahrenheit}
+}
+
+// String returns r in the canonical CSV syntax: 210 or 210-240, followed by
+// °F for Fahrenheit.
+func (r TemperatureRange) String() string {
+	s := strconv.Itoa(r.Min)
+	if r.IsRange() {
+		s += "-" + strconv.Itoa(r.Max)
+	}
+	if r.Unit == Fahrenheit {
+		s += "°F"
+	}
+	return s
+}
+
+func toCelsius(f int) int {
+	return int(math.Round(float64(f-32) * 5 / 9))
+}
+
+func toFahrenheit(c int) int {
+	return int(math.Round(float64(c)*9/5 + 32))
+}
+
+// TemperatureFormat controls how temperatures are printed on the card. The
+// zero value prints Celsius as 210 or 210-240, as the CSV syntax does.
+type TemperatureFormat struct {
+	// Unit is the scale to print in, "C" (the default) or "F".
+	Unit TemperatureUnit `json:"unit,omitempty"`
+	// Separator goes between the minimum and maximum, "-" by default.
+	Separator string `json:"separator,omitempty"`
+	// Suffix is appended to the value, for example "°C".
+	Suffix string `json:"suffix,omitempty"`
+}
+
+// Validate reports an unknown unit.
+func (f TemperatureFormat) Validate() error {
+	switch f.Unit {
+	case "", Celsius, Fahrenheit:
+		return nil
+	}
+	return fmt.Errorf("temperature unit must be %q or %q, got %q", Celsius, Fahrenheit, f.Unit)
+}
+
+// Format prints r, converted to the format's unit.
+func (f TemperatureFormat) Format(r TemperatureRange) string {
+	if f.Unit == Fahrenheit {
+		r = r.Fahrenheit()
+	} else {
+		r = r.Celsius()
+	}
+
+	separator := f.Separator
+	if separator == "" {
+		separator = "-"
+	}
+
+	s := strconv.Itoa(r.Min)
+	if r.IsRange() {
+		s += separator + strconv.Itoa(r.Max)
+	}
+	return s + f.Suffix
+}
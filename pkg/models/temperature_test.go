@@ -0,0 +1,133 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseTemperature(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    TemperatureRange
+		wantErr bool
+	}{
+		{input: "210", want: TemperatureRange{210, 210, Celsius}},
+		{input: " 210 ", want: TemperatureRange{210, 210, Celsius}},
+		{input: "210-240", want: TemperatureRange{210, 240, Celsius}},
+		{input: "210 - 240", want: TemperatureRange{210, 240, Celsius}},
+		{input: "210–240", want: TemperatureRange{210, 240, Celsius}},
+		{input: "210—240", want: TemperatureRange{210, 240, Celsius}},
+		{input: "210 to 240", want: TemperatureRange{210, 240, Celsius}},
+		{input: "225±15", want: TemperatureRange{210, 240, Celsius}},
+		{input: "225 +/- 15", want: TemperatureRange{210, 240, Celsius}},
+		{input: "210-240°C", want: TemperatureRange{210, 240, Celsius}},
+		{input: "210-240 C", want: TemperatureRange{210, 240, Celsius}},
+		{input: "410-460°F", want: TemperatureRange{410, 460, Fahrenheit}},
+		{input: "140 ºf", want: TemperatureRange{140, 140, Fahrenheit}},
+		{input: "", wantErr: true},
+		{input: "hot", wantErr: true},
+		{input: "240-210", wantErr: true},
+		{input: "210-210", wantErr: true},
+		{input: "200-220-240", wantErr: true},
+		{input: "210±0", wantErr: true},
+		{input: "10±20", wantErr: true},
+		{input: "-10", wantErr: true},
+		{input: "210K", wantErr: true},
+		{input: "99999999999999999999", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseTemperature(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTemperature() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseTemperature() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTemperatureRange_Conversion(t *testing.T) {
+	f := TemperatureRange{Min: 410, Max: 460, Unit: Fahrenheit}
+	if got := f.Celsius(); got != (TemperatureRange{210, 238, Celsius}) {
+		t.Errorf("Celsius() = %+v", got)
+	}
+	if got := f.Celsius().String(); got != "210-238" {
+		t.Errorf("String() = %s", got)
+	}
+	if got := f.String(); got != "410-460°F" {
+		t.Errorf("String() = %s", got)
+	}
+
+	c := TemperatureRange{Min: 60, Max: 60, Unit: Celsius}
+	if got := c.Fahrenheit(); got != (TemperatureRange{140, 140, Fahrenheit}) {
+		t.Errorf("Fahrenheit() = %+v", got)
+	}
+	if c.IsRange() || !f.IsRange() {
+		t.Error("IsRange() is wrong")
+	}
+
+	data, _ := json.Marshal(c)
+	if string(data) != `{"min":60,"max":60,"unit":"C"}` {
+		t.Errorf("JSON = %s", data)
+	}
+}
+
+func TestTemperatureFormat_Format(t *testing.T) {
+	r := TemperatureRange{Min: 210, Max: 240, Unit: Celsius}
+	single := TemperatureRange{Min: 60, Max: 60, Unit: Celsius}
+
+	tests := []struct {
+		name   string
+		format TemperatureFormat
+		r      TemperatureRange
+		want   string
+	}{
+		{name: "default", r: r, want: "210-240"},
+		{name: "default single", r: single, want: "60"},
+		{name: "en dash with unit", format: TemperatureFormat{Separator: "–", Suffix: "°C"}, r: r, want: "210–240°C"},
+		{name: "fahrenheit", format: TemperatureFormat{Unit: Fahrenheit, Suffix: "°F"}, r: r, want: "410-464°F"},
+		{name: "fahrenheit input shown in celsius", r: TemperatureRange{410, 460, Fahrenheit}, want: "210-238"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.format.Format(tt.r); got != tt.want {
+				t.Errorf("Format() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	if err := (TemperatureFormat{Unit: "K"}).Validate(); err == nil {
+		t.Error("Validate() should reject unit K")
+	}
+}
+
+func TestFilamentSample_Temperatures(t *testing.T) {
+	sample := FilamentSample{
+		Brand:      "Test",
+		Type:       "PLA",
+		Color:      "Red",
+		TempHotend: "210–240",
+		TempBed:    "140°F",
+	}
+
+	if err := sample.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if got := sample.Filename(); got != "Test_PLA_Red_210-240_60.stl" {
+		t.Errorf("Filename() = %s", got)
+	}
+
+	args := sample.OpenSCADArgsWith(ArgOptions{TemperatureFormat: TemperatureFormat{Suffix: "°C"}})
+	if args[7] != `TEMP_HOTEND="210-240\u00b0C"` || args[9] != `TEMP_BED="60\u00b0C"` {
+		t.Errorf("OpenSCADArgsWith() = %q", args)
+	}
+
+	sample.NormalizeTemperatures()
+	if sample.TempHotend != "210-240" || sample.TempBed != "60" {
+		t.Errorf("NormalizeTemperatures() = %s, %s", sample.TempHotend, sample.TempBed)
+	}
+}
@@ -0,0 +1,104 @@
+package scad
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ParameterFile is an OpenSCAD Customizer parameter file, the JSON file the
+// Customizer saves its presets to next to the template.
+type ParameterFile struct {
+	ParameterSets     map[string]ParameterSet `json:"parameterSets"`
+	FileFormatVersion string                  `json:"fileFormatVersion"`
+}
+
+// ParameterSet is a named Customizer preset. The Customizer stores every
+// value as text, such as "2.2", "[1, 0.8]" or "Liberation Sans", without
+// quotes around strings.
+type ParameterSet map[string]string
+
+// UnmarshalJSON accepts numbers and booleans as well as text, for files
+// written by hand.
+func (s *ParameterSet) UnmarshalJSON(data []byte) error {
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	set := make(ParameterSet, len(raw))
+	for name, value := range raw {
+		switch v := value.(type) {
+		case string:
+			set[name] = v
+		case float64:
+			set[name] = strconv.FormatFloat(v, 'g', -1, 64)
+		case bool:
+			set[name] = strconv.FormatBool(v)
+		default:
+			return fmt.Errorf("parameter %s: expected text, a number or a boolean", name)
+		}
+	}
+	*s = set
+	return nil
+}
+
+// ParseParameterFile reads a Customizer parameter file.
+func ParseParameterFile(data []byte) (*ParameterFile, error) {
+	var f ParameterFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+	if f.ParameterSets == nil {
+		return nil, fmt.Errorf("no parameterSets")
+	}
+	return &f, nil
+}
+
+// SetNames returns the names of the parameter sets in sorted order.
+func (f *ParameterFile) SetNames() []string {
+	names := make([]string, 0, len(f.ParameterSets))
+	for name := range f.ParameterSets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Literals returns the set as OpenSCAD literals keyed by name. Values of
+// string parameters in schema are quoted as they are; other values are
+// typed by Infer, as the Customizer reads them. schema may be nil. It fails
+// for names -D cannot assign.
+func (s ParameterSet) Literals(schema *Schema) (map[string]string, error) {
+	literals := make(map[string]string, len(s))
+	for name, value := range s {
+		if !ValidName(name) {
+			return nil, fmt.Errorf("invalid parameter name %q", name)
+		}
+		literals[name] = Infer(value)
+		if schema == nil {
+			continue
+		}
+		if p, ok := schema.Lookup(name); ok && p.Type == TypeString {
+			literals[name] = String(value)
+		}
+	}
+	return literals, nil
+}
+
+// CustomizerValue returns the text the Customizer stores for an OpenSCAD
+// literal, the inverse of Literals: strings without their quotes, and other
+// literals in the form Infer gives them, such as 80 for 80.0. Expressions
+// are returned as they are.
+func CustomizerValue(literal string) string {
+	switch LiteralType(literal) {
+	case TypeExpression:
+		return literal
+	case TypeString:
+		if s, err := Unquote(strings.TrimSpace(literal)); err == nil {
+			return s
+		}
+	}
+	return Infer(literal)
+}
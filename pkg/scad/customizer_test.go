@@ -0,0 +1,91 @@
+package scad
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseParameterFile(t *testing.T) {
+	data := `{
+		"parameterSets": {
+			"Default": {"$fn": "50", "FONT": "Liberation Sans:style=Bold", "INSET_DEPTHS": "[1, 0.8]"},
+			"Hand written": {"CARD_THICKNESS": 3, "SHOW": true}
+		},
+		"fileFormatVersion": "1"
+	}`
+
+	f, err := ParseParameterFile([]byte(data))
+	if err != nil {
+		t.Fatalf("ParseParameterFile() error = %v", err)
+	}
+	if names := f.SetNames(); !reflect.DeepEqual(names, []string{"Default", "Hand written"}) {
+		t.Errorf("SetNames() = %v", names)
+	}
+	want := ParameterSet{"CARD_THICKNESS": "3", "SHOW": "true"}
+	if got := f.ParameterSets["Hand written"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("ParameterSets[Hand written] = %v, want %v", got, want)
+	}
+
+	for _, bad := range []string{
+		`{"fileFormatVersion": "1"}`,
+		`{"parameterSets": {"A": {"X": [1, 2]}}}`,
+		`{"parameterSets": [`,
+	} {
+		if _, err := ParseParameterFile([]byte(bad)); err == nil {
+			t.Errorf("ParseParameterFile(%s) should fail", bad)
+		}
+	}
+}
+
+func TestParameterSet_Literals(t *testing.T) {
+	schema, err := ParseTemplate([]byte("FONT=\"Liberation Sans\";\nLABEL=\"x\";\nCARD_THICKNESS=2.2;\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	set := ParameterSet{
+		"$fn": "50", "FONT": "Liberation Sans:style=Bold", "LABEL": "240",
+		"CARD_THICKNESS": "3", "INSET_DEPTHS": "[1, 0.8]",
+	}
+
+	got, err := set.Literals(schema)
+	if err != nil {
+		t.Fatalf("Literals() error = %v", err)
+	}
+	want := map[string]string{
+		"$fn": "50", "FONT": `"Liberation Sans:style=Bold"`, "LABEL": `"240"`,
+		"CARD_THICKNESS": "3", "INSET_DEPTHS": "[1, 0.8]",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Literals() = %v, want %v", got, want)
+	}
+
+	// Without a template, values are typed as they read.
+	if got, _ := set.Literals(nil); got["LABEL"] != "240" {
+		t.Errorf("Literals(nil)[LABEL] = %s, want 240", got["LABEL"])
+	}
+
+	if _, err := (ParameterSet{"not a name": "1"}).Literals(schema); err == nil {
+		t.Error("Literals() should fail for an invalid name")
+	}
+}
+
+func TestCustomizerValue(t *testing.T) {
+	tests := []struct {
+		literal string
+		want    string
+	}{
+		{`"Liberation Sans:style=Bold"`, "Liberation Sans:style=Bold"},
+		{`"Gr\u00fcn"`, "Grün"},
+		{`"240"`, "240"},
+		{"80.0", "80"},
+		{"[1.0, 0.8,\n  0.6]", "[1, 0.8, 0.6]"},
+		{"true", "true"},
+		{"undef", "undef"},
+		{"CARD_HEIGHT - 6.75", "CARD_HEIGHT - 6.75"},
+	}
+	for _, tt := range tests {
+		if got := CustomizerValue(tt.literal); got != tt.want {
+			t.Errorf("CustomizerValue(%s) = %q, want %q", tt.literal, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package scad
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseValue reads a value written as text, such as a CSV cell, the way it
+// would be written in OpenSCAD: numbers accepted by ParseNumber, true,
+// false, undef (as nil), "quoted strings" and [vectors] of such values.
+// Anything else is the text itself, so FONT=Liberation Sans needs no
+// quotes.
+func ParseValue(s string) any {
+	s = strings.TrimSpace(s)
+	switch s {
+	case "true":
+		return true
+	case "false":
+		return false
+	case "undef":
+		return nil
+	}
+	if n, err := ParseNumber(s); err == nil {
+		return n
+	}
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		if unquoted, err := Unquote(s); err == nil {
+			return unquoted
+		}
+	}
+	if len(s) >= 2 && s[0] == '[' && s[len(s)-1] == ']' {
+		if parts, ok := split(s[1:len(s)-1], ','); ok {
+			elems := make([]any, 0, len(parts))
+			for _, part := range parts {
+				if strings.TrimSpace(part) == "" {
+					if len(parts) == 1 {
+						break
+					}
+					return s
+				}
+				elems = append(elems, ParseValue(part))
+			}
+			return elems
+		}
+	}
+	return s
+}
+
+// Infer returns the OpenSCAD literal of a value written as text, as read by
+// ParseValue.
+func Infer(s string) string {
+	literal, err := Literal(ParseValue(s))
+	if err != nil {
+		// ParseValue only returns values Literal supports.
+		return String(s)
+	}
+	return literal
+}
+
+// ParseAssignments reads NAME=value pairs separated by semicolons, such as
+// CARD_THICKNESS=3;INVERT_CARD=true. Semicolons inside quotes or brackets do
+// not separate pairs. Values are returned as written, for Infer.
+func ParseAssignments(s string) (map[string]string, error) {
+	parts, ok := split(s, ';')
+	if !ok {
+		return nil, fmt.Errorf("unbalanced quotes or brackets in %q", s)
+	}
+
+	values := make(map[string]string, len(parts))
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, value, found := strings.Cut(part, "=")
+		name = strings.TrimSpace(name)
+		if !found {
+			return nil, fmt.Errorf("expected NAME=value, got %q", part)
+		}
+		if !ValidName(name) {
+			return nil, fmt.Errorf("invalid parameter name %q", name)
+		}
+		if _, dup := values[name]; dup {
+			return nil, fmt.Errorf("parameter %s is set twice", name)
+		}
+		values[name] = strings.TrimSpace(value)
+	}
+	return values, nil
+}
+
+// split cuts s at every sep outside double quotes and brackets. It reports
+// false when quotes or brackets are unbalanced.
+func split(s string, sep byte) ([]string, bool) {
+	var parts []string
+	depth, quoted, start := 0, false, 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case quoted && c == '\\':
+			i++
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+		case c == '[':
+			depth++
+		case c == ']':
+			if depth--; depth < 0 {
+				return nil, false
+			}
+		case c == sep && depth == 0:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	if quoted || depth != 0 {
+		return nil, false
+	}
+	return append(parts, s[start:]), true
+}
@@ -0,0 +1,79 @@
+package scad
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestInfer(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{`3`, `3`},
+		{` 2.5 `, `2.5`},
+		{`-1e3`, `-1000`},
+		{`true`, `true`},
+		{`false`, `false`},
+		{`undef`, `undef`},
+		{`Liberation Sans:style=Bold`, `"Liberation Sans:style=Bold"`},
+		{`"12"`, `"12"`},
+		{`"say \"hi\""`, `"say \"hi\""`},
+		{`[1, 2, 3]`, `[1, 2, 3]`},
+		{`[0.4, [1, true], "a,b"]`, `[0.4, [1, true], "a,b"]`},
+		{`[]`, `[]`},
+		{`[1,,2]`, `"[1,,2]"`},
+		{`[1, 2`, `"[1, 2"`},
+		{`0x10`, `"0x10"`},
+		{`inf`, `"inf"`},
+		{`"); cube(100); x=("`, `"); cube(100); x=("`},
+		{`x"); cube(100); //`, `"x\"); cube(100); //"`},
+	}
+
+	for _, tt := range tests {
+		if got := Infer(tt.in); got != tt.want {
+			t.Errorf("Infer(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseAssignments(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    map[string]string
+		wantErr string
+	}{
+		{
+			in:   `CARD_THICKNESS=3; INVERT_CARD = true;`,
+			want: map[string]string{"CARD_THICKNESS": "3", "INVERT_CARD": "true"},
+		},
+		{
+			in:   `FONT="Noto; Sans";INSET_DEPTHS=[0.2; 0.4]`,
+			want: map[string]string{"FONT": `"Noto; Sans"`, "INSET_DEPTHS": "[0.2; 0.4]"},
+		},
+		{in: ``, want: map[string]string{}},
+		{in: `$fn=64`, want: map[string]string{"$fn": "64"}},
+		{in: `CARD_THICKNESS`, wantErr: "expected NAME=value"},
+		{in: `2nd=1`, wantErr: `invalid parameter name "2nd"`},
+		{in: `A=1;A=2`, wantErr: "set twice"},
+		{in: `FONT="Noto`, wantErr: "unbalanced"},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseAssignments(tt.in)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseAssignments(%q) error = %v, want %q", tt.in, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseAssignments(%q) error = %v", tt.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseAssignments(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
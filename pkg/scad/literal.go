@@ -0,0 +1,203 @@
+// Package scad encodes Go values as OpenSCAD literals, so that values taken
+// from CSV files can be passed to OpenSCAD with -D without being able to
+// change the meaning of the define.
+package scad
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrUnsupported is returned by Literal for values that have no OpenSCAD
+// literal form.
+var ErrUnsupported = errors.New("no OpenSCAD literal for value")
+
+// String returns s as a double-quoted OpenSCAD string literal. Quotes and
+// backslashes are escaped, control characters use their escape sequences and
+// everything outside printable ASCII is written as \uXXXX or \UXXXXXX, so the
+// literal is plain ASCII whatever the input. NUL characters, which OpenSCAD
+// strings cannot hold, are dropped, and invalid UTF-8 becomes U+FFFD.
+func String(s string) string {
+	var b strings.Builder
+	b.Grow(len(s) + 2)
+	b.WriteByte('"')
+	for _, r := range strings.ToValidUTF8(s, string(utf8.RuneError)) {
+		switch {
+		case r == 0:
+		case r == '"':
+			b.WriteString(`\"`)
+		case r == '\\':
+			b.WriteString(`\\`)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&b, `\x%02x`, r)
+		case r < 0x7f:
+			b.WriteRune(r)
+		case r <= 0xffff:
+			fmt.Fprintf(&b, `\u%04x`, r)
+		default:
+			fmt.Fprintf(&b, `\U%06x`, r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// Unquote returns the text of an OpenSCAD string literal, undoing the
+// escape sequences String writes.
+func Unquote(literal string) (string, error) {
+	if len(literal) < 2 || literal[0] != '"' || literal[len(literal)-1] != '"' {
+		return "", fmt.Errorf("%q is not a string literal", literal)
+	}
+	body := literal[1 : len(literal)-1]
+
+	var b strings.Builder
+	b.Grow(len(body))
+	for i := 0; i < len(body); i++ {
+		c := body[i]
+		if c == '"' {
+			return "", fmt.Errorf("unescaped quote in %q", literal)
+		}
+		if c != '\\' {
+			b.WriteByte(c)
+			continue
+		}
+		if i++; i == len(body) {
+			return "", fmt.Errorf("unfinished escape sequence in %q", literal)
+		}
+		digits := 0
+		switch body[i] {
+		case '"', '\\':
+			b.WriteByte(body[i])
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case 'x':
+			digits = 2
+		case 'u':
+			digits = 4
+		case 'U':
+			digits = 6
+		default:
+			return "", fmt.Errorf("unknown escape sequence \\%c in %q", body[i], literal)
+		}
+		if digits == 0 {
+			continue
+		}
+		if i+1+digits > len(body) {
+			return "", fmt.Errorf("unfinished escape sequence in %q", literal)
+		}
+		r, err := strconv.ParseUint(body[i+1:i+1+digits], 16, 32)
+		if err != nil {
+			return "", fmt.Errorf("invalid escape sequence in %q", literal)
+		}
+		b.WriteRune(rune(r))
+		i += digits
+	}
+	return b.String(), nil
+}
+
+// Number returns f as an OpenSCAD number literal. OpenSCAD has no literals
+// for NaN and infinities, so those are rejected.
+func Number(f float64) (string, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return "", fmt.Errorf("%w: %v", ErrUnsupported, f)
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64), nil
+}
+
+// Bool returns b as an OpenSCAD boolean literal.
+func Bool(b bool) string {
+	return strconv.FormatBool(b)
+}
+
+// Literal encodes v as an OpenSCAD literal. Strings, booleans, integers,
+// floats and slices or arrays of those (as vectors) are supported, and nil
+// becomes undef.
+func Literal(v any) (string, error) {
+	switch x := v.(type) {
+	case nil:
+		return "undef", nil
+	case string:
+		return String(x), nil
+	case bool:
+		return Bool(x), nil
+	case float64:
+		return Number(x)
+	case float32:
+		return Number(float64(x))
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(rv.Uint(), 10), nil
+	case reflect.Slice, reflect.Array:
+		elems := make([]string, rv.Len())
+		for i := range elems {
+			elem, err := Literal(rv.Index(i).Interface())
+			if err != nil {
+				return "", err
+			}
+			elems[i] = elem
+		}
+		return "[" + strings.Join(elems, ", ") + "]", nil
+	}
+
+	return "", fmt.Errorf("%w: %T", ErrUnsupported, v)
+}
+
+var (
+	identPattern  = regexp.MustCompile(`^\$?[A-Za-z_][A-Za-z0-9_]*$`)
+	numberPattern = regexp.MustCompile(`^[+-]?([0-9]+\.?[0-9]*|\.[0-9]+)([eE][+-]?[0-9]+)?$`)
+)
+
+// ValidName reports whether name can be assigned with -D: an identifier,
+// optionally starting with $ for special variables such as $fn.
+func ValidName(name string) bool {
+	return identPattern.MatchString(name)
+}
+
+// Define returns the argument for OpenSCAD's -D option that assigns v to
+// name, such as BRAND="Prusament".
+func Define(name string, v any) (string, error) {
+	if !ValidName(name) {
+		return "", fmt.Errorf("invalid OpenSCAD variable name %q", name)
+	}
+	literal, err := Literal(v)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", name, err)
+	}
+	return name + "=" + literal, nil
+}
+
+// ParseNumber parses s as a plain decimal number such as 12, 4.5 or 1e3.
+// Unlike strconv.ParseFloat it rejects hexadecimal, infinities, NaN and
+// anything else OpenSCAD would not read as a number literal.
+func ParseNumber(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	if !numberPattern.MatchString(s) {
+		return 0, fmt.Errorf("%q is not a number", s)
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a number", s)
+	}
+	return f, nil
+}
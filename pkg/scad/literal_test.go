@@ -0,0 +1,231 @@
+package scad
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{`Prusament`, `"Prusament"`},
+		{`12" Dark`, `"12\" Dark"`},
+		{`back\slash`, `"back\\slash"`},
+		{`Grün`, `"Gr\u00fcn"`},
+		{`🎨`, `"\U01f3a8"`},
+		{"line\nbreak\ttab\r", `"line\nbreak\ttab\r"`},
+		{"bell\x07", `"bell\x07"`},
+		{"nul\x00byte", `"nulbyte"`},
+		{"bad\xffutf8", `"bad\ufffdutf8"`},
+		{`"; cube(100); x="`, `"\"; cube(100); x=\""`},
+	}
+
+	for _, tt := range tests {
+		if got := String(tt.in); got != tt.want {
+			t.Errorf("String(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestUnquote(t *testing.T) {
+	for _, s := range []string{"Prusament", `12" Dark`, `back\slash`, "Grün", "🎨", "line\nbreak\ttab\r", "bell\x07", `"; cube(100); x="`} {
+		got, err := Unquote(String(s))
+		if err != nil || got != s {
+			t.Errorf("Unquote(String(%q)) = %q, %v", s, got, err)
+		}
+	}
+
+	for _, bad := range []string{`Prusament`, `"a"b"`, `"a\"`, `"\q"`, `"\u00f"`, `"\U1f3a8"`} {
+		if got, err := Unquote(bad); err == nil {
+			t.Errorf("Unquote(%s) = %q, want an error", bad, got)
+		}
+	}
+}
+
+func TestLiteral(t *testing.T) {
+	tests := []struct {
+		in      any
+		want    string
+		wantErr bool
+	}{
+		{in: nil, want: "undef"},
+		{in: "PLA", want: `"PLA"`},
+		{in: true, want: "true"},
+		{in: 12, want: "12"},
+		{in: int64(-3), want: "-3"},
+		{in: uint8(7), want: "7"},
+		{in: 4.5, want: "4.5"},
+		{in: float32(0.25), want: "0.25"},
+		{in: 1e21, want: "1e+21"},
+		{in: []float64{1, 2.5, 3}, want: "[1, 2.5, 3]"},
+		{in: []any{"a", 1, []int{2, 3}}, want: `["a", 1, [2, 3]]`},
+		{in: [2]bool{true, false}, want: "[true, false]"},
+		{in: math.NaN(), wantErr: true},
+		{in: math.Inf(1), wantErr: true},
+		{in: map[string]int{}, wantErr: true},
+		{in: []any{1, struct{}{}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := Literal(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Literal(%v) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if err != nil && !errors.Is(err, ErrUnsupported) {
+			t.Errorf("Literal(%v) error should wrap ErrUnsupported, got %v", tt.in, err)
+		}
+		if got != tt.want {
+			t.Errorf("Literal(%v) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestDefine(t *testing.T) {
+	got, err := Define("BRAND", `My "Brand"`)
+	if err != nil || got != `BRAND="My \"Brand\""` {
+		t.Errorf("Define() = %s, %v", got, err)
+	}
+	if got, err := Define("$fn", 64); err != nil || got != "$fn=64" {
+		t.Errorf("Define($fn) = %s, %v", got, err)
+	}
+
+	for _, name := range []string{"", "1ABC", "A B", "A=1;B", "$", "a-b"} {
+		if _, err := Define(name, 1); err == nil {
+			t.Errorf("Define(%q) should fail", name)
+		}
+	}
+}
+
+func TestParseNumber(t *testing.T) {
+	valid := map[string]float64{"12": 12, " 4.5 ": 4.5, "-3": -3, "+2": 2, ".5": 0.5, "5.": 5, "1e3": 1000, "2.5E-1": 0.25}
+	for in, want := range valid {
+		if got, err := ParseNumber(in); err != nil || got != want {
+			t.Errorf("ParseNumber(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+
+	for _, in := range []string{"", "abc", "4;r_hole=0", "0x10", "Inf", "NaN", "1_000", "1e999", "1,5", "1 2", "--1", "."} {
+		if _, err := ParseNumber(in); err == nil {
+			t.Errorf("ParseNumber(%q) should fail", in)
+		}
+	}
+}
+
+// decode reads an OpenSCAD string literal the way OpenSCAD's lexer does
+// and reports whether lit is exactly one well-formed literal.
+func decode(lit string) (string, bool) {
+	if len(lit) < 2 || lit[0] != '"' || lit[len(lit)-1] != '"' {
+		return "", false
+	}
+	body := lit[1 : len(lit)-1]
+
+	var b strings.Builder
+	for i := 0; i < len(body); i++ {
+		c := body[i]
+		if c == '"' {
+			return "", false
+		}
+		if c != '\\' {
+			b.WriteByte(c)
+			continue
+		}
+		i++
+		if i >= len(body) {
+			return "", false
+		}
+		hex := func(n int) bool {
+			if i+n >= len(body) {
+				return false
+			}
+			v, err := strconv.ParseUint(body[i+1:i+1+n], 16, 32)
+			if err != nil {
+				return false
+			}
+			b.WriteRune(rune(v))
+			i += n
+			return true
+		}
+		switch body[i] {
+		case '"', '\\':
+			b.WriteByte(body[i])
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case 'x':
+			if !hex(2) {
+				return "", false
+			}
+		case 'u':
+			if !hex(4) {
+				return "", false
+			}
+		case 'U':
+			if !hex(6) {
+				return "", false
+			}
+		default:
+			return "", false
+		}
+	}
+	return b.String(), true
+}
+
+func FuzzString(f *testing.F) {
+	for _, seed := range []string{"", "PLA", `12" Dark`, `a\b`, "Grün", "🎨", "\x00\x01\n", "\xff", `"; cube(1); x="`} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, s string) {
+		lit := String(s)
+
+		for i := 0; i < len(lit); i++ {
+			if lit[i] < 0x20 || lit[i] >= 0x7f {
+				t.Fatalf("String(%q) = %q contains a byte outside printable ASCII", s, lit)
+			}
+		}
+
+		got, ok := decode(lit)
+		if !ok {
+			t.Fatalf("String(%q) = %q is not a single well-formed literal", s, lit)
+		}
+
+		want := strings.ReplaceAll(strings.ToValidUTF8(s, string(utf8.RuneError)), "\x00", "")
+		if got != want {
+			t.Fatalf("String(%q) = %q decodes to %q, want %q", s, lit, got, want)
+		}
+	})
+}
+
+func FuzzParseNumber(f *testing.F) {
+	for _, seed := range []string{"12", "4.5", "-1e3", "4;r_hole=0", "0x1p3", "Inf", ".5"} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, s string) {
+		n, err := ParseNumber(s)
+		if err != nil {
+			return
+		}
+
+		lit, err := Number(n)
+		if err != nil {
+			t.Fatalf("ParseNumber(%q) = %v has no literal: %v", s, n, err)
+		}
+		if !numberPattern.MatchString(lit) {
+			t.Fatalf("Number(%v) = %q is not a plain number literal", n, lit)
+		}
+		if back, err := strconv.ParseFloat(lit, 64); err != nil || back != n {
+			t.Fatalf("Number(%v) = %q does not round-trip", n, lit)
+		}
+	})
+}
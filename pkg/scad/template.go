@@ -0,0 +1,476 @@
+package scad
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Type is the kind of value a template parameter holds.
+type Type string
+
+// Parameter types. A parameter whose default is computed, such as
+// NOTCH_Y=CARD_HEIGHT - 6.75, has TypeExpression and accepts any value.
+const (
+	TypeNumber     Type = "number"
+	TypeString     Type = "string"
+	TypeBool       Type = "boolean"
+	TypeVector     Type = "vector"
+	TypeUndef      Type = "undef"
+	TypeExpression Type = "expression"
+)
+
+// Param is a variable assigned at the top level of a template, which -D can
+// override.
+type Param struct {
+	Name string `json:"name"`
+	Type Type   `json:"type"`
+	// Default is the assigned value as written, such as 2.2 or
+	// [1.0, 0.8].
+	Default string `json:"default"`
+	// Description is the comment on the line above the assignment, and
+	// Group the Customizer tab it is in, from a /* [Group] */ comment.
+	Description string `json:"description,omitempty"`
+	Group       string `json:"group,omitempty"`
+	// Min and Max bound a number from a Customizer range comment such as
+	// // [0:10] after the assignment. Options lists the values of a
+	// Customizer drop-down comment such as // [PLA, PETG].
+	Min     *float64 `json:"min,omitempty"`
+	Max     *float64 `json:"max,omitempty"`
+	Options []string `json:"options,omitempty"`
+	// Line is the line of the assignment in the template.
+	Line int `json:"line"`
+}
+
+// Schema lists the parameters of a template in the order they are
+// assigned.
+type Schema struct {
+	Params []Param
+	index  map[string]int
+}
+
+// Lookup returns the parameter called name.
+func (s *Schema) Lookup(name string) (Param, bool) {
+	i, ok := s.index[name]
+	if !ok {
+		return Param{}, false
+	}
+	return s.Params[i], true
+}
+
+// Has reports whether name can be overridden: a parameter of the template
+// or a special variable such as $fn, which is built into OpenSCAD.
+func (s *Schema) Has(name string) bool {
+	_, ok := s.Lookup(name)
+	return ok || strings.HasPrefix(name, "$")
+}
+
+// Check reports whether the OpenSCAD literal can override the parameter
+// called name. Special variables such as $fn accept any value even when the
+// template does not assign them.
+func (s *Schema) Check(name, literal string) error {
+	p, ok := s.Lookup(name)
+	if !ok {
+		if s.Has(name) {
+			return nil
+		}
+		return fmt.Errorf("the template has no parameter %s", name)
+	}
+	return p.Check(literal)
+}
+
+// CheckAll checks every literal of a set of overrides keyed by name, such
+// as those of a config file, and joins the errors in name order.
+func (s *Schema) CheckAll(literals map[string]string) error {
+	names := make([]string, 0, len(literals))
+	for name := range literals {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs []error
+	for _, name := range names {
+		if err := s.Check(name, literals[name]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Check reports whether the OpenSCAD literal has the type of the parameter
+// and fits its Customizer range or options. Numbers and booleans may stand
+// for each other, as OpenSCAD treats 0 and 1 as false and true, and undef
+// fits every parameter.
+func (p Param) Check(literal string) error {
+	got := LiteralType(literal)
+	if got == TypeUndef || p.Type == TypeExpression || p.Type == TypeUndef {
+		return nil
+	}
+	if got != p.Type && !(isBoolish(got) && isBoolish(p.Type)) {
+		return fmt.Errorf("%s must be a %s like %s, got %s %s", p.Name, p.Type, p.Default, got, literal)
+	}
+
+	if got == TypeNumber {
+		n, _ := ParseNumber(literal)
+		if (p.Min != nil && n < *p.Min) || (p.Max != nil && n > *p.Max) {
+			return fmt.Errorf("%s must be between %v and %v, got %s", p.Name, *p.Min, *p.Max, literal)
+		}
+	}
+	if len(p.Options) > 0 && !p.allows(literal) {
+		return fmt.Errorf("%s must be one of %s, got %s", p.Name, strings.Join(p.Options, ", "), literal)
+	}
+	return nil
+}
+
+func isBoolish(t Type) bool {
+	return t == TypeNumber || t == TypeBool
+}
+
+// allows reports whether literal is among the Customizer options.
+func (p Param) allows(literal string) bool {
+	for _, option := range p.Options {
+		switch LiteralType(literal) {
+		case TypeNumber:
+			want, err1 := ParseNumber(option)
+			got, err2 := ParseNumber(literal)
+			if err1 == nil && err2 == nil && want == got {
+				return true
+			}
+		case TypeString:
+			if s, err := Unquote(literal); err == nil && s == option {
+				return true
+			}
+		default:
+			if literal == option {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// LiteralType returns the type of an OpenSCAD literal such as 2.2,
+// "Noto Sans" or [1, 2], or TypeExpression when s is not a literal.
+func LiteralType(s string) Type {
+	s = strings.TrimSpace(s)
+	switch s {
+	case "true", "false":
+		return TypeBool
+	case "undef":
+		return TypeUndef
+	}
+	if _, err := ParseNumber(s); err == nil {
+		return TypeNumber
+	}
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		if !strings.Contains(unescaped(s[1:len(s)-1]), `"`) {
+			return TypeString
+		}
+	}
+	if len(s) >= 2 && s[0] == '[' && s[len(s)-1] == ']' {
+		parts, ok := split(s[1:len(s)-1], ',')
+		if !ok {
+			return TypeExpression
+		}
+		if len(parts) == 1 && strings.TrimSpace(parts[0]) == "" {
+			return TypeVector
+		}
+		for _, part := range parts {
+			if LiteralType(part) == TypeExpression {
+				return TypeExpression
+			}
+		}
+		return TypeVector
+	}
+	return TypeExpression
+}
+
+// unescaped drops escape sequences from the body of a string literal, so
+// that only quotes that would end it remain.
+func unescaped(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+			continue
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+var (
+	assignmentPattern = regexp.MustCompile(`^(\$?[A-Za-z_][A-Za-z0-9_]*)\s*=([^=][\s\S]*|)$`)
+	groupPattern      = regexp.MustCompile(`^/\*\s*\[([^\]]*)\]\s*\*/$`)
+	includePattern    = regexp.MustCompile(`^(include|use)\s*<`)
+)
+
+// ParseTemplate reads the variables assigned at the top level of a .scad
+// file, outside modules and functions, together with the Customizer
+// annotations around them. A variable assigned twice is listed once, with
+// its last value, as OpenSCAD uses that one.
+func ParseTemplate(src []byte) (*Schema, error) {
+	p := &templateParser{src: string(src), line: 1}
+	schema := &Schema{index: make(map[string]int)}
+
+	for {
+		stmt, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		if stmt == nil {
+			break
+		}
+		m := assignmentPattern.FindStringSubmatch(stmt.text)
+		if m == nil {
+			continue
+		}
+
+		value := strings.TrimSpace(m[2])
+		param := Param{
+			Name:        m[1],
+			Type:        LiteralType(value),
+			Default:     value,
+			Description: stmt.description,
+			Group:       stmt.group,
+			Line:        stmt.line,
+		}
+		param.annotate(stmt.annotation)
+
+		if i, dup := schema.index[param.Name]; dup {
+			schema.Params[i] = param
+			continue
+		}
+		schema.index[param.Name] = len(schema.Params)
+		schema.Params = append(schema.Params, param)
+	}
+	return schema, nil
+}
+
+// annotate applies a Customizer comment such as [0:10], [0:0.5:10], [10]
+// or [PLA, PETG, 10:Label].
+func (p *Param) annotate(comment string) {
+	comment = strings.TrimSpace(comment)
+	if len(comment) < 2 || comment[0] != '[' || comment[len(comment)-1] != ']' {
+		return
+	}
+	body := comment[1 : len(comment)-1]
+
+	if strings.Contains(body, ",") {
+		for _, option := range strings.Split(body, ",") {
+			value, _, _ := strings.Cut(option, ":")
+			p.Options = append(p.Options, strings.TrimSpace(value))
+		}
+		return
+	}
+
+	var bounds []float64
+	for _, part := range strings.Split(body, ":") {
+		n, err := ParseNumber(part)
+		if err != nil {
+			return
+		}
+		bounds = append(bounds, n)
+	}
+	switch len(bounds) {
+	case 1:
+		p.Min, p.Max = new(float64), &bounds[0]
+	case 2:
+		p.Min, p.Max = &bounds[0], &bounds[1]
+	case 3:
+		p.Min, p.Max = &bounds[0], &bounds[2]
+	}
+}
+
+// statement is a top-level statement of a template without its
+// terminating semicolon.
+type statement struct {
+	text string
+	line int
+	// description is the line comment on the line above, group the
+	// current Customizer group and annotation the line comment after the
+	// statement on its last line.
+	description string
+	group       string
+	annotation  string
+}
+
+type templateParser struct {
+	src  string
+	pos  int
+	line int
+	// comment is the last line comment and commentLine the line it was
+	// on.
+	comment     string
+	commentLine int
+	group       string
+}
+
+// next returns the next top-level statement, or nil at the end.
+func (p *templateParser) next() (*statement, error) {
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.src) {
+			return nil, nil
+		}
+		switch {
+		case strings.HasPrefix(p.src[p.pos:], "//"):
+			p.comment, p.commentLine = p.lineComment(), p.line
+			continue
+		case strings.HasPrefix(p.src[p.pos:], "/*"):
+			text, err := p.blockComment()
+			if err != nil {
+				return nil, err
+			}
+			if m := groupPattern.FindStringSubmatch(text); m != nil {
+				p.group = strings.TrimSpace(m[1])
+			}
+			continue
+		}
+
+		stmt := &statement{line: p.line, group: p.group}
+		if p.commentLine == p.line-1 && !commentedOut(p.comment) {
+			stmt.description = p.comment
+		}
+		text, err := p.statement()
+		if err != nil {
+			return nil, err
+		}
+		stmt.text = strings.TrimSpace(text)
+
+		// A line comment on the same line annotates the statement.
+		rest := p.src[p.pos:]
+		if end := strings.IndexByte(rest, '\n'); end >= 0 {
+			rest = rest[:end]
+		}
+		if trimmed := strings.TrimLeft(rest, " \t"); strings.HasPrefix(trimmed, "//") {
+			p.pos += len(rest) - len(trimmed)
+			stmt.annotation = p.lineComment()
+		}
+		return stmt, nil
+	}
+}
+
+// commentedOut reports whether comment is an assignment that was commented
+// out, such as an alternative value kept above the one in use, rather than
+// a description.
+func commentedOut(comment string) bool {
+	code, ok := strings.CutSuffix(comment, ";")
+	return ok && assignmentPattern.MatchString(strings.TrimSpace(code))
+}
+
+func (p *templateParser) skipSpace() {
+	for p.pos < len(p.src) {
+		switch p.src[p.pos] {
+		case '\n':
+			p.line++
+		case ' ', '\t', '\r':
+		default:
+			return
+		}
+		p.pos++
+	}
+}
+
+// lineComment consumes a // comment and returns its text.
+func (p *templateParser) lineComment() string {
+	end := strings.IndexByte(p.src[p.pos:], '\n')
+	if end < 0 {
+		end = len(p.src) - p.pos
+	}
+	text := p.src[p.pos+2 : p.pos+end]
+	p.pos += end
+	return strings.TrimSpace(text)
+}
+
+// blockComment consumes a /* */ comment and returns it.
+func (p *templateParser) blockComment() (string, error) {
+	end := strings.Index(p.src[p.pos+2:], "*/")
+	if end < 0 {
+		return "", fmt.Errorf("line %d: unterminated comment", p.line)
+	}
+	text := p.src[p.pos : p.pos+2+end+2]
+	p.line += strings.Count(text, "\n")
+	p.pos += len(text)
+	return text, nil
+}
+
+// statement consumes a statement up to its semicolon at nesting depth 0,
+// or up to the closing brace of a block such as a module body. include
+// and use statements end with their line.
+func (p *templateParser) statement() (string, error) {
+	start, line := p.pos, p.line
+	if includePattern.MatchString(p.src[p.pos:]) {
+		if end := strings.IndexByte(p.src[p.pos:], '\n'); end >= 0 {
+			p.pos += end
+			return p.src[start:p.pos], nil
+		}
+		p.pos = len(p.src)
+		return p.src[start:], nil
+	}
+
+	depth := 0
+	var b strings.Builder
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		switch {
+		case c == '\n':
+			p.line++
+		case c == '"':
+			end, err := p.stringEnd()
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(p.src[p.pos:end])
+			p.pos = end
+			continue
+		case strings.HasPrefix(p.src[p.pos:], "//"):
+			p.lineComment()
+			continue
+		case strings.HasPrefix(p.src[p.pos:], "/*"):
+			if _, err := p.blockComment(); err != nil {
+				return "", err
+			}
+			b.WriteByte(' ')
+			continue
+		case c == '(' || c == '[' || c == '{':
+			depth++
+		case c == ')' || c == ']' || c == '}':
+			depth--
+			if depth < 0 {
+				return "", fmt.Errorf("line %d: unbalanced %q", p.line, c)
+			}
+			if c == '}' && depth == 0 {
+				p.pos++
+				b.WriteByte(c)
+				return b.String(), nil
+			}
+		case c == ';' && depth == 0:
+			p.pos++
+			return b.String(), nil
+		}
+		b.WriteByte(c)
+		p.pos++
+	}
+	if depth > 0 {
+		return "", fmt.Errorf("line %d: unterminated statement", line)
+	}
+	return p.src[start:], nil
+}
+
+// stringEnd returns the position after the string literal at p.pos.
+func (p *templateParser) stringEnd() (int, error) {
+	for i := p.pos + 1; i < len(p.src); i++ {
+		switch p.src[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1, nil
+		case '\n':
+			p.line++
+		}
+	}
+	return 0, fmt.Errorf("line %d: unterminated string", p.line)
+}
@@ -0,0 +1,210 @@
+package scad
+
+import (
+	"strings"
+	"testing"
+
+	filamentsamples "github.com/guntharp/go-filamentsamples"
+)
+
+const testTemplate = `// Change For Each Card
+BRAND="extrudr"; // the brand { printed } on top
+
+//hole radius. Set to 0 to have no hole
+r_hole=2; // [0:5]
+
+/* [Card] */
+CARD_THICKNESS=2.2; // [1:0.1:4]
+INSET_DEPTHS=[1.0, 0.8,
+    0.6];
+/* OLD=1;
+   { */
+FONT = "Noto Sans"; // [Liberation Sans, Noto Sans:Noto]
+INVERT_CARD=1;
+SHOW=true;
+NOTCH_Y=CARD_HEIGHT - 6.75;
+TEXT="};";
+CARD_THICKNESS=2.4; // [1:0.1:4]
+
+/* [Hidden] */
+$fn = 50;
+
+include <lib.scad>
+use <other.scad>
+module Card(Size) {
+    INNER=Size * 2;
+    if (INNER == 4) { cube(INNER); }
+}
+function half(x) = x / 2;
+cube(CARD_THICKNESS);
+`
+
+func TestParseTemplate(t *testing.T) {
+	schema, err := ParseTemplate([]byte(testTemplate))
+	if err != nil {
+		t.Fatalf("ParseTemplate() error = %v", err)
+	}
+
+	var names []string
+	for _, p := range schema.Params {
+		names = append(names, p.Name)
+	}
+	want := "BRAND r_hole CARD_THICKNESS INSET_DEPTHS FONT INVERT_CARD SHOW NOTCH_Y TEXT $fn"
+	if got := strings.Join(names, " "); got != want {
+		t.Fatalf("names = %s, want %s", got, want)
+	}
+
+	tests := []struct {
+		name        string
+		typ         Type
+		def         string
+		description string
+		group       string
+		line        int
+	}{
+		{"BRAND", TypeString, `"extrudr"`, "Change For Each Card", "", 2},
+		{"r_hole", TypeNumber, "2", "hole radius. Set to 0 to have no hole", "", 5},
+		{"CARD_THICKNESS", TypeNumber, "2.4", "", "Card", 18},
+		{"INSET_DEPTHS", TypeVector, "[1.0, 0.8,\n    0.6]", "", "Card", 9},
+		{"FONT", TypeString, `"Noto Sans"`, "", "Card", 13},
+		{"INVERT_CARD", TypeNumber, "1", "", "Card", 14},
+		{"SHOW", TypeBool, "true", "", "Card", 15},
+		{"NOTCH_Y", TypeExpression, "CARD_HEIGHT - 6.75", "", "Card", 16},
+		{"TEXT", TypeString, `"};"`, "", "Card", 17},
+		{"$fn", TypeNumber, "50", "", "Hidden", 21},
+	}
+	for _, tt := range tests {
+		p, ok := schema.Lookup(tt.name)
+		if !ok {
+			t.Errorf("%s not found", tt.name)
+			continue
+		}
+		if p.Type != tt.typ || p.Default != tt.def || p.Description != tt.description || p.Group != tt.group || p.Line != tt.line {
+			t.Errorf("%s = %+v, want type %s, default %s, description %q, group %q, line %d",
+				tt.name, p, tt.typ, tt.def, tt.description, tt.group, tt.line)
+		}
+	}
+
+	if p, _ := schema.Lookup("r_hole"); p.Min == nil || *p.Min != 0 || *p.Max != 5 {
+		t.Errorf("r_hole range = %v..%v, want 0..5", p.Min, p.Max)
+	}
+	if p, _ := schema.Lookup("CARD_THICKNESS"); p.Min == nil || *p.Min != 1 || *p.Max != 4 {
+		t.Errorf("CARD_THICKNESS range = %v..%v, want 1..4", p.Min, p.Max)
+	}
+	if p, _ := schema.Lookup("FONT"); strings.Join(p.Options, "|") != "Liberation Sans|Noto Sans" {
+		t.Errorf("FONT options = %q", p.Options)
+	}
+	if _, ok := schema.Lookup("INNER"); ok {
+		t.Error("variables inside modules are not template parameters")
+	}
+}
+
+func TestParseTemplate_Shipped(t *testing.T) {
+	schema, err := ParseTemplate(filamentsamples.Template)
+	if err != nil {
+		t.Fatalf("ParseTemplate() error = %v", err)
+	}
+
+	// Assignments commented out above the one in use are not descriptions.
+	tests := []struct {
+		name        string
+		description string
+	}{
+		{"r_hole", "hole radius. Set to 0 to have no hole"},
+		{"COLOR_SIZE", "Change only if absolutely necessary"},
+		{"INSET_DEPTHS", ""},
+		{"FONT", ""},
+		{"TEXT_TEMP", ""},
+	}
+	for _, tt := range tests {
+		p, ok := schema.Lookup(tt.name)
+		if !ok {
+			t.Errorf("%s not found", tt.name)
+			continue
+		}
+		if p.Description != tt.description {
+			t.Errorf("%s description = %q, want %q", tt.name, p.Description, tt.description)
+		}
+	}
+}
+
+func TestParseTemplate_Errors(t *testing.T) {
+	for _, src := range []string{
+		"FONT=\"Liberation;\n",
+		"/* open comment\nA=1;\n",
+		"module Card() {\n  cube(1);\n",
+		"A=[1, 2]];\n",
+	} {
+		if _, err := ParseTemplate([]byte(src)); err == nil {
+			t.Errorf("ParseTemplate(%q) should fail", src)
+		}
+	}
+}
+
+func TestSchema_Check(t *testing.T) {
+	schema, err := ParseTemplate([]byte(testTemplate))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		literal string
+		wantErr string
+	}{
+		{"CARD_THICKNESS", "3", ""},
+		{"CARD_THICKNESS", `"3"`, `CARD_THICKNESS must be a number like 2.4, got string "3"`},
+		{"CARD_THICKNESS", "5", "CARD_THICKNESS must be between 1 and 4, got 5"},
+		{"CARD_THICKNESS", "undef", ""},
+		{"r_hole", "6", "r_hole must be between 0 and 5, got 6"},
+		{"INSET_DEPTHS", "[0.4, 0.2]", ""},
+		{"INSET_DEPTHS", "0.4", "INSET_DEPTHS must be a vector"},
+		{"INVERT_CARD", "false", ""},
+		{"SHOW", "0", ""},
+		{"SHOW", `"no"`, "SHOW must be a boolean"},
+		{"FONT", `"Noto Sans"`, ""},
+		{"FONT", `"Comic Sans"`, "FONT must be one of Liberation Sans, Noto Sans"},
+		{"NOTCH_Y", `"anything"`, ""},
+		{"$fn", "100", ""},
+		{"$fa", "12", ""},
+		{"CARD_THICKNES", "3", "the template has no parameter CARD_THICKNES"},
+	}
+	for _, tt := range tests {
+		err := schema.Check(tt.name, tt.literal)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("Check(%s, %s) error = %v", tt.name, tt.literal, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("Check(%s, %s) error = %v, want %q", tt.name, tt.literal, err, tt.wantErr)
+		}
+	}
+
+	err = schema.CheckAll(map[string]string{"SHOW": `"no"`, "CARD_THICKNESS": `"3"`, "r_hole": "1"})
+	if err == nil || !strings.HasPrefix(err.Error(), "CARD_THICKNESS") || !strings.Contains(err.Error(), "\nSHOW") {
+		t.Errorf("CheckAll() error = %v, want CARD_THICKNESS and SHOW in name order", err)
+	}
+}
+
+func TestLiteralType(t *testing.T) {
+	tests := map[string]Type{
+		`2.2`:               TypeNumber,
+		`-1e3`:              TypeNumber,
+		`"a \" b"`:          TypeString,
+		`"a" + "b"`:         TypeExpression,
+		`true`:              TypeBool,
+		`undef`:             TypeUndef,
+		`[]`:                TypeVector,
+		`[1, [2, "x"]]`:     TypeVector,
+		`[0:2]`:             TypeExpression,
+		`[for (i=[0:2]) i]`: TypeExpression,
+		`str("N", TEMP)`:    TypeExpression,
+	}
+	for literal, want := range tests {
+		if got := LiteralType(literal); got != want {
+			t.Errorf("LiteralType(%s) = %s, want %s", literal, got, want)
+		}
+	}
+}